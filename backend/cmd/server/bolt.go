@@ -6,6 +6,7 @@ package main
 import (
 	"log"
 
+	"lunch/pkg/claims"
 	storage_jwt_keys "lunch/pkg/jwt/keys/storage"
 	"lunch/pkg/lunch/events"
 	"lunch/pkg/store"
@@ -27,4 +28,5 @@ var (
 	eventsStorage = events.NewCache(
 		events.NewBoltStorage(boltStore),
 	)
+	claimsStore = claims.NewBolt(boltStore)
 )
@@ -7,6 +7,7 @@ import (
 	"context"
 	"log"
 
+	"lunch/pkg/claims"
 	storage_jwt_keys "lunch/pkg/jwt/keys/storage"
 	"lunch/pkg/lunch/events"
 	"lunch/pkg/store"
@@ -39,6 +40,7 @@ var (
 		storage_users.NewDynamoDB(dynamodbStore, "lunch-production-webapp-users"),
 	)
 	eventsStorage = events.NewCache(
-		events.NewDynamoDBStore(dynamodbStore, "lunch-production-webapp-events"),
+		events.NewDynamoDBStore(dynamodbStore, "lunch-production-webapp-events-by-id"),
 	)
+	claimsStore = claims.NewDynamoDB(dynamodbStore, "lunch-production-webapp-claims")
 )
@@ -3,21 +3,39 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"lunch/pkg/dispatcher"
 	"lunch/pkg/http"
 	"lunch/pkg/jwt"
 	"lunch/pkg/lunch"
+	"lunch/pkg/scheduler"
 	service_users "lunch/pkg/users/service"
 )
 
+// mustLoadWebhooksKey returns the key webhook secrets are encrypted with. Webhooks can't be created if it's not set.
+func mustLoadWebhooksKey() []byte {
+	encoded := os.Getenv("WEBHOOKS_SECRET_KEY")
+	if encoded == "" {
+		log.Printf("[INFO] WEBHOOKS_SECRET_KEY is not set, webhooks are disabled")
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Fatalf("failed to decode WEBHOOKS_SECRET_KEY: %v", err)
+	}
+	return key
+}
+
 var (
-	roller       = lunch.New(eventsStorage, usersStore)
+	roller       = lunch.New(eventsStorage, usersStore, lunch.WithWebhooksKey(mustLoadWebhooksKey()))
 	jwtService   = jwt.NewService(jwtKeysStore)
 	usersService = service_users.New(usersStore)
 )
@@ -38,7 +56,16 @@ func main() {
 		log.Fatalf("failed to parse configuration: %v", err)
 	}
 
-	srv := http.NewServer(cfg, roller, jwtService, usersService)
+	srv, err := http.NewServer(cfg, roller, claimsStore, jwtService, usersService)
+	if err != nil {
+		log.Fatalf("failed to create server: %v", err)
+	}
+
+	sched := scheduler.New(roller, claimsStore)
+	sched.Start()
+
+	disp := dispatcher.New(roller, claimsStore)
+	disp.Start()
 
 	// Wait for shut down in a separate goroutine.
 	errCh := make(chan error)
@@ -53,6 +80,14 @@ func main() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
+		if err := sched.Stop(shutdownCtx); err != nil {
+			log.Printf("[ERROR] failed to stop scheduler: %s", err)
+		}
+
+		if err := disp.Stop(shutdownCtx); err != nil {
+			log.Printf("[ERROR] failed to stop dispatcher: %s", err)
+		}
+
 		errCh <- srv.Shutdown(shutdownCtx)
 	}()
 
@@ -0,0 +1,78 @@
+package claims
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"lunch/pkg/store"
+)
+
+// pruneEvery is how many claims are made between deleting expired ones.
+const pruneEvery = 1000
+
+type claim struct {
+	Key   string    `json:"key"`
+	Until time.Time `json:"until"`
+}
+
+type bolt struct {
+	db     *store.Bolt
+	bucket string
+
+	guard  *sync.Mutex
+	claims int
+}
+
+func NewBolt(db *store.Bolt) *bolt {
+	return &bolt{
+		db:     db,
+		bucket: "claims",
+		guard:  &sync.Mutex{},
+	}
+}
+
+func (b *bolt) Claim(ctx context.Context, key string, now, until time.Time) error {
+	if err := b.db.Update(ctx, b.bucket, key, func(current []byte) (interface{}, error) {
+		if current != nil {
+			c := &claim{}
+			if err := json.Unmarshal(current, c); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal claim: %w", err)
+			}
+			if !c.Until.Before(now) {
+				return nil, ErrClaimed
+			}
+		}
+		return &claim{Key: key, Until: until}, nil
+	}); err != nil {
+		return err
+	}
+
+	b.guard.Lock()
+	b.claims++
+	prune := b.claims%pruneEvery == 0
+	b.guard.Unlock()
+	if prune {
+		if err := b.prune(ctx, now); err != nil {
+			return fmt.Errorf("failed to delete expired claims: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *bolt) prune(ctx context.Context, now time.Time) error {
+	cc := []*claim{}
+	if err := b.db.List(ctx, b.bucket, &cc); err != nil {
+		return err
+	}
+	for _, c := range cc {
+		if c.Until.Before(now) {
+			if err := b.db.Delete(ctx, b.bucket, c.Key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
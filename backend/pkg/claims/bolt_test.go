@@ -0,0 +1,52 @@
+package claims
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"lunch/pkg/store"
+)
+
+func TestBolt(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	db, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	// two instances on the same database
+	first, second := NewBolt(db), NewBolt(db)
+
+	now := time.Date(2021, time.September, 6, 11, 30, 0, 0, time.UTC)
+	assertNoError(t, first.Claim(context.Background(), "key", now, now.Add(time.Minute)))
+	assertError(t, ErrClaimed, second.Claim(context.Background(), "key", now, now.Add(time.Minute)))
+	assertError(t, ErrClaimed, first.Claim(context.Background(), "key", now.Add(time.Minute), now.Add(2*time.Minute)))
+	assertNoError(t, second.Claim(context.Background(), "other", now, now.Add(time.Minute)))
+
+	// expired claims can be taken over
+	assertNoError(t, second.Claim(context.Background(), "key", now.Add(2*time.Minute), now.Add(3*time.Minute)))
+	assertError(t, ErrClaimed, first.Claim(context.Background(), "key", now.Add(2*time.Minute), now.Add(3*time.Minute)))
+
+	assertNoError(t, first.prune(context.Background(), now.Add(2*time.Minute)))
+	cc := []*claim{}
+	assertNoError(t, db.List(context.Background(), "claims", &cc))
+	if len(cc) != 1 || cc[0].Key != "key" {
+		t.Errorf("expected only the unexpired claim to be left, got %+v", cc)
+	}
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	assertError(t, nil, err)
+}
+
+func assertError(t *testing.T, expected error, got error) {
+	t.Helper()
+
+	if !errors.Is(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -0,0 +1,17 @@
+package claims
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var ErrClaimed = fmt.Errorf("already claimed")
+
+// Storage records claims on keys, so that when many server instances could do the same thing, like rolling a
+// scheduled room, only the one that claimed it does.
+type Storage interface {
+	// Claim claims the key until the given time. It returns ErrClaimed if the key is claimed, unless the claim
+	// expired before now.
+	Claim(ctx context.Context, key string, now, until time.Time) error
+}
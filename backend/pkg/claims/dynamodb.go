@@ -0,0 +1,62 @@
+package claims
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"lunch/pkg/store"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBStorage keeps claims in a table keyed by 'id'. Expired claims are deleted by the table TTL on
+// 'expires_at', which can lag, so 'until' is checked too.
+type DynamoDBStorage struct {
+	storage   *store.DynamoDB
+	tableName string
+}
+
+func NewDynamoDB(storage *store.DynamoDB, tableName string) *DynamoDBStorage {
+	return &DynamoDBStorage{
+		storage:   storage,
+		tableName: tableName,
+	}
+}
+
+func (s *DynamoDBStorage) Claim(ctx context.Context, key string, now, until time.Time) error {
+	// insert fails if the key exists
+	err := s.storage.Execute(ctx, fmt.Sprintf(`
+		INSERT INTO "%s"
+			value {
+				'id': ?,
+				'until': ?,
+				'expires_at': ?
+			}
+	`, s.tableName), key, until.UnixNano(), until.Unix())
+	duplicate := &types.DuplicateItemException{}
+	switch {
+	case err == nil:
+		return nil
+	case !errors.As(err, &duplicate):
+		return fmt.Errorf("failed to insert: %w", err)
+	}
+
+	// update fails unless the claim has expired
+	err = s.storage.Execute(ctx, fmt.Sprintf(`
+		UPDATE "%s"
+		SET "until" = ?
+		SET "expires_at" = ?
+		WHERE "id" = ? AND "until" < ?
+	`, s.tableName), until.UnixNano(), until.Unix(), key, now.UnixNano())
+	conditionFailed := &types.ConditionalCheckFailedException{}
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &conditionFailed):
+		return ErrClaimed
+	default:
+		return fmt.Errorf("failed to update: %w", err)
+	}
+}
@@ -0,0 +1,206 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/webhooks"
+	"lunch/pkg/users"
+)
+
+const (
+	defaultInterval = 5 * time.Second
+	defaultTimeout  = 10 * time.Second
+	// defaultWorkers is how many deliveries are sent at the same time, so that a slow webhook doesn't hold up
+	// the others.
+	defaultWorkers = 8
+	// claimFor is how long an attempt is claimed for. It's longer than it takes to send and record an attempt,
+	// and if an instance stops in between, another one makes the attempt after it.
+	claimFor = time.Minute
+)
+
+// Dispatcher sends pending webhook deliveries, and retries failed ones with exponential backoff. Every server
+// instance runs a dispatcher, and each attempt is claimed, so that only one of them makes it.
+type Dispatcher struct {
+	roller      *lunch.Roller
+	claimsStore claims.Storage
+	client      *http.Client
+	interval    time.Duration
+	workers     int
+	now         func() time.Time
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func New(roller *lunch.Roller, claimsStore claims.Storage) *Dispatcher {
+	return &Dispatcher{
+		roller:      roller,
+		claimsStore: claimsStore,
+		client:      newClient(),
+		interval:    defaultInterval,
+		workers:     defaultWorkers,
+		now:         time.Now,
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// newClient returns a client that connects only to public addresses, as webhook hosts can resolve to
+// anything, and doesn't follow redirects.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !webhooks.AllowedIP(ip) {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect on our behalf, without the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start sends due deliveries every interval in the background, until Stop is called. Deliveries are stored,
+// so the ones that were due while the dispatcher wasn't running are sent on the first tick.
+func (d *Dispatcher) Start() {
+	ticker := time.NewTicker(d.interval)
+	go func() {
+		defer close(d.stopped)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				if err := d.tick(d.now()); err != nil {
+					log.Printf("[ERROR] dispatcher: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop waits for the current deliveries to finish, and stops the dispatcher.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.done)
+	select {
+	case <-d.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tick attempts all deliveries that are due at now.
+func (d *Dispatcher) tick(now time.Time) error {
+	ctx := users.NewContext(context.Background(), users.System)
+
+	deliveries, err := d.roller.DueDeliveries(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to list due deliveries: %w", err)
+	}
+
+	queue := make(chan *lunch.Delivery)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range queue {
+				if err := d.deliver(ctx, now, delivery); err != nil {
+					log.Printf("[ERROR] dispatcher: delivery %s: %s", delivery.ID, err)
+				}
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		queue <- delivery
+	}
+	close(queue)
+	wg.Wait()
+	return nil
+}
+
+// deliver makes the next attempt of the delivery, unless another instance claimed it.
+func (d *Dispatcher) deliver(ctx context.Context, now time.Time, delivery *lunch.Delivery) error {
+	key := fmt.Sprintf("webhooks/delivery/%s/%d", delivery.ID, delivery.Attempts+1)
+	if err := d.claimsStore.Claim(ctx, key, now, now.Add(claimFor)); errors.Is(err, claims.ErrClaimed) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to claim attempt: %w", err)
+	}
+
+	attempt := d.send(ctx, delivery)
+	if err := d.roller.RecordAttempt(ctx, delivery.Delivery, attempt); err != nil {
+		return err
+	}
+	if !attempt.Succeeded() {
+		log.Printf("[WARN] dispatcher: delivery %s to %s failed, attempt %d: %s", delivery.ID, delivery.Webhook.URL, delivery.Attempts, attemptError(attempt))
+	}
+	return nil
+}
+
+// send posts the delivery payload to the webhook, signed with the webhook secret.
+func (d *Dispatcher) send(ctx context.Context, delivery *lunch.Delivery) *webhooks.Attempt {
+	now := d.now()
+	attempt := &webhooks.Attempt{Time: now}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("failed to create request: %s", err)
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhooks.HeaderDelivery, string(delivery.ID))
+	req.Header.Set(webhooks.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhooks.HeaderSignature, webhooks.Sign(delivery.Webhook.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	// drain the body, so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	attempt.StatusCode = resp.StatusCode
+	return attempt
+}
+
+func attemptError(attempt *webhooks.Attempt) string {
+	if attempt.Error != "" {
+		return attempt.Error
+	}
+	return fmt.Sprintf("unexpected status code: %d", attempt.StatusCode)
+}
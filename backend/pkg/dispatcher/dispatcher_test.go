@@ -0,0 +1,183 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/webhooks"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestTick(t *testing.T) {
+	t.Parallel()
+
+	var secret string
+	statusCodes := []int{http.StatusServiceUnavailable, http.StatusNoContent}
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %s", err)
+		}
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+		if err != nil {
+			t.Errorf("failed to parse timestamp: %s", err)
+		}
+		assertEqual(t, webhooks.Sign(secret, time.Unix(timestamp, 0), body), r.Header.Get(webhooks.HeaderSignature))
+		assertEqual(t, "place_created", r.Header.Get(webhooks.HeaderEvent))
+
+		w.WriteHeader(statusCodes[received])
+		received++
+	}))
+	defer server.Close()
+
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ctx := users.NewContext(context.Background(), owner)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt), lunch.WithWebhooksKey([]byte("key")))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	webhook, err := roller.CreateWebhook(ctx, roomID, "http://hooks.example.com/lunch", []string{"place_created"}, time.Now())
+	assertNoError(t, err)
+	secret = webhook.Secret
+
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	// the delivery is stored in the background
+	assertNoError(t, roller.Wait(ctx))
+
+	now := time.Now()
+	due, err := roller.DueDeliveries(ctx, now)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(due))
+
+	claimsStore := claims.NewBolt(bolt)
+	d := New(roller, claimsStore)
+	d.client = testClient(server)
+	d.now = func() time.Time { return now }
+
+	// another instance is making the first attempt
+	assertNoError(t, claimsStore.Claim(ctx, fmt.Sprintf("webhooks/delivery/%s/1", due[0].ID), now, now.Add(claimFor)))
+	assertNoError(t, d.tick(now))
+	assertEqual(t, 0, received)
+
+	// made again when the claim expires
+	now = now.Add(claimFor + time.Second)
+	assertNoError(t, d.tick(now))
+	assertEqual(t, 1, received)
+
+	// not retried before backoff
+	assertNoError(t, d.tick(now))
+	assertEqual(t, 1, received)
+
+	now = now.Add(webhooks.Backoff(1))
+	assertNoError(t, d.tick(now))
+	assertEqual(t, 2, received)
+
+	deliveries, err := roller.ListDeliveries(ctx, roomID, webhook.ID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(deliveries))
+	assertEqual(t, webhooks.StatusDelivered, deliveries[0].Status)
+	assertEqual(t, 2, deliveries[0].Attempts)
+	assertEqual(t, http.StatusNoContent, deliveries[0].StatusCode)
+
+	// delivered ones are not sent again
+	assertNoError(t, d.tick(now.Add(time.Hour)))
+	assertEqual(t, 2, received)
+}
+
+func TestSend(t *testing.T) {
+	t.Parallel()
+
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	d := New(nil, nil)
+	delivery := &lunch.Delivery{
+		Delivery: &webhooks.Delivery{ID: "delivery", EventType: "place_created"},
+		Webhook:  &webhooks.Webhook{URL: server.URL, Secret: "secret"},
+	}
+
+	// the server is on a loopback address
+	attempt := d.send(context.Background(), delivery)
+	assertEqual(t, 0, received)
+	if attempt.Error == "" {
+		t.Errorf("expected the attempt to fail")
+	}
+
+	// redirects are not followed
+	d.client = testClient(server)
+	delivery.Webhook.URL = "http://hooks.example.com/lunch"
+	attempt = d.send(context.Background(), delivery)
+	assertEqual(t, 1, received)
+	assertEqual(t, http.StatusFound, attempt.StatusCode)
+	assertEqual(t, false, attempt.Succeeded())
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	d := New(lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt)), claims.NewBolt(bolt))
+	d.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assertNoError(t, d.Stop(ctx))
+}
+
+// testClient returns the dispatcher client, connecting to the server whatever the host is.
+func testClient(server *httptest.Server) *http.Client {
+	client := newClient()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	client.Transport = transport
+	return client
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("\nexpected: %+v\ngot: %+v", nil, err)
+	}
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if expected != got {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -5,11 +5,15 @@ import (
 
 	"lunch/pkg/http/oauth"
 	"lunch/pkg/http/webhooks"
+	"lunch/pkg/http/websocket"
+	client_slack "lunch/pkg/slack"
 )
 
 type Configuration struct {
-	Webhooks *webhooks.Configuration
-	OAuth    *oauth.Configuration
+	Webhooks  *webhooks.Configuration
+	OAuth     *oauth.Configuration
+	Slack     *client_slack.Configuration
+	Websocket *websocket.Configuration
 }
 
 func (c *Configuration) Parse() error {
@@ -23,5 +27,15 @@ func (c *Configuration) Parse() error {
 		return fmt.Errorf("failed to parse oauth configuration: %w", err)
 	}
 
+	c.Slack = &client_slack.Configuration{}
+	if err := c.Slack.Parse(); err != nil {
+		return fmt.Errorf("failed to parse slack client configuration: %w", err)
+	}
+
+	c.Websocket = &websocket.Configuration{}
+	if err := c.Websocket.Parse(); err != nil {
+		return fmt.Errorf("failed to parse websocket configuration: %w", err)
+	}
+
 	return nil
 }
@@ -1,10 +1,13 @@
 package http
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"lunch/pkg/claims"
 	"lunch/pkg/http/auth"
 	"lunch/pkg/http/oauth"
 	"lunch/pkg/http/rest"
@@ -12,6 +15,7 @@ import (
 	"lunch/pkg/http/websocket"
 	"lunch/pkg/jwt"
 	"lunch/pkg/lunch"
+	client_slack "lunch/pkg/slack"
 	service_users "lunch/pkg/users/service"
 
 	"github.com/go-chi/chi/v5"
@@ -22,9 +26,15 @@ import (
 func NewHandler(
 	cfg *Configuration,
 	roller *lunch.Roller,
+	claimsStore claims.Storage,
 	jwtService *jwt.Service,
 	usersService *service_users.Service,
-) http.Handler {
+) (http.Handler, error) {
+	wsHandler, err := websocket.Handler(cfg.Websocket, roller)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create websocket handler: %w", err)
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RequestLogger(&logFormatter{}))
@@ -44,14 +54,28 @@ func NewHandler(
 	}))
 	r.Use(auth.Parser(jwtService))
 
+	// shared, so that all calls count towards the same rate limits
+	slackClient := client_slack.New(cfg.Slack)
+
 	r.Route("/api", func(r chi.Router) {
-		r.Mount("/webhooks", webhooks.Handler(cfg.Webhooks, roller, usersService))
-		r.Mount("/oauth", oauth.Handler(cfg.OAuth, jwtService, usersService))
-		r.Mount("/ws", websocket.Handler(roller))
+		r.Mount("/webhooks", webhooks.Handler(cfg.Webhooks, roller, claimsStore, usersService, slackClient))
+		r.Mount("/oauth", oauth.Handler(cfg.OAuth, jwtService, usersService, slackClient))
+		r.Mount("/ws", wsHandler)
+		r.Get("/metrics", metricsHandler(roller))
 		r.Mount("/", rest.Handler())
 	})
 
-	return r
+	return r, nil
+}
+
+// metricsHandler responds with counters of the events bus.
+func metricsHandler(roller *lunch.Roller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(roller.Metrics()); err != nil {
+			log.Printf("[ERROR] failed to encode metrics: %s", err)
+		}
+	}
 }
 
 type logEntry struct {
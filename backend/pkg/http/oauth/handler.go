@@ -6,6 +6,7 @@ import (
 
 	"lunch/pkg/http/oauth/slack"
 	"lunch/pkg/jwt"
+	client_slack "lunch/pkg/slack"
 	service_users "lunch/pkg/users/service"
 
 	"github.com/go-chi/chi/v5"
@@ -24,9 +25,9 @@ func (c *Configuration) Parse() error {
 	return nil
 }
 
-func Handler(cfg *Configuration, jwtService *jwt.Service, usersService *service_users.Service) http.Handler {
+func Handler(cfg *Configuration, jwtService *jwt.Service, usersService *service_users.Service, slackClient *client_slack.Client) http.Handler {
 	r := chi.NewMux()
 	applicationJSON := middleware.AllowContentType("application/json")
-	r.With(applicationJSON).Post("/slack", slack.Handler(cfg.Slack, jwtService, usersService))
+	r.With(applicationJSON).Post("/slack", slack.Handler(cfg.Slack, jwtService, usersService, slackClient))
 	return r
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"lunch/pkg/claims"
 	"lunch/pkg/jwt"
 	"lunch/pkg/lunch"
 	service_users "lunch/pkg/users/service"
@@ -17,17 +18,24 @@ import (
 type Server struct {
 	handler    http.Handler
 	httpServer *http.Server
+	roller     *lunch.Roller
 }
 
 func NewServer(
 	cfg *Configuration,
 	roller *lunch.Roller,
+	claimsStore claims.Storage,
 	jwtService *jwt.Service,
 	usersService *service_users.Service,
-) *Server {
-	return &Server{
-		handler: NewHandler(cfg, roller, jwtService, usersService),
+) (*Server, error) {
+	handler, err := NewHandler(cfg, roller, claimsStore, jwtService, usersService)
+	if err != nil {
+		return nil, err
 	}
+	return &Server{
+		handler: handler,
+		roller:  roller,
+	}, nil
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -76,8 +84,12 @@ func (s *Server) ListenAndServe(addr string, certs ...tls.Certificate) error {
 	return nil
 }
 
+// Shutdown stops the http server, and then waits for events published by requests to be handled.
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Printf("[INFO] stopping http server")
 	defer log.Printf("[INFO] http server stopped")
-	return s.httpServer.Shutdown(ctx)
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.roller.Shutdown(ctx)
 }
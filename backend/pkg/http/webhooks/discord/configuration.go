@@ -0,0 +1,65 @@
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Configuration struct {
+	// PublicKey verifies interaction signatures, it's shown on the application page in the Discord developer portal.
+	PublicKey ed25519.PublicKey
+	// RoomID is the room commands are applied to.
+	RoomID rooms.ID
+	// WebhookURL is where rolls and boosts are posted. Notifications are off if it's empty.
+	WebhookURL string
+	// Timeout is how long posting to the webhook may take.
+	Timeout time.Duration
+}
+
+// Enabled returns true if Discord interactions are configured.
+func (c *Configuration) Enabled() bool {
+	return len(c.PublicKey) > 0
+}
+
+func (c *Configuration) Parse() error {
+	publicKey := os.Getenv("DISCORD_PUBLIC_KEY")
+	if publicKey == "" {
+		log.Printf("[INFO] DISCORD_PUBLIC_KEY is not set, discord interactions are disabled")
+		return nil
+	}
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("DISCORD_PUBLIC_KEY must be a hex encoded ed25519 public key")
+	}
+	c.PublicKey = key
+
+	roomID := os.Getenv("DISCORD_ROOM_ID")
+	if roomID == "" {
+		return fmt.Errorf("DISCORD_ROOM_ID is not set")
+	}
+	c.RoomID = rooms.ID(roomID)
+
+	c.WebhookURL = os.Getenv("DISCORD_WEBHOOK_URL")
+	if c.WebhookURL == "" {
+		log.Printf("[WARN] DISCORD_WEBHOOK_URL is not set, discord notifications won't work")
+	}
+
+	c.Timeout = defaultTimeout
+	if timeout := os.Getenv("DISCORD_WEBHOOK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("DISCORD_WEBHOOK_TIMEOUT must be a duration, like 10s: %w", err)
+		}
+		c.Timeout = d
+	}
+
+	return nil
+}
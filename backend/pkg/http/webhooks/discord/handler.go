@@ -0,0 +1,268 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/users"
+	service_users "lunch/pkg/users/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// boostPrefix starts custom ids of boost buttons, the rest is the place id.
+	boostPrefix = "boost:"
+	// maxButtons is how many buttons a message can have: 5 action rows of 5 buttons.
+	maxButtons       = 25
+	maxButtonsPerRow = 5
+)
+
+type Handler struct {
+	cfg          *Configuration
+	roller       *lunch.Roller
+	client       *http.Client
+	usersService *service_users.Service
+}
+
+func NewHandler(cfg *Configuration, roller *lunch.Roller, usersService *service_users.Service) http.Handler {
+	h := &Handler{
+		cfg:          cfg,
+		roller:       roller,
+		client:       &http.Client{Timeout: cfg.Timeout},
+		usersService: usersService,
+	}
+
+	if cfg.WebhookURL != "" {
+		roller.OnRollCreated(h.onRollCreated)
+		roller.OnBoostCreated(h.onBoostCreated)
+	}
+
+	r := chi.NewMux()
+	r.With(middleware.AllowContentType("application/json")).Post("/", h.ServeHTTP)
+
+	return r
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	interaction, err := ParseRequest(r, h.cfg.PublicKey)
+	if err != nil {
+		// Discord expects 401 for requests with invalid signatures
+		log.Printf("[WARN] failed to parse request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("[INFO] incoming interaction: %s %d", interaction.ID, interaction.Type)
+
+	if interaction.Type == interactionTypePing {
+		if err := respondJSON(w, Pong()); err != nil {
+			log.Printf("[ERROR] failed to marshal response: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	sender := interaction.Sender()
+	user := &users.User{ID: users.DiscordID(sender.ID), Name: sender.Name()}
+	if err := h.usersService.Create(r.Context(), user); err != nil {
+		log.Printf("[ERROR] failed to create user: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx := users.NewContext(r.Context(), user)
+	var response *Response
+	switch interaction.Type {
+	case interactionTypeApplicationCommand:
+		response = h.handleCommand(ctx, interaction.Data)
+	case interactionTypeMessageComponent:
+		response = h.handleComponent(ctx, interaction.Data)
+	default:
+		response = BadRequest(fmt.Errorf("unknown interaction type %d", interaction.Type))
+	}
+	if err := respondJSON(w, response); err != nil {
+		log.Printf("[ERROR] failed to marshal response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func respondJSON(w http.ResponseWriter, body interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(body)
+}
+
+func (h *Handler) handleCommand(ctx context.Context, data *InteractionData) *Response {
+	switch data.Name {
+	case "roll":
+		return h.handleRoll(ctx)
+	case "add":
+		return h.handleAdd(ctx, data.StringOption("place"))
+	case "list":
+		return h.handleList(ctx)
+	default:
+		return BadRequest(fmt.Errorf("unknown command '%s'", data.Name))
+	}
+}
+
+func (h *Handler) handleComponent(ctx context.Context, data *InteractionData) *Response {
+	switch {
+	case strings.HasPrefix(data.CustomID, boostPrefix):
+		return h.handleBoost(ctx, places.ID(strings.TrimPrefix(data.CustomID, boostPrefix)))
+	default:
+		return BadRequest(fmt.Errorf("unknown component '%s'", data.CustomID))
+	}
+}
+
+func (h *Handler) handleRoll(ctx context.Context) *Response {
+	roll, err := h.roller.CreateRoll(ctx, h.cfg.RoomID, time.Now())
+	switch {
+	case err == nil:
+		return Ephemeral(NewEmbed("You rolled **%s**", roll.Place.Name))
+	case errors.Is(err, lunch.ErrNoPoints):
+		return Ephemeral(NewEmbed("Failed to roll: no more points left"))
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return Ephemeral(NewEmbed("No places to choose from, add some!"))
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleAdd(ctx context.Context, placeName string) *Response {
+	err := h.roller.CreatePlace(ctx, h.cfg.RoomID, placeName)
+	switch {
+	case err == nil:
+		return Ephemeral(NewEmbed("**%s** added!", strings.TrimSpace(placeName)))
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrAlreadyExists):
+		return BadRequest(err)
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleList(ctx context.Context) *Response {
+	chances, err := h.roller.ListPlaces(ctx, h.cfg.RoomID, time.Now())
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return Ephemeral(NewEmbed("No places to choose from, add some!"))
+	default:
+		return InternalServerError(err)
+	}
+
+	sort.Slice(chances, func(i, j int) bool {
+		return chances[i].Name < chances[j].Name
+	})
+	sort.SliceStable(chances, func(i, j int) bool {
+		return chances[i].Chance < chances[j].Chance
+	})
+
+	quota, err := h.roller.Quota(ctx, h.cfg.RoomID, time.Now())
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	embed := NewEmbed("You have **%d** points left, they reset on %s.", quota.Points, quota.ResetsAt.Format("Monday, January 2"))
+	if quota.FreeRoll {
+		embed.Description += " Today's free roll is still available."
+	}
+	embed.Title = "Odds"
+
+	var rows []*Component
+	for i, chance := range chances {
+		embed.Fields = append(embed.Fields, &Field{
+			Name:   chance.Name,
+			Value:  fmt.Sprintf("%.2f%%", chance.Chance*100),
+			Inline: true,
+		})
+		// places with the lowest odds are first, they need boosts the most
+		if i >= maxButtons {
+			continue
+		}
+		if i%maxButtonsPerRow == 0 {
+			rows = append(rows, ActionRow())
+		}
+		row := rows[len(rows)-1]
+		row.Components = append(row.Components, Button(fmt.Sprintf("Boost %s", chance.Name), boostPrefix+string(chance.ID)))
+	}
+
+	response := Ephemeral(embed)
+	response.Data.Components = rows
+	return response
+}
+
+func (h *Handler) handleBoost(ctx context.Context, placeID places.ID) *Response {
+	err := h.roller.CreateBoost(ctx, h.cfg.RoomID, placeID, time.Now())
+	switch {
+	case err == nil:
+		place, err := h.roller.GetPlace(ctx, h.cfg.RoomID, placeID)
+		if err != nil {
+			return InternalServerError(err)
+		}
+		return Ephemeral(NewEmbed("Boosted **%s**", place.Name))
+	case errors.Is(err, lunch.ErrNoPoints):
+		return Ephemeral(NewEmbed("Failed to boost: no more points left"))
+	case errors.Is(err, lunch.ErrNotFound):
+		return Ephemeral(NewEmbed("Failed to boost: place not found"))
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) onRollCreated(ctx context.Context, roll *lunch.Roll) error {
+	if roll.RoomID != h.cfg.RoomID {
+		return nil
+	}
+	return h.post(ctx, &Message{Embeds: []*Embed{NewEmbed("%s rolled **%s**", userName(roll.User), roll.Place.Name)}})
+}
+
+func (h *Handler) onBoostCreated(ctx context.Context, boost *lunch.Boost) error {
+	if boost.RoomID != h.cfg.RoomID {
+		return nil
+	}
+	return h.post(ctx, &Message{Embeds: []*Embed{NewEmbed("%s boosted **%s**", userName(boost.User), boost.Place.Name)}})
+}
+
+func userName(user *users.User) string {
+	if user == nil {
+		return "Someone"
+	}
+	return user.Name
+}
+
+// post sends the message to the configured channel webhook.
+func (h *Handler) post(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
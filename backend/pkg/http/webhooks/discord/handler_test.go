@@ -0,0 +1,228 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	service_users "lunch/pkg/users/service"
+	storage_users "lunch/pkg/users/storage"
+)
+
+const timestamp = "1669371072"
+
+func recorded(t *testing.T, name string) []byte {
+	body, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func signedRequest(body []byte, privateKey ed25519.PrivateKey) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Signature-Timestamp", timestamp)
+	signature := ed25519.Sign(privateKey, append([]byte(timestamp), body...))
+	r.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+	return r
+}
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := recorded(t, "add.json")
+
+	testCases := []struct {
+		name      string
+		request   func() *http.Request
+		expectErr bool
+	}{
+		{"valid", func() *http.Request { return signedRequest(body, privateKey) }, false},
+		{"signed by someone else", func() *http.Request { return signedRequest(body, otherKey) }, true},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(body, privateKey)
+			r.Body = ioutil.NopCloser(bytes.NewReader(bytes.Replace(body, []byte("Pizza"), []byte("Sushi"), 1)))
+			return r
+		}, true},
+		{"other timestamp", func() *http.Request {
+			r := signedRequest(body, privateKey)
+			r.Header.Set("X-Signature-Timestamp", "1669371073")
+			return r
+		}, true},
+		{"missing signature", func() *http.Request {
+			r := signedRequest(body, privateKey)
+			r.Header.Del("X-Signature-Ed25519")
+			return r
+		}, true},
+		{"malformed signature", func() *http.Request {
+			r := signedRequest(body, privateKey)
+			r.Header.Set("X-Signature-Ed25519", "not hex")
+			return r
+		}, true},
+	}
+
+	for _, testCase := range testCases {
+		interaction, err := ParseRequest(testCase.request(), publicKey)
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", testCase.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testCase.name, err)
+		}
+		if interaction.Type != interactionTypeApplicationCommand || interaction.Data.Name != "add" {
+			t.Errorf("%s: unexpected interaction: %+v", testCase.name, interaction)
+		}
+		if place := interaction.Data.StringOption("place"); place != "Pizza Place" {
+			t.Errorf("%s: unexpected place '%s'", testCase.name, place)
+		}
+		if name := interaction.Sender().Name(); name != "Mason Remote" {
+			t.Errorf("%s: unexpected sender '%s'", testCase.name, name)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fake Discord API, only the webhook is called
+	posted := make(chan *Message, 10)
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/webhooks/1/token" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		msg := &Message{}
+		if err := json.NewDecoder(r.Body).Decode(msg); err != nil {
+			t.Errorf("failed to decode message: %s", err)
+		}
+		posted <- msg
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer discord.Close()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersStore := storage_users.NewBolt(bolt)
+	roller := lunch.New(events.NewBoltStorage(bolt), usersStore)
+
+	handler := NewHandler(&Configuration{
+		PublicKey:  publicKey,
+		RoomID:     rooms.ID("room"),
+		WebhookURL: discord.URL + "/api/webhooks/1/token",
+		Timeout:    time.Second,
+	}, roller, service_users.New(usersStore))
+
+	send := func(body []byte) *Response {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, signedRequest(body, privateKey))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d", w.Code)
+		}
+		response := &Response{}
+		if err := json.NewDecoder(w.Body).Decode(response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	description := func(response *Response) string {
+		if response.Data == nil || len(response.Data.Embeds) == 0 {
+			return ""
+		}
+		return response.Data.Embeds[0].Description
+	}
+
+	waitPosted := func(expected string) {
+		if err := roller.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-posted:
+			if len(msg.Embeds) != 1 || !strings.Contains(msg.Embeds[0].Description, expected) {
+				t.Errorf("expected notification about '%s', got %+v", expected, msg)
+			}
+		default:
+			t.Fatalf("expected notification about '%s'", expected)
+		}
+	}
+
+	if response := send(recorded(t, "ping.json")); response.Type != responseTypePong {
+		t.Errorf("expected pong, got %+v", response)
+	}
+
+	if got := description(send(recorded(t, "roll.json"))); !strings.Contains(got, "No places") {
+		t.Errorf("roll: unexpected response '%s'", got)
+	}
+
+	if got := description(send(recorded(t, "add.json"))); !strings.Contains(got, "**Pizza Place** added") {
+		t.Errorf("add: unexpected response '%s'", got)
+	}
+	if got := description(send(recorded(t, "add.json"))); !strings.Contains(got, "already exists") {
+		t.Errorf("add: unexpected response '%s'", got)
+	}
+
+	list := send(recorded(t, "list.json"))
+	if list.Data.Flags != flagEphemeral || len(list.Data.Embeds[0].Fields) != 1 || list.Data.Embeds[0].Fields[0].Value != "100.00%" {
+		t.Fatalf("list: unexpected response %+v", list.Data)
+	}
+	if len(list.Data.Components) != 1 || len(list.Data.Components[0].Components) != 1 {
+		t.Fatalf("list: expected one boost button, got %+v", list.Data.Components)
+	}
+	customID := list.Data.Components[0].Components[0].CustomID
+
+	if got := description(send(recorded(t, "roll.json"))); !strings.Contains(got, "You rolled **Pizza Place**") {
+		t.Errorf("roll: unexpected response '%s'", got)
+	}
+	waitPosted("Mason Remote rolled **Pizza Place**")
+
+	boost := bytes.Replace(recorded(t, "boost.json"), []byte("boost:PLACE_ID"), []byte(customID), 1)
+	if got := description(send(boost)); !strings.Contains(got, "Boosted **Pizza Place**") {
+		t.Errorf("boost: unexpected response '%s'", got)
+	}
+	waitPosted("Mason Remote boosted **Pizza Place**")
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(recorded(t, "ping.json")))
+	unsigned.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, unsigned)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected unsigned request to be rejected, got %d", w.Code)
+	}
+}
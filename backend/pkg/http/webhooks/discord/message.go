@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"fmt"
+	"log"
+)
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-response-object-interaction-callback-type
+type responseType int
+
+const (
+	responseTypePong                     responseType = 1
+	responseTypeChannelMessageWithSource responseType = 4
+)
+
+// flagEphemeral makes the message visible only by the user who sent the interaction.
+const flagEphemeral = 1 << 6
+
+const (
+	colorBlurple = 0x5865f2
+	colorRed     = 0xed4245
+)
+
+// https://discord.com/developers/docs/resources/channel#embed-object-embed-field-structure
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// https://discord.com/developers/docs/resources/channel#embed-object
+type Embed struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Color       int      `json:"color,omitempty"`
+	Fields      []*Field `json:"fields,omitempty"`
+}
+
+func NewEmbed(format string, a ...interface{}) *Embed {
+	return &Embed{
+		Description: fmt.Sprintf(format, a...),
+		Color:       colorBlurple,
+	}
+}
+
+type componentType int
+
+const (
+	componentTypeActionRow componentType = 1
+	componentTypeButton    componentType = 2
+)
+
+type buttonStyle int
+
+const (
+	buttonStylePrimary buttonStyle = 1
+)
+
+// https://discord.com/developers/docs/interactions/message-components
+type Component struct {
+	Type       componentType `json:"type"`
+	Style      buttonStyle   `json:"style,omitempty"`
+	Label      string        `json:"label,omitempty"`
+	CustomID   string        `json:"custom_id,omitempty"`
+	Components []*Component  `json:"components,omitempty"`
+}
+
+// https://discord.com/developers/docs/interactions/message-components#action-rows
+func ActionRow(components ...*Component) *Component {
+	return &Component{
+		Type:       componentTypeActionRow,
+		Components: components,
+	}
+}
+
+// https://discord.com/developers/docs/interactions/message-components#buttons
+func Button(label, customID string) *Component {
+	return &Component{
+		Type:     componentTypeButton,
+		Style:    buttonStylePrimary,
+		Label:    label,
+		CustomID: customID,
+	}
+}
+
+// Message is both the data of an interaction response, and a message posted to a webhook.
+// https://discord.com/developers/docs/resources/webhook#execute-webhook
+type Message struct {
+	Content    string       `json:"content,omitempty"`
+	Embeds     []*Embed     `json:"embeds,omitempty"`
+	Components []*Component `json:"components,omitempty"`
+	Flags      int          `json:"flags,omitempty"`
+}
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-response-object
+type Response struct {
+	Type responseType `json:"type"`
+	Data *Message     `json:"data,omitempty"`
+}
+
+func Pong() *Response {
+	return &Response{Type: responseTypePong}
+}
+
+// Ephemeral replies with a message visible only by the user.
+func Ephemeral(embeds ...*Embed) *Response {
+	return &Response{
+		Type: responseTypeChannelMessageWithSource,
+		Data: &Message{Embeds: embeds, Flags: flagEphemeral},
+	}
+}
+
+// InChannel replies with a message visible by everyone in the channel.
+func InChannel(embeds ...*Embed) *Response {
+	return &Response{
+		Type: responseTypeChannelMessageWithSource,
+		Data: &Message{Embeds: embeds},
+	}
+}
+
+// BadRequest tells the user that the request was invalid.
+func BadRequest(err error) *Response {
+	embed := NewEmbed("%s", err)
+	embed.Color = colorRed
+	return Ephemeral(embed)
+}
+
+// InternalServerError tells the user that the request failed.
+func InternalServerError(err error) *Response {
+	log.Printf("[ERROR] %s", err)
+	embed := NewEmbed("Sorry, that didn't work. Try again or contact the app administrator.")
+	embed.Color = colorRed
+	return Ephemeral(embed)
+}
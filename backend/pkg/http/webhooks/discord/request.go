@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-object-interaction-type
+type interactionType int
+
+const (
+	interactionTypePing               interactionType = 1
+	interactionTypeApplicationCommand interactionType = 2
+	interactionTypeMessageComponent   interactionType = 3
+)
+
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// GlobalName is the display name of the user, if set.
+	GlobalName string `json:"global_name"`
+}
+
+// Name returns the display name of the user.
+func (u *User) Name() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+type Member struct {
+	User *User `json:"user"`
+}
+
+type Option struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+// InteractionData is the data of a command, or of a message component.
+type InteractionData struct {
+	// Name is the name of the command.
+	Name    string    `json:"name"`
+	Options []*Option `json:"options"`
+	// CustomID is the id of the component.
+	CustomID string `json:"custom_id"`
+}
+
+// StringOption returns the value of the string option, or an empty string if it's not set.
+func (d *InteractionData) StringOption(name string) string {
+	for _, option := range d.Options {
+		if option.Name != name {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(option.Value, &value); err != nil {
+			return ""
+		}
+		return value
+	}
+	return ""
+}
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding#interaction-object
+type Interaction struct {
+	ID   string           `json:"id"`
+	Type interactionType  `json:"type"`
+	Data *InteractionData `json:"data"`
+	// Member is set in servers, and User in direct messages.
+	Member *Member `json:"member"`
+	User   *User   `json:"user"`
+}
+
+// Sender returns the user who sent the interaction.
+func (i *Interaction) Sender() *User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// https://discord.com/developers/docs/interactions/receiving-and-responding#security-and-authorization
+func verifySignature(header http.Header, body []byte, publicKey ed25519.PublicKey) error {
+	signature := header.Get("X-Signature-Ed25519")
+	if signature == "" {
+		return fmt.Errorf("X-Signature-Ed25519 is missing")
+	}
+
+	timestamp := header.Get("X-Signature-Timestamp")
+	if timestamp == "" {
+		return fmt.Errorf("X-Signature-Timestamp is missing")
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature")
+	}
+
+	message := append([]byte(timestamp), body...)
+	if !ed25519.Verify(publicKey, message, sig) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// ParseRequest reads an interaction, and verifies that it's signed by Discord.
+func ParseRequest(r *http.Request, publicKey ed25519.PublicKey) (*Interaction, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	if err := verifySignature(r.Header, body, publicKey); err != nil {
+		return nil, fmt.Errorf("failed to verify request signature: %w", err)
+	}
+
+	interaction := &Interaction{}
+	if err := json.Unmarshal(body, interaction); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+	}
+	if interaction.Type != interactionTypePing && interaction.Sender() == nil {
+		return nil, fmt.Errorf("sender is missing")
+	}
+	if interaction.Type != interactionTypePing && interaction.Data == nil {
+		return nil, fmt.Errorf("data is missing")
+	}
+	return interaction, nil
+}
@@ -4,15 +4,21 @@ import (
 	"fmt"
 	"net/http"
 
+	"lunch/pkg/claims"
+	"lunch/pkg/http/webhooks/discord"
 	"lunch/pkg/http/webhooks/slack"
+	"lunch/pkg/http/webhooks/teams"
 	"lunch/pkg/lunch"
+	client_slack "lunch/pkg/slack"
 	service_users "lunch/pkg/users/service"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Configuration struct {
-	Slack *slack.Configuration
+	Slack   *slack.Configuration
+	Teams   *teams.Configuration
+	Discord *discord.Configuration
 }
 
 func (c *Configuration) Parse() error {
@@ -20,11 +26,25 @@ func (c *Configuration) Parse() error {
 	if err := c.Slack.Parse(); err != nil {
 		return fmt.Errorf("failed to parse slack configuration: %w", err)
 	}
+	c.Teams = &teams.Configuration{}
+	if err := c.Teams.Parse(); err != nil {
+		return fmt.Errorf("failed to parse teams configuration: %w", err)
+	}
+	c.Discord = &discord.Configuration{}
+	if err := c.Discord.Parse(); err != nil {
+		return fmt.Errorf("failed to parse discord configuration: %w", err)
+	}
 	return nil
 }
 
-func Handler(cfg *Configuration, roller *lunch.Roller, usersService *service_users.Service) http.Handler {
+func Handler(cfg *Configuration, roller *lunch.Roller, claimsStore claims.Storage, usersService *service_users.Service, slackClient *client_slack.Client) http.Handler {
 	r := chi.NewMux()
-	r.Mount("/slack", slack.NewHandler(cfg.Slack, roller, usersService))
+	r.Mount("/slack", slack.NewHandler(cfg.Slack, roller, claimsStore, usersService, slackClient))
+	if cfg.Teams.Enabled() {
+		r.Mount("/teams", teams.NewHandler(cfg.Teams, roller, usersService))
+	}
+	if cfg.Discord.Enabled() {
+		r.Mount("/discord", discord.NewHandler(cfg.Discord, roller, usersService))
+	}
 	return r
 }
@@ -1,19 +1,34 @@
 package slack
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 )
 
 type Configuration struct {
-	SigningSecret  string
-	BotAccessToken string
+	SigningSecret string
+	// SkipVerification turns off webhooks' signature verification, for local development only.
+	SkipVerification bool
+	BotAccessToken   string
 }
 
 func (c *Configuration) Parse() error {
+	if skip := os.Getenv("SLACK_SKIP_VERIFICATION"); skip != "" {
+		skipVerification, err := strconv.ParseBool(skip)
+		if err != nil {
+			return fmt.Errorf("failed to parse SLACK_SKIP_VERIFICATION: %w", err)
+		}
+		c.SkipVerification = skipVerification
+	}
+
 	signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
-	if signingSecret == "" {
-		log.Printf("[WARN] SLACK_SIGNING_SECRET is not set, webhooks' signature will not be verified")
+	switch {
+	case signingSecret == "" && !c.SkipVerification:
+		return fmt.Errorf("SLACK_SIGNING_SECRET is not set, set SLACK_SKIP_VERIFICATION=true to run without it in development")
+	case c.SkipVerification:
+		log.Printf("[WARN] SLACK_SKIP_VERIFICATION is set, webhooks' signature will not be verified")
 	}
 	c.SigningSecret = signingSecret
 
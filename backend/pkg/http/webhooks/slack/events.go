@@ -0,0 +1,201 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/channels"
+	client_slack "lunch/pkg/slack"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+// seenEventsTTL is how long event ids are claimed. Slack gives up retrying long before that.
+const seenEventsTTL = time.Hour
+
+// claimEvent returns false if the event was already claimed, by this or another server instance.
+func (h *Handler) claimEvent(ctx context.Context, eventID string, now time.Time) (bool, error) {
+	err := h.claimsStore.Claim(ctx, fmt.Sprintf("slack/event/%s", eventID), now, now.Add(seenEventsTTL))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, claims.ErrClaimed):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+}
+
+func (h *Handler) handleEvent(ctx context.Context, event *Event) error {
+	user, err := h.eventUser(ctx, event.User)
+	if err != nil {
+		return err
+	}
+	ctx = users.NewContext(ctx, user)
+
+	switch event.Type {
+	case "app_home_opened":
+		if event.Tab != "home" {
+			return nil
+		}
+		return h.publishHome(ctx, user)
+	case "app_mention":
+		return h.handleMention(ctx, event)
+	default:
+		return nil
+	}
+}
+
+// eventUser returns the user who triggered the event. Events don't include user names, so they are fetched from
+// profiles. Users whose profile can't be fetched are named after their ids until the next try.
+func (h *Handler) eventUser(ctx context.Context, userID string) (*users.User, error) {
+	user, err := h.usersService.Get(ctx, users.ID(userID))
+	switch {
+	case err == nil && user.Name != userID:
+		return user, nil
+	case err != nil && !errors.Is(err, storage_users.ErrNotFound):
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	name := userID
+	if profile, err := h.client.UserInfo(ctx, h.cfg.BotAccessToken, userID); err != nil {
+		log.Printf("[WARN] failed to get profile of %s: %s", userID, err)
+	} else {
+		name = profile.Name
+	}
+
+	user = &users.User{ID: users.ID(userID), Name: name}
+	if err := h.usersService.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// maxHomeBlocks is how many blocks Slack allows in a view.
+const maxHomeBlocks = 100
+
+// recentRolls is how many rolls are shown per room in App Home.
+const recentRolls = 5
+
+// publishHome shows odds, quota and recent rolls of every room the user is a member of.
+func (h *Handler) publishHome(ctx context.Context, user *users.User) error {
+	rr, err := h.roller.ListRooms(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list rooms: %w", err)
+	}
+
+	sort.Slice(rr, func(i, j int) bool {
+		return rr[i].Name < rr[j].Name
+	})
+
+	bb := []*Block{}
+	if len(rr) == 0 {
+		bb = append(bb, Section(Markdown("You are not a member of any room yet.")))
+	}
+	for _, room := range rr {
+		roomBlocks, err := h.homeRoom(ctx, room)
+		if err != nil {
+			return err
+		}
+		bb = append(bb, roomBlocks...)
+	}
+	if len(bb) > maxHomeBlocks {
+		bb = bb[:maxHomeBlocks]
+	}
+
+	if err := h.client.PublishView(ctx, h.cfg.BotAccessToken, &client_slack.PublishViewRequest{
+		UserID: string(user.ID),
+		View: &View{
+			Type:   viewTypeHome,
+			Blocks: bb,
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to publish home: %w", err)
+	}
+	return nil
+}
+
+func (h *Handler) homeRoom(ctx context.Context, room *lunch.Room) ([]*Block, error) {
+	bb := []*Block{Section(Markdown("*%s*", room.Name))}
+
+	odds, err := h.list(ctx, room.ID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list places: %w", err)
+	}
+	bb = append(bb, odds...)
+
+	rolls, err := h.roller.ListRolls(ctx, room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rolls: %w", err)
+	}
+	sort.Slice(rolls, func(i, j int) bool {
+		return rolls[i].Time.After(rolls[j].Time)
+	})
+	if len(rolls) > recentRolls {
+		rolls = rolls[:recentRolls]
+	}
+	if len(rolls) > 0 {
+		lines := make([]string, 0, len(rolls))
+		for _, roll := range rolls {
+			lines = append(lines, fmt.Sprintf(
+				"<!date^%d^{date_short}|%s> *%s*, rolled by %s",
+				roll.Time.Unix(), roll.Time.Format("Jan 2"), roll.Place.Name, mention(roll.UserID),
+			))
+		}
+		bb = append(bb, Section(Markdown("Recent rolls:\n%s", strings.Join(lines, "\n"))))
+	}
+
+	return append(bb, Divider()), nil
+}
+
+// handleMention handles commands like "@lunch roll" in a channel bound to a room.
+func (h *Handler) handleMention(ctx context.Context, event *Event) error {
+	words := strings.Fields(event.Text)
+	// the first word is the mention of the app itself
+	if len(words) > 0 && strings.HasPrefix(words[0], "<@") {
+		words = words[1:]
+	}
+
+	reply := func(text string, blocks ...*Block) error {
+		_, err := h.postMessage(ctx, event.Channel, event.TS, text, blocks...)
+		return err
+	}
+
+	if len(words) == 0 || words[0] != "roll" {
+		return reply("Try @lunch roll")
+	}
+
+	channel, err := h.roller.GetChannel(ctx, channels.ID(event.Channel))
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNotFound):
+		return reply(
+			"This channel is not bound to a room, use /lunch bind <room> to bind it",
+			Section(Markdown("This channel is not bound to a room, use `/lunch bind <room>` to bind it")),
+		)
+	default:
+		return err
+	}
+
+	roll, err := h.roller.CreateRoll(ctx, channel.RoomID, time.Now())
+	switch {
+	case err == nil:
+		return reply(
+			fmt.Sprintf("%s rolled %s", mention(roll.UserID), roll.Place.Name),
+			Section(Markdown("%s rolled *%s*", mention(roll.UserID), roll.Place.Name)),
+		)
+	case errors.Is(err, lunch.ErrNoPoints):
+		return reply(fmt.Sprintf("%s, you have no more points left", mention(users.ID(event.User))))
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return reply("No places to choose from, add some!")
+	default:
+		return err
+	}
+}
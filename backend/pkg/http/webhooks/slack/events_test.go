@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	client_slack "lunch/pkg/slack"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	service_users "lunch/pkg/users/service"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestClaimEvent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// two server instances
+	claimsStore := testClaims(t)
+	first, second := &Handler{claimsStore: claimsStore}, &Handler{claimsStore: claimsStore}
+
+	claim := func(h *Handler, eventID string, at time.Time) bool {
+		t.Helper()
+
+		claimed, err := h.claimEvent(ctx, eventID, at)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return claimed
+	}
+
+	if !claim(first, "Ev1", now) {
+		t.Error("new event must be claimed")
+	}
+	if claim(second, "Ev1", now.Add(time.Minute)) {
+		t.Error("retried event must not be claimed again")
+	}
+	if !claim(second, "Ev2", now.Add(time.Minute)) {
+		t.Error("new event must be claimed")
+	}
+	if !claim(first, "Ev1", now.Add(seenEventsTTL+time.Minute)) {
+		t.Error("event must be claimable after ttl")
+	}
+}
+
+func TestEventUser(t *testing.T) {
+	t.Parallel()
+
+	profiles := map[string]string{"U1": "spengler"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := profiles[r.URL.Query().Get("user")]
+		if !ok {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"user_not_found"}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"ok":true,"user":{"id":"%s","name":"%s"}}`, r.URL.Query().Get("user"), name)
+	}))
+	defer server.Close()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersService := service_users.New(storage_users.NewBolt(bolt))
+	h := &Handler{
+		cfg:          &Configuration{BotAccessToken: "token"},
+		client:       client_slack.New(&client_slack.Configuration{BaseURL: server.URL, Timeout: time.Second}),
+		usersService: usersService,
+	}
+	ctx := context.Background()
+
+	name := func(userID string) string {
+		t.Helper()
+
+		user, err := h.eventUser(ctx, userID)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		stored, err := usersService.Get(ctx, users.ID(userID))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if stored.Name != user.Name {
+			t.Errorf("expected %s to be stored, got %s", user.Name, stored.Name)
+		}
+		return user.Name
+	}
+
+	if got := name("U1"); got != "spengler" {
+		t.Errorf("expected the name from the profile, got %s", got)
+	}
+
+	// named after the id until the profile can be fetched
+	if got := name("U2"); got != "U2" {
+		t.Errorf("expected the id, got %s", got)
+	}
+	profiles["U2"] = "venkman"
+	if got := name("U2"); got != "venkman" {
+		t.Errorf("expected the name from the profile, got %s", got)
+	}
+}
+
+func TestParseRequest_event(t *testing.T) {
+	t.Parallel()
+
+	body := `{
+		"type": "event_callback",
+		"event_id": "Ev1",
+		"event": {"type": "app_mention", "user": "U1", "channel": "C1", "text": "<@U2> roll", "ts": "1.1"}
+	}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	_, _, challange, event, err := ParseRequest(r, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if challange != nil {
+		t.Errorf("unexpected challange: %+v", challange)
+	}
+	if event == nil {
+		t.Fatal("expected an event")
+	}
+	if event.EventID != "Ev1" || event.Event.Type != "app_mention" || event.Event.Channel != "C1" {
+		t.Errorf("unexpected event: %+v %+v", event, event.Event)
+	}
+}
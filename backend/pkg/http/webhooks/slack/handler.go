@@ -1,7 +1,6 @@
 package slack
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,10 +8,19 @@ import (
 	"log"
 	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"lunch/pkg/claims"
 	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/notifications"
 	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/schedules"
+	client_slack "lunch/pkg/slack"
 	"lunch/pkg/users"
 	service_users "lunch/pkg/users/service"
 
@@ -21,26 +29,34 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-const roomID = "69c83096-995a-48ce-b843-80a926b0a9ec"
-
 type Handler struct {
 	cfg          *Configuration
 	roller       *lunch.Roller
-	client       *http.Client
+	client       *client_slack.Client
+	verifier     *Verifier
 	usersService *service_users.Service
+	claimsStore  claims.Storage
+
+	summaries roomLocks
 }
 
-func NewHandler(cfg *Configuration, roller *lunch.Roller, usersService *service_users.Service) http.Handler {
+func NewHandler(cfg *Configuration, roller *lunch.Roller, claimsStore claims.Storage, usersService *service_users.Service, client *client_slack.Client) http.Handler {
 	h := &Handler{
 		cfg:          cfg,
 		roller:       roller,
-		client:       &http.Client{},
+		client:       client,
 		usersService: usersService,
+		claimsStore:  claimsStore,
+	}
+	if !cfg.SkipVerification {
+		h.verifier = NewVerifier(cfg.SigningSecret, claimsStore)
 	}
 
 	roller.OnRollCreated(h.onRollCreated)
 	roller.OnBoostCreated(h.onBoostCreated)
+	roller.OnVetoCreated(h.onVetoCreated)
 	roller.OnPlaceCreated(h.onPlaceCreated)
+	roller.OnReminderDue(h.onReminderDue)
 
 	r := chi.NewMux()
 	r.With(middleware.AllowContentType("application/json", "application/x-www-form-urlencoded")).Post("/", h.ServeHTTP)
@@ -49,7 +65,7 @@ func NewHandler(cfg *Configuration, roller *lunch.Roller, usersService *service_
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	command, actions, challange, err := ParseRequest(r, h.cfg.SigningSecret)
+	command, actions, challange, event, err := ParseRequest(r, h.verifier)
 	if err != nil {
 		log.Printf("[WARN] failed to parse request: %s", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -67,8 +83,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		channelID := ""
+		if actions.Channel != nil {
+			channelID = actions.Channel.ID
+		}
+
 		ctx := users.NewContext(r.Context(), user)
-		response := h.handleActions(ctx, actions.ResponseUrl, actions.Actions...)
+		if actions.Type == interactionTypeViewSubmission {
+			h.respondView(w, h.handleViewSubmission(ctx, actions.View))
+			return
+		}
+
+		response := h.handleActions(ctx, channelID, actions.ResponseUrl, actions.Actions...)
 		if err := respondJSON(w, response); err != nil {
 			log.Printf("[ERROR] failed to marshal response: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -86,11 +112,36 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		ctx := users.NewContext(r.Context(), user)
 		response := h.handleCommand(ctx, command)
+		if response == nil {
+			// nothing to say, for example when a modal is opened instead
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 		if err := respondJSON(w, response); err != nil {
 			log.Printf("[ERROR] failed to marshal response: %s", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+	case event != nil:
+		log.Printf("[INFO] incoming event: %s %s", event.EventID, event.Event.Type)
+
+		// Slack retries events that were not acknowledged in time, handle each one only once
+		if claimed, err := h.claimEvent(r.Context(), event.EventID, time.Now()); err != nil {
+			log.Printf("[ERROR] %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		} else if !claimed {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// events must be acknowledged within 3 seconds, so they are handled in the background
+		h.roller.Go(func(ctx context.Context) {
+			if err := h.handleEvent(ctx, event.Event); err != nil {
+				log.Printf("[ERROR] failed to handle event %s: %s", event.EventID, err)
+			}
+		})
+		w.WriteHeader(http.StatusOK)
 	case challange != nil:
 		log.Printf("[INFO] incoming challange: %+v", challange)
 		if err := respondPlainText(w, []byte(challange.Challenge)); err != nil {
@@ -117,7 +168,26 @@ func respondJSON(w http.ResponseWriter, body interface{}) error {
 	return json.NewEncoder(w).Encode(body)
 }
 
-func (h *Handler) list(ctx context.Context) ([]*Block, error) {
+// channelRoomID returns id of the room the channel is bound to. If the channel is not bound to any room,
+// a message explaining how to bind it is returned instead.
+func (h *Handler) channelRoomID(ctx context.Context, channelID string) (rooms.ID, *Message) {
+	channel, err := h.roller.GetChannel(ctx, channels.ID(channelID))
+	switch {
+	case err == nil:
+		return channel.RoomID, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return "", Ephemeral(
+			"This channel is not bound to a room, use /lunch bind <room> to bind it",
+			Section(Markdown("This channel is not bound to a room, use `/lunch bind <room>` to bind it")),
+		)
+	default:
+		return "", InternalServerError(err)
+	}
+}
+
+// list renders places with their odds. Buttons need the channel to be bound to the room, so they are left out
+// in direct messages.
+func (h *Handler) list(ctx context.Context, roomID rooms.ID, withButtons bool) ([]*Block, error) {
 	chances, err := h.roller.ListPlaces(ctx, roomID, time.Now())
 	if err != nil {
 		return nil, err
@@ -137,77 +207,195 @@ func (h *Handler) list(ctx context.Context) ([]*Block, error) {
 	}
 
 	for _, chance := range chances {
-		bb = append(bb, SectionFields(
-			[]*TextBlock{
-				PlainText("%s", chance.Name),
-				PlainText("%.2f%%", chance.Chance*100),
-			},
-			WithButton(PlainText("Boost"), "boost", string(chance.ID)),
-		))
+		bb = append(bb, SectionFields([]*TextBlock{
+			PlainText("%s", chance.Name),
+			PlainText("%.2f%%", chance.Chance*100),
+		}))
+		if withButtons {
+			bb = append(bb, Actions(
+				Button(PlainText("Boost"), "boost", string(chance.ID)),
+				Button(PlainText("Veto"), "veto", string(chance.ID)),
+			))
+		}
+	}
+
+	quota, err := h.roller.Quota(ctx, roomID, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
-	return bb, nil
+	return append(bb, Divider(), quotaBlock(quota)), nil
 }
 
-func (h *Handler) asyncPost(url string, msg *Message) error {
-	body := &bytes.Buffer{}
-	if err := json.NewEncoder(body).Encode(msg); err != nil {
-		return fmt.Errorf("failed to marshal response: %w", err)
+func quotaBlock(quota *lunch.Quota) *Block {
+	text := Markdown("You have *%d* points left, they reset on %s.", quota.Points, quota.ResetsAt.Format("Monday, January 2"))
+	if quota.FreeRoll {
+		text.Text += " Today's free roll is still available."
 	}
+	return Section(text)
+}
 
-	resp, err := h.client.Post(url, "application/json", body)
-	if err != nil {
+func (h *Handler) asyncPost(ctx context.Context, url string, msg *Message) error {
+	if err := h.client.Respond(ctx, url, msg); err != nil {
 		return fmt.Errorf("failed to post response to slack: %w", err)
 	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to post response to slack: %s", resp.Status)
+func (h *Handler) handleBoost(ctx context.Context, channelID, responseURL string, placeID places.ID) error {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return h.asyncPost(ctx, responseURL, msg)
 	}
 
-	return nil
+	err := h.roller.CreateBoost(ctx, roomID, placeID, time.Now())
+	switch {
+	case err == nil:
+		responseBlocks, err := h.list(ctx, roomID, true)
+		if err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return h.asyncPost(ctx, responseURL, ReplaceEphemeral("Boosting", responseBlocks...))
+	case errors.Is(err, lunch.ErrNoPoints):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to boost: no more points left"))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to boost: place not found"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
 }
 
-func (h *Handler) handleBoost(ctx context.Context, responseURL string, placeID places.ID) error {
-	err := h.roller.CreateBoost(ctx, roomID, placeID, time.Now())
+func (h *Handler) handleVeto(ctx context.Context, channelID, responseURL string, placeID places.ID) error {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return h.asyncPost(ctx, responseURL, msg)
+	}
+
+	err := h.roller.CreateVeto(ctx, roomID, placeID, time.Now())
 	switch {
 	case err == nil:
-		responseBlocks, err := h.list(ctx)
+		responseBlocks, err := h.list(ctx, roomID, true)
 		if err != nil {
-			return h.asyncPost(responseURL, InternalServerError(err))
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
 		}
-		return h.asyncPost(responseURL, ReplaceEphemeral("Boosting", responseBlocks...))
+		return h.asyncPost(ctx, responseURL, ReplaceEphemeral("Vetoing", responseBlocks...))
 	case errors.Is(err, lunch.ErrNoPoints):
-		return h.asyncPost(responseURL, Ephemeral("Failed to boost: no more points left"))
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to veto: no more points left"))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to veto: place not found"))
 	default:
-		return h.asyncPost(responseURL, InternalServerError(err))
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
 	}
 }
 
-func (h *Handler) handleActions(ctx context.Context, responseURL string, actions ...*Action) error {
+func (h *Handler) handleRestore(ctx context.Context, channelID, responseURL string, placeID places.ID) error {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return h.asyncPost(ctx, responseURL, msg)
+	}
+
+	err := h.roller.RestorePlace(ctx, roomID, placeID)
+	switch {
+	case err == nil:
+		return h.asyncPost(ctx, responseURL, ReplaceEphemeral("Restored", Section(Markdown("Restored!"))))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to restore: place not found"))
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to restore: only place creator or room owner can restore it"))
+	case errors.Is(err, lunch.ErrAlreadyExists):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to restore: a place with the same name was added since"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+}
+
+func (h *Handler) handleUndo(ctx context.Context, responseURL string, roomID rooms.ID) error {
+	err := h.roller.Undo(ctx, roomID, time.Now())
+	switch {
+	case err == nil:
+		return h.asyncPost(ctx, responseURL, ReplaceEphemeral("Undone", Section(Markdown("Undone, your point is back"))))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to undo: nothing to undo"))
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to undo: it's too late"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+}
+
+func (h *Handler) handleActions(ctx context.Context, channelID, responseURL string, actions ...*Action) error {
 	if len(actions) != 1 {
-		return h.asyncPost(responseURL, BadRequest(fmt.Errorf("unexpected number of actions: %d", len(actions))))
+		return h.asyncPost(ctx, responseURL, BadRequest(fmt.Errorf("unexpected number of actions: %d", len(actions))))
 	}
 	action := actions[0]
 
 	log.Printf("[INFO] incoming action: %+v", action)
 	switch action.ActionID {
 	case "boost":
-		if err := h.handleBoost(ctx, responseURL, places.ID(action.Value)); err != nil {
-			return h.asyncPost(responseURL, InternalServerError(err))
+		if err := h.handleBoost(ctx, channelID, responseURL, places.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "veto":
+		if err := h.handleVeto(ctx, channelID, responseURL, places.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "vote":
+		if err := h.handleVote(ctx, channelID, responseURL, places.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "close_poll":
+		if err := h.handleClosePoll(ctx, channelID, responseURL); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "undo":
+		if err := h.handleUndo(ctx, responseURL, rooms.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "roll":
+		return h.asyncPost(ctx, responseURL, h.roll(ctx, rooms.ID(action.Value)))
+	case "odds":
+		if err := h.handleOdds(ctx, responseURL, rooms.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "mute_reminders":
+		if err := h.handleMuteReminders(ctx, responseURL, rooms.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return nil
+	case "restore":
+		if err := h.handleRestore(ctx, channelID, responseURL, places.ID(action.Value)); err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
 		}
 		return nil
 	default:
-		return h.asyncPost(responseURL, BadRequest(fmt.Errorf("unknown action '%s'", action.ActionID)))
+		return h.asyncPost(ctx, responseURL, BadRequest(fmt.Errorf("unknown action '%s'", action.ActionID)))
+	}
+}
+
+func (h *Handler) handleRoll(ctx context.Context, channelID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
 	}
+	return h.roll(ctx, roomID)
 }
 
-func (h *Handler) handleRoll(ctx context.Context) *Message {
+func (h *Handler) roll(ctx context.Context, roomID rooms.ID) *Message {
 	roll, err := h.roller.CreateRoll(ctx, roomID, time.Now())
 	switch {
 	case err == nil:
 		return Ephemeral(
-			fmt.Sprintf("You rolled %s", roll.Place.Name),         // Used in notifications
-			Section(Markdown("You rolled *%s*", roll.Place.Name)), // Used in app
+			fmt.Sprintf("You rolled %s", roll.Place.Name), // Used in notifications
+			SectionText( // Used in app
+				Markdown("You rolled *%s*", roll.Place.Name),
+				WithButton(PlainText("Undo"), "undo", string(roomID)),
+			),
 		)
 	case errors.Is(err, lunch.ErrNoPoints):
 		return Ephemeral("Failed to roll: no more points left")
@@ -218,8 +406,19 @@ func (h *Handler) handleRoll(ctx context.Context) *Message {
 	}
 }
 
-func (h *Handler) handleAdd(ctx context.Context, placeName string) *Message {
-	if err := h.roller.CreatePlace(ctx, roomID, placeName); err != nil {
+func (h *Handler) handleAdd(ctx context.Context, channelID, placeName string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	placeName = strings.TrimSpace(placeName)
+	err := h.roller.CreatePlace(ctx, roomID, placeName)
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrAlreadyExists):
+		return BadRequest(err)
+	default:
 		return InternalServerError(err)
 	}
 	return Ephemeral(
@@ -228,8 +427,75 @@ func (h *Handler) handleAdd(ctx context.Context, placeName string) *Message {
 	)
 }
 
-func (h *Handler) handleList(ctx context.Context) *Message {
-	responseBlocks, err := h.list(ctx)
+func (h *Handler) handleRemove(ctx context.Context, channelID, placeName string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	pp, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return InternalServerError(err)
+	}
+
+	var place *lunch.Place
+	for _, p := range pp {
+		if strings.EqualFold(p.Name, placeName) {
+			place = p
+			break
+		}
+	}
+	if place == nil {
+		return Ephemeral(fmt.Sprintf("%s not found", placeName))
+	}
+
+	err = h.roller.DeletePlace(ctx, roomID, place.ID)
+	switch {
+	case err == nil:
+		return Ephemeral(
+			fmt.Sprintf("%s removed", place.Name),
+			SectionFields(
+				[]*TextBlock{Markdown("*%s* removed!", place.Name)},
+				WithButton(PlainText("Restore"), "restore", string(place.ID)),
+			),
+		)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return Ephemeral("Failed to remove: only place creator or room owner can remove it")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleOdds(ctx context.Context, responseURL string, roomID rooms.ID) error {
+	responseBlocks, err := h.list(ctx, roomID, false)
+	if err != nil {
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+	return h.asyncPost(ctx, responseURL, Ephemeral("Odds", responseBlocks...))
+}
+
+func (h *Handler) handleMuteReminders(ctx context.Context, responseURL string, roomID rooms.ID) error {
+	err := h.roller.MuteReminders(ctx, roomID, true, time.Now())
+	switch {
+	case err == nil:
+		return h.asyncPost(ctx, responseURL, ReplaceEphemeral(
+			"Reminders are off",
+			Section(Markdown("Reminders are off, use `/lunch reminders on` in the room channel to turn them back on")),
+		))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to turn reminders off: room not found"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+}
+
+func (h *Handler) handleList(ctx context.Context, channelID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	responseBlocks, err := h.list(ctx, roomID, true)
 	if err != nil {
 		return InternalServerError(err)
 	}
@@ -240,136 +506,679 @@ func (h *Handler) handleCommand(ctx context.Context, cmd *CommandRequest) *Messa
 	log.Printf("[INFO] incoming command: %+v", cmd)
 	switch cmd.Command {
 	case "/roll":
-		return h.handleRoll(ctx)
+		return h.handleRoll(ctx, cmd.ChannelID)
 	case "/add":
-		return h.handleAdd(ctx, cmd.Text)
+		if strings.TrimSpace(cmd.Text) == "" {
+			return h.handleAddModal(ctx, cmd.ChannelID, cmd.TriggerID)
+		}
+		return h.handleAdd(ctx, cmd.ChannelID, cmd.Text)
 	case "/list":
-		return h.handleList(ctx)
+		return h.handleList(ctx, cmd.ChannelID)
+	case "/remove":
+		return h.handleRemove(ctx, cmd.ChannelID, cmd.Text)
+	case "/lunch":
+		return h.handleLunch(ctx, cmd)
 	default:
 		return BadRequest(fmt.Errorf("unknown command '%s'", cmd.Command))
 	}
 }
 
-func (s *Handler) onBoostCreated(ctx context.Context, boost *lunch.Boost) error {
-	text := fmt.Sprintf("<@%s> boosted %s", boost.UserID, boost.Place.Name)
-	blocks := Section(Markdown("<@%s> boosted *%s*", boost.UserID, boost.Place.Name))
+func (h *Handler) handleLunch(ctx context.Context, cmd *CommandRequest) *Message {
+	args := strings.Fields(cmd.Text)
+	if len(args) == 0 {
+		return lunchHelp()
+	}
+	switch args[0] {
+	case "bind":
+		return h.handleBind(ctx, cmd.ChannelID, strings.Join(args[1:], " "))
+	case "rooms":
+		return h.handleRooms(ctx, cmd.ChannelID)
+	case "settings":
+		return h.handleSettings(ctx, cmd.ChannelID)
+	case "set":
+		if len(args) != 3 {
+			return lunchHelp()
+		}
+		return h.handleSet(ctx, cmd.ChannelID, args[1], args[2])
+	case "poll":
+		return h.handlePoll(ctx, cmd.ChannelID, args[1:]...)
+	case "schedule":
+		return h.handleSchedule(ctx, cmd.ChannelID, args[1:]...)
+	case "reminders":
+		return h.handleReminders(ctx, cmd.ChannelID, args[1:]...)
+	case "notify":
+		return h.handleNotify(ctx, cmd.ChannelID, args[1:]...)
+	default:
+		return lunchHelp()
+	}
+}
 
-	users, err := s.usersService.List(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list users: %w", err)
+func lunchHelp() *Message {
+	return Ephemeral(
+		"Usage: /lunch bind <room>, /lunch rooms, /lunch settings, /lunch set <setting> <value>, /lunch poll [approval|ranked] [minutes], /lunch schedule [<HH:MM> <mon,tue,...>|off], /lunch reminders [on|off], /lunch notify [all|rolls|none] [<HH:MM>-<HH:MM>]",
+		Section(Markdown("`/lunch bind <room>` - bind this channel to a room")),
+		Section(Markdown("`/lunch rooms` - list your rooms")),
+		Section(Markdown("`/lunch settings` - show settings of the room")),
+		Section(Markdown("`/lunch set <setting> <value>` - change a setting of the room")),
+		Section(Markdown("`/lunch poll [approval|ranked] [minutes]` - vote for a place instead of rolling")),
+		Section(Markdown("`/lunch schedule [<HH:MM> <mon,tue,...>|off]` - roll automatically at a time of the day")),
+		Section(Markdown("`/lunch reminders [on|off]` - get reminded before the scheduled roll, see the `reminderMinutes` setting")),
+		Section(Markdown("`/lunch notify [all|rolls|none] [<HH:MM>-<HH:MM>]` - choose what you are notified about, and quiet hours")),
+	)
+}
+
+func (h *Handler) handleNotify(ctx context.Context, channelID string, args ...string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
 	}
 
-	wg, ctx := errgroup.WithContext(ctx)
-	for _, user := range users {
-		if user.ID == boost.UserID {
-			continue
+	if len(args) == 0 {
+		preferences, err := h.roller.GetNotifications(ctx, roomID)
+		if err != nil {
+			return InternalServerError(err)
 		}
+		return Ephemeral(fmt.Sprintf("Notifications: %s", formatPreferences(preferences)))
+	}
 
-		user := user
-		wg.Go(func() error {
-			if err := s.sendMessage(ctx, user, text, blocks); err != nil {
-				return fmt.Errorf("failed to send message: %w", err)
+	quietFrom, quietTo := "", ""
+	switch len(args) {
+	case 1:
+	case 2:
+		quiet := strings.SplitN(args[1], "-", 2)
+		if len(quiet) != 2 {
+			return BadRequest(fmt.Errorf("'%s' must be quiet hours like 18:00-09:00", args[1]))
+		}
+		quietFrom, quietTo = quiet[0], quiet[1]
+	default:
+		return lunchHelp()
+	}
+
+	preferences, err := h.roller.UpdateNotifications(ctx, roomID, notifications.Level(args[0]), quietFrom, quietTo, time.Now())
+	switch {
+	case err == nil:
+		return Ephemeral(fmt.Sprintf("Notifications: %s", formatPreferences(preferences)))
+	case errors.Is(err, lunch.ErrInvalid):
+		return BadRequest(err)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return Ephemeral("Failed to change notifications: join the room first")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func formatPreferences(preferences *notifications.Preferences) string {
+	if preferences.QuietFrom == "" {
+		return string(preferences.Level)
+	}
+	return fmt.Sprintf("%s, quiet %s-%s", preferences.Level, preferences.QuietFrom, preferences.QuietTo)
+}
+
+func (h *Handler) handleReminders(ctx context.Context, channelID string, args ...string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	if len(args) == 0 {
+		muted, err := h.roller.RemindersMuted(ctx, roomID)
+		if err != nil {
+			return InternalServerError(err)
+		}
+		if muted {
+			return Ephemeral("Reminders: off")
+		}
+		return Ephemeral("Reminders: on")
+	}
+
+	var muted bool
+	switch args[0] {
+	case "on":
+		muted = false
+	case "off":
+		muted = true
+	default:
+		return lunchHelp()
+	}
+
+	err := h.roller.MuteReminders(ctx, roomID, muted, time.Now())
+	switch {
+	case err == nil && muted:
+		return Ephemeral("Reminders: off")
+	case err == nil:
+		return Ephemeral("Reminders: on")
+	case errors.Is(err, lunch.ErrNotFound):
+		return Ephemeral("Failed to change reminders: room not found")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleSchedule(ctx context.Context, channelID string, args ...string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	if len(args) == 0 {
+		schedule, err := h.roller.GetSchedule(ctx, roomID)
+		switch {
+		case err == nil:
+			return Ephemeral(fmt.Sprintf("Schedule: %s", schedule))
+		case errors.Is(err, lunch.ErrNotFound):
+			return Ephemeral("Schedule: off")
+		default:
+			return InternalServerError(err)
+		}
+	}
+
+	hour, minute, weekdays := 0, 0, []time.Weekday{}
+	switch {
+	case len(args) == 1 && args[0] == "off":
+	case len(args) == 2:
+		var err error
+		if hour, minute, err = schedules.ParseTime(args[0]); err != nil {
+			return BadRequest(err)
+		}
+		if weekdays, err = schedules.ParseWeekdays(args[1]); err != nil {
+			return BadRequest(err)
+		}
+	default:
+		return lunchHelp()
+	}
+
+	schedule, err := h.roller.UpdateSchedule(ctx, roomID, hour, minute, weekdays, time.Now())
+	switch {
+	case err == nil:
+		return Ephemeral(fmt.Sprintf("Schedule: %s", schedule))
+	case errors.Is(err, lunch.ErrInvalid):
+		return BadRequest(err)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return Ephemeral("Failed to change schedule: only room owner can do that")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+const defaultPollMinutes = 30
+
+func (h *Handler) handlePoll(ctx context.Context, channelID string, args ...string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	method := polls.MethodApproval
+	if len(args) > 0 {
+		method = polls.Method(args[0])
+	}
+
+	minutes := defaultPollMinutes
+	if len(args) > 1 {
+		m, err := strconv.Atoi(args[1])
+		if err != nil {
+			return BadRequest(fmt.Errorf("'%s' is not a number of minutes", args[1]))
+		}
+		minutes = m
+	}
+
+	now := time.Now()
+	poll, err := h.roller.OpenPoll(ctx, roomID, method, now.Add(time.Duration(minutes)*time.Minute), now)
+	switch {
+	case err == nil:
+		return InChannel("Vote for lunch", pollBlocks(poll)...)
+	case errors.Is(err, lunch.ErrInvalid):
+		return BadRequest(err)
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleVote(ctx context.Context, channelID, responseURL string, placeID places.ID) error {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return h.asyncPost(ctx, responseURL, msg)
+	}
+
+	poll, err := h.roller.Vote(ctx, roomID, placeID, time.Now())
+	switch {
+	case err == nil:
+		return h.asyncPost(ctx, responseURL, ReplaceInChannel("Vote for lunch", pollBlocks(poll)...))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to vote: place not found"))
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to vote: poll is closed"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+}
+
+func (h *Handler) handleClosePoll(ctx context.Context, channelID, responseURL string) error {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return h.asyncPost(ctx, responseURL, msg)
+	}
+
+	_, err := h.roller.ClosePoll(ctx, roomID, time.Now())
+	switch {
+	case err == nil, errors.Is(err, lunch.ErrNoPlaces):
+		poll, err := h.roller.GetPoll(ctx, roomID)
+		if err != nil {
+			return h.asyncPost(ctx, responseURL, InternalServerError(err))
+		}
+		return h.asyncPost(ctx, responseURL, ReplaceInChannel("Poll closed", pollBlocks(poll)...))
+	case errors.Is(err, lunch.ErrNotFound):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to close: the poll is already closed"))
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return h.asyncPost(ctx, responseURL, Ephemeral("Failed to close: only poll creator can close it early"))
+	default:
+		return h.asyncPost(ctx, responseURL, InternalServerError(err))
+	}
+}
+
+// pollBlocks renders the poll with a vote button for every place, or the result if the poll is closed.
+func pollBlocks(poll *lunch.Poll) []*Block {
+	bb := []*Block{}
+	switch {
+	case poll.IsClosed && poll.WinnerID == "":
+		bb = append(bb, Section(Markdown("*Poll closed*, nobody voted")))
+	case poll.IsClosed:
+		for _, place := range poll.Places {
+			if place.ID == poll.WinnerID {
+				bb = append(bb, Section(Markdown("*Poll closed*, *%s* won!", place.Name)))
 			}
-			return nil
-		})
+		}
+	case poll.Method == polls.MethodRankedChoice:
+		bb = append(bb, Section(Markdown(
+			"*Vote for lunch!* Vote in order of preference, vote again to take it back. Closes <!date^%d^{time}|%s>.",
+			poll.ClosesAt.Unix(), poll.ClosesAt.Format(time.RFC1123),
+		)))
+	default:
+		bb = append(bb, Section(Markdown(
+			"*Vote for lunch!* Vote for as many places as you like, vote again to take it back. Closes <!date^%d^{time}|%s>.",
+			poll.ClosesAt.Unix(), poll.ClosesAt.Format(time.RFC1123),
+		)))
 	}
-	return wg.Wait()
+	bb = append(bb, Divider())
+
+	for _, place := range poll.Places {
+		fields := []*TextBlock{
+			PlainText("%s", place.Name),
+			PlainText("%d votes", poll.Votes[place.ID]),
+		}
+		if poll.IsClosed {
+			bb = append(bb, SectionFields(fields))
+		} else {
+			bb = append(bb, SectionFields(fields, WithButton(PlainText("Vote"), "vote", string(place.ID))))
+		}
+	}
+
+	if !poll.IsClosed {
+		bb = append(bb, Actions(Button(PlainText("Close"), "close_poll", string(poll.ID))))
+	}
+
+	return bb
 }
 
-func (s *Handler) onPlaceCreated(ctx context.Context, place *lunch.Place) error {
-	text := fmt.Sprintf("<@%s> added %s", place.UserID, place.Name)
-	blocks := Section(Markdown("<@%s> added *%s*", place.UserID, place.Name))
+func settingsBlocks(settings rooms.Settings) []*Block {
+	return []*Block{
+		Section(nil, Markdown("*Setting*"), Markdown("*Value*")),
+		Divider(),
+		Section(nil, PlainText("strategy"), PlainText("%s", settings.Strategy)),
+		Section(nil, PlainText("halfLifeDays"), PlainText("%g", settings.HalfLifeDays)),
+		Section(nil, PlainText("noRepeatDays"), PlainText("%d", settings.NoRepeatDays)),
+		Section(nil, PlainText("points"), PlainText("%d", settings.Points)),
+		Section(nil, PlainText("period"), PlainText("%s", settings.Period)),
+		Section(nil, PlainText("boostMultiplier"), PlainText("%g", settings.BoostMultiplier)),
+		Section(nil, PlainText("freeDailyRoll"), PlainText("%t", settings.FreeDailyRoll)),
+		Section(nil, PlainText("undoMinutes"), PlainText("%g", settings.UndoMinutes)),
+		Section(nil, PlainText("timezone"), PlainText("%s", settings.Location())),
+		Section(nil, PlainText("reminderMinutes"), PlainText("%d", settings.ReminderMinutes)),
+		Section(nil, PlainText("notifyChannel"), PlainText("%t", settings.NotifyChannel)),
+	}
+}
+
+func (h *Handler) handleSettings(ctx context.Context, channelID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
 
-	users, err := s.usersService.List(ctx)
+	room, err := h.roller.GetRoom(ctx, roomID)
 	if err != nil {
-		return fmt.Errorf("failed to list users: %w", err)
+		return InternalServerError(err)
 	}
 
-	wg, ctx := errgroup.WithContext(ctx)
-	for _, user := range users {
-		if user.ID == place.UserID {
-			continue
+	return Ephemeral("Settings", settingsBlocks(room.Settings)...)
+}
+
+func (h *Handler) handleSet(ctx context.Context, channelID, key, value string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	room, err := h.roller.GetRoom(ctx, roomID)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	settings := room.Settings
+	if err := settings.Set(key, value); err != nil {
+		return BadRequest(err)
+	}
+
+	err = h.roller.UpdateRoomSettings(ctx, roomID, settings)
+	switch {
+	case err == nil:
+		return Ephemeral("Settings updated", settingsBlocks(settings)...)
+	case errors.Is(err, lunch.ErrInvalid):
+		return BadRequest(err)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return Ephemeral("Only room owner can change settings")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleBind(ctx context.Context, channelID, roomName string) *Message {
+	if roomName == "" {
+		return lunchHelp()
+	}
+
+	rr, err := h.roller.ListRooms(ctx)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	matches := []*lunch.Room{}
+	for _, room := range rr {
+		if string(room.ID) == roomName || strings.EqualFold(room.Name, roomName) {
+			matches = append(matches, room)
 		}
+	}
 
-		user := user
-		wg.Go(func() error {
-			if err := s.sendMessage(ctx, user, text, blocks); err != nil {
-				return fmt.Errorf("failed to send message: %w", err)
-			}
-			return nil
-		})
+	switch len(matches) {
+	case 0:
+		return Ephemeral(fmt.Sprintf("You are not a member of %s", roomName))
+	case 1:
+	default:
+		return Ephemeral(fmt.Sprintf("There are multiple rooms called %s, use room id instead", roomName))
 	}
-	return wg.Wait()
+
+	room := matches[0]
+	err = h.roller.BindChannel(ctx, room.ID, channels.ID(channelID), time.Now())
+	switch {
+	case err == nil:
+		return InChannel(
+			fmt.Sprintf("This channel is now bound to %s", room.Name),
+			Section(Markdown("This channel is now bound to *%s*", room.Name)),
+		)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return Ephemeral(fmt.Sprintf("You are not a member of %s, or of the room this channel is bound to", room.Name))
+	case errors.Is(err, lunch.ErrAlreadyExists):
+		return Ephemeral("This channel is already bound by another app")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleRooms(ctx context.Context, channelID string) *Message {
+	rr, err := h.roller.ListRooms(ctx)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	if len(rr) == 0 {
+		return Ephemeral("You are not a member of any room")
+	}
+
+	sort.Slice(rr, func(i, j int) bool {
+		return rr[i].Name < rr[j].Name
+	})
+
+	var boundRoomID rooms.ID
+	channel, err := h.roller.GetChannel(ctx, channels.ID(channelID))
+	switch {
+	case err == nil:
+		boundRoomID = channel.RoomID
+	case errors.Is(err, lunch.ErrNotFound):
+	default:
+		return InternalServerError(err)
+	}
+
+	bb := []*Block{
+		Section(nil, Markdown("*Room*"), Markdown("*ID*")),
+		Divider(),
+	}
+	for _, room := range rr {
+		name := room.Name
+		if room.ID == boundRoomID {
+			name = fmt.Sprintf("%s (this channel)", room.Name)
+		}
+		bb = append(bb, Section(nil, PlainText("%s", name), PlainText("%s", room.ID)))
+	}
+
+	return Ephemeral("Rooms", bb...)
+}
+
+func (s *Handler) onBoostCreated(ctx context.Context, boost *lunch.Boost) error {
+	text := fmt.Sprintf("<@%s> boosted %s", boost.UserID, boost.Place.Name)
+	blocks := Section(Markdown("<@%s> boosted *%s*", boost.UserID, boost.Place.Name))
+	return s.notify(ctx, boost.RoomID, notifications.TopicOther, boost.UserID, text, blocks)
+}
+
+func (s *Handler) onVetoCreated(ctx context.Context, veto *lunch.Veto) error {
+	text := fmt.Sprintf("<@%s> vetoed %s", veto.UserID, veto.Place.Name)
+	blocks := Section(Markdown("<@%s> vetoed *%s*", veto.UserID, veto.Place.Name))
+	return s.notify(ctx, veto.RoomID, notifications.TopicOther, veto.UserID, text, blocks)
+}
+
+func (s *Handler) onPlaceCreated(ctx context.Context, place *lunch.Place) error {
+	text := fmt.Sprintf("<@%s> added %s", place.UserID, place.Name)
+	blocks := Section(Markdown("<@%s> added *%s*", place.UserID, place.Name))
+	return s.notify(ctx, place.RoomID, notifications.TopicOther, place.UserID, text, blocks)
 }
 
 func (s *Handler) onRollCreated(ctx context.Context, roll *lunch.Roll) error {
-	users, err := s.usersService.List(ctx)
+	text := fmt.Sprintf("%s rolled %s", mention(roll.UserID), roll.Place.Name)
+	blocks := Section(Markdown("%s rolled *%s*", mention(roll.UserID), roll.Place.Name))
+	return s.notify(ctx, roll.RoomID, notifications.TopicRolls, roll.UserID, text, blocks)
+}
+
+// mention returns how the user is mentioned in a message.
+func mention(userID users.ID) string {
+	if userID == users.System.ID {
+		return users.System.Name
+	}
+	return fmt.Sprintf("<@%s>", userID)
+}
+
+// notify posts to channels bound to the room if the room is configured to, otherwise sends a direct message
+// to room members who want to hear about the topic, except the one who did it.
+func (s *Handler) notify(ctx context.Context, roomID rooms.ID, topic notifications.Topic, actorID users.ID, text string, blocks ...*Block) error {
+	room, err := s.roller.GetRoom(ctx, roomID)
 	if err != nil {
-		return fmt.Errorf("failed to list users: %w", err)
+		return fmt.Errorf("failed to get room: %w", err)
 	}
 
-	text := fmt.Sprintf("<@%s> rolled %s", roll.UserID, roll.Place.Name)
-	blocks := Section(Markdown("<@%s> rolled *%s*", roll.UserID, roll.Place.Name))
+	if room.Settings.NotifyChannel {
+		cc, err := s.roller.ListChannels(ctx, roomID)
+		if err != nil {
+			return fmt.Errorf("failed to list channels: %w", err)
+		}
+		slackChannels := make([]*channels.Channel, 0, len(cc))
+		for _, channel := range cc {
+			if channel.Platform == channels.PlatformSlack {
+				slackChannels = append(slackChannels, channel)
+			}
+		}
+		if len(slackChannels) > 0 {
+			return s.notifyChannels(ctx, room, slackChannels, text, blocks...)
+		}
+	}
 
-	wg, ctx := errgroup.WithContext(ctx)
-	for _, user := range users {
-		if user.ID == roll.UserID {
+	recipients, err := s.roller.Recipients(ctx, roomID, topic, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list recipients: %w", err)
+	}
+
+	// one failed message should not stop the rest
+	var wg errgroup.Group
+	for _, user := range recipients {
+		// users from other chats are notified there
+		if user.ID == actorID || !user.ID.IsSlack() {
 			continue
 		}
 		user := user
 		wg.Go(func() error {
-			if err := s.sendMessage(ctx, user, text, blocks); err != nil {
-				return fmt.Errorf("failed to send message: %w", err)
-			}
-			return nil
+			return lunch.Once(ctx, fmt.Sprintf("dm/%s", user.ID), func() error {
+				if err := s.sendMessage(ctx, user, text, blocks...); err != nil {
+					return fmt.Errorf("failed to send message: %w", err)
+				}
+				return nil
+			})
 		})
 	}
 	return wg.Wait()
 }
 
-func (s *Handler) sendMessage(ctx context.Context, user *users.User, text string, blocks ...*Block) error {
-	type request struct {
-		Channel string   `json:"channel"`
-		Text    string   `json:"text"`
-		Blocks  []*Block `json:"blocks"`
+// notifyChannels keeps one summary message per channel and day up to date, and posts the notification
+// as a reply in its thread.
+func (s *Handler) notifyChannels(ctx context.Context, room *lunch.Room, cc []*channels.Channel, text string, blocks ...*Block) error {
+	// summary is posted once per day, even if events of the room come at the same time
+	unlock := s.summaries.lock(room.ID)
+	defer unlock()
+
+	now := time.Now()
+	day, err := s.roller.Today(ctx, room.ID, now)
+	if err != nil {
+		return fmt.Errorf("failed to get today: %w", err)
 	}
+	summaryText, summary := summaryBlocks(room, day)
 
-	log.Printf("[INFO] sending message to %s", user.ID)
+	var firstErr error
+	for _, channel := range cc {
+		if err := s.notifyChannel(ctx, room, channel, day.Date, summaryText, summary, text, blocks); err != nil {
+			log.Printf("[ERROR] failed to notify channel %s: %s", channel.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
 
-	type response struct {
-		OK    bool   `json:"ok"`
-		Error string `json:"error"`
+func (s *Handler) notifyChannel(ctx context.Context, room *lunch.Room, channel *channels.Channel, day, summaryText string, summary []*Block, text string, blocks []*Block) error {
+	msg, err := s.dailyMessage(ctx, room, channel, day, summaryText, summary)
+	if err != nil {
+		return err
 	}
-	body, err := json.Marshal(&request{
-		Channel: string(user.ID),
-		Text:    text,
-		Blocks:  blocks,
+
+	return lunch.Once(ctx, fmt.Sprintf("reply/%s", channel.ID), func() error {
+		if _, err := s.postMessage(ctx, string(channel.ID), msg.TS, text, blocks...); err != nil {
+			return fmt.Errorf("failed to post reply: %w", err)
+		}
+		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+}
+
+// summaryBlocks renders what happened in the room today: the latest roll, and places that were boosted or vetoed.
+func summaryBlocks(room *lunch.Room, day *lunch.Day) (string, []*Block) {
+	bb := []*Block{Section(Markdown("*Lunch in %s*, %s", room.Name, day.Date))}
+
+	text := fmt.Sprintf("Lunch in %s is not rolled yet", room.Name)
+	if len(day.Rolls) == 0 {
+		bb = append(bb, Section(Markdown("Not rolled yet")))
+	} else {
+		latest := day.Rolls[len(day.Rolls)-1]
+		text = fmt.Sprintf("Lunch in %s is %s", room.Name, latest.Place.Name)
+		line := Markdown("*%s*, rolled by %s", latest.Place.Name, mention(latest.UserID))
+		if rerolls := len(day.Rolls) - 1; rerolls > 0 {
+			line.Text += fmt.Sprintf(" after %d rerolls", rerolls)
+		}
+		bb = append(bb, Section(line))
 	}
 
-	req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/chat.postMessage", bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if len(day.Boosts) > 0 {
+		names := make([]string, 0, len(day.Boosts))
+		for _, boost := range day.Boosts {
+			names = append(names, boost.Place.Name)
+		}
+		bb = append(bb, Section(Markdown("Boosted: %s", strings.Join(names, ", "))))
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.cfg.BotAccessToken))
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+	if len(day.Vetoes) > 0 {
+		names := make([]string, 0, len(day.Vetoes))
+		for _, veto := range day.Vetoes {
+			names = append(names, veto.Place.Name)
+		}
+		bb = append(bb, Section(Markdown("Vetoed: %s", strings.Join(names, ", "))))
 	}
-	defer resp.Body.Close()
 
-	var r response
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+	return text, bb
+}
+
+func (s *Handler) onReminderDue(ctx context.Context, reminder *lunch.Reminder) error {
+	text := fmt.Sprintf("Lunch in %s is rolled at %s", reminder.Room.Name, reminder.RollsAt.Format(time.Kitchen))
+	blocks := []*Block{
+		Section(Markdown(
+			"Lunch in *%s* is rolled <!date^%d^{time}|%s>, roll now if you don't want to wait.",
+			reminder.Room.Name, reminder.RollsAt.Unix(), reminder.RollsAt.Format(time.Kitchen),
+		)),
+		Actions(
+			Button(PlainText("Roll now"), "roll", string(reminder.RoomID)),
+			Button(PlainText("Show odds"), "odds", string(reminder.RoomID)),
+			Button(PlainText("Stop reminders"), "mute_reminders", string(reminder.RoomID)),
+		),
 	}
 
-	if r.OK {
-		return nil
+	var wg errgroup.Group
+	for _, user := range reminder.Users {
+		user := user
+		wg.Go(func() error {
+			return lunch.Once(ctx, fmt.Sprintf("dm/%s", user.ID), func() error {
+				if err := s.sendMessage(ctx, user, text, blocks...); err != nil {
+					return fmt.Errorf("failed to send message: %w", err)
+				}
+				return nil
+			})
+		})
 	}
+	return wg.Wait()
+}
+
+func (s *Handler) sendMessage(ctx context.Context, user *users.User, text string, blocks ...*Block) error {
+	log.Printf("[INFO] sending message to %s", user.ID)
 
-	return fmt.Errorf("failed to send message: %s", r.Error)
+	_, err := s.postMessage(ctx, string(user.ID), "", text, blocks...)
+	return err
+}
+
+// postMessage posts a message to the channel, or to the thread if threadTS is not empty,
+// and returns the timestamp of the new message.
+func (s *Handler) postMessage(ctx context.Context, channel, threadTS, text string, blocks ...*Block) (string, error) {
+	ts, err := s.client.PostMessage(ctx, s.cfg.BotAccessToken, &client_slack.PostMessageRequest{
+		Channel:  channel,
+		ThreadTS: threadTS,
+		Text:     text,
+		Blocks:   blocks,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send message: %w", err)
+	}
+	return ts, nil
+}
+
+// updateMessage replaces the message with the given timestamp.
+func (s *Handler) updateMessage(ctx context.Context, channel, ts, text string, blocks ...*Block) error {
+	if err := s.client.UpdateMessage(ctx, s.cfg.BotAccessToken, &client_slack.UpdateMessageRequest{
+		Channel: channel,
+		TS:      ts,
+		Text:    text,
+		Blocks:  blocks,
+	}); err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+	return nil
 }
@@ -47,18 +47,70 @@ type accessory struct {
 	Value    string        `json:"value"`
 }
 
+type elementType string
+
+const (
+	elementTypePlainTextInput elementType = "plain_text_input"
+	elementTypeStaticSelect   elementType = "static_select"
+)
+
+// element is an interactive input element of an input block.
+type element struct {
+	Type        elementType `json:"type"`
+	ActionID    string      `json:"action_id"`
+	Placeholder *TextBlock  `json:"placeholder,omitempty"`
+	Options     []*Option   `json:"options,omitempty"`
+}
+
+// https://api.slack.com/reference/block-kit/block-elements#input
+func PlainTextInput(actionID string, placeholder *TextBlock) *element {
+	return &element{
+		Type:        elementTypePlainTextInput,
+		ActionID:    actionID,
+		Placeholder: placeholder,
+	}
+}
+
+// https://api.slack.com/reference/block-kit/block-elements#static_select
+func StaticSelect(actionID string, placeholder *TextBlock, options ...*Option) *element {
+	return &element{
+		Type:        elementTypeStaticSelect,
+		ActionID:    actionID,
+		Placeholder: placeholder,
+		Options:     options,
+	}
+}
+
 type blockObjectType string
 
 const (
 	blockObjectTypeSection blockObjectType = "section"
 	blockObjectTypeDivider blockObjectType = "divider"
+	blockObjectTypeActions blockObjectType = "actions"
+	blockObjectTypeInput   blockObjectType = "input"
 )
 
 type Block struct {
 	Type      blockObjectType `json:"type"`
+	BlockID   string          `json:"block_id,omitempty"`
 	Text      *TextBlock      `json:"text,omitempty"`
 	Fields    []*TextBlock    `json:"fields,omitempty"`
 	Accessory *accessory      `json:"accessory,omitempty"`
+	Elements  []*accessory    `json:"elements,omitempty"`
+	Label     *TextBlock      `json:"label,omitempty"`
+	Element   *element        `json:"element,omitempty"`
+	Optional  bool            `json:"optional,omitempty"`
+}
+
+// https://api.slack.com/reference/block-kit/blocks#input
+func Input(blockID string, label *TextBlock, element *element, optional bool) *Block {
+	return &Block{
+		Type:     blockObjectTypeInput,
+		BlockID:  blockID,
+		Label:    label,
+		Element:  element,
+		Optional: optional,
+	}
 }
 
 func Divider() *Block {
@@ -78,6 +130,15 @@ func SectionFields(fields []*TextBlock, options ...sectionOption) *Block {
 	return b
 }
 
+// https://api.slack.com/reference/block-kit/blocks#section
+func SectionText(text *TextBlock, options ...sectionOption) *Block {
+	b := Section(text)
+	for _, apply := range options {
+		apply(b)
+	}
+	return b
+}
+
 // https://api.slack.com/reference/block-kit/blocks#section
 func Section(text *TextBlock, fields ...*TextBlock) *Block {
 	return &Block{
@@ -90,12 +151,25 @@ func Section(text *TextBlock, fields ...*TextBlock) *Block {
 // https://api.slack.com/reference/block-kit/block-elements#button
 func WithButton(text *TextBlock, actionID, value string) sectionOption {
 	return func(b *Block) {
-		b.Accessory = &accessory{
-			Text:     text,
-			Type:     accessoryTypeButton,
-			ActionID: actionID,
-			Value:    value,
-		}
+		b.Accessory = Button(text, actionID, value)
+	}
+}
+
+// https://api.slack.com/reference/block-kit/block-elements#button
+func Button(text *TextBlock, actionID, value string) *accessory {
+	return &accessory{
+		Text:     text,
+		Type:     accessoryTypeButton,
+		ActionID: actionID,
+		Value:    value,
+	}
+}
+
+// https://api.slack.com/reference/block-kit/blocks#actions
+func Actions(elements ...*accessory) *Block {
+	return &Block{
+		Type:     blockObjectTypeActions,
+		Elements: elements,
 	}
 }
 
@@ -132,3 +206,44 @@ func NewMessage(rt responseType, text string, sections ...*Block) *Message {
 		Blocks:       sections,
 	}
 }
+
+type viewType string
+
+const (
+	viewTypeHome  viewType = "home"
+	viewTypeModal viewType = "modal"
+)
+
+// https://api.slack.com/reference/surfaces/views
+type View struct {
+	Type   viewType `json:"type"`
+	Blocks []*Block `json:"blocks"`
+	// Modal only fields.
+	CallbackID      string     `json:"callback_id,omitempty"`
+	PrivateMetadata string     `json:"private_metadata,omitempty"`
+	Title           *TextBlock `json:"title,omitempty"`
+	Submit          *TextBlock `json:"submit,omitempty"`
+	Close           *TextBlock `json:"close,omitempty"`
+}
+
+type viewResponseAction string
+
+const (
+	viewResponseActionErrors viewResponseAction = "errors"
+)
+
+// ViewResponse is a response to a view submission. No response closes the modal.
+// https://api.slack.com/surfaces/modals/using#displaying_errors
+type ViewResponse struct {
+	ResponseAction viewResponseAction `json:"response_action"`
+	// Errors are messages by block id.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ViewErrors keeps the modal open and shows the errors next to the blocks.
+func ViewErrors(errors map[string]string) *ViewResponse {
+	return &ViewResponse{
+		ResponseAction: viewResponseActionErrors,
+		Errors:         errors,
+	}
+}
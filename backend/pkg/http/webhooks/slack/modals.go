@@ -0,0 +1,130 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/rooms"
+	client_slack "lunch/pkg/slack"
+)
+
+const (
+	addPlaceCallbackID = "add_place"
+
+	// block ids and action ids of the add place modal inputs
+	addPlaceName  = "name"
+	addPlaceURL   = "url"
+	addPlaceTags  = "tags"
+	addPlacePrice = "price"
+)
+
+func addPlaceModal(roomID rooms.ID) *View {
+	priceOptions := make([]*Option, 0, places.MaxPriceLevel)
+	for level := places.MinPriceLevel + 1; level <= places.MaxPriceLevel; level++ {
+		priceOptions = append(priceOptions, &Option{
+			Text:  PlainText("%s", strings.Repeat("$", level)),
+			Value: strconv.Itoa(level),
+		})
+	}
+
+	return &View{
+		Type:            viewTypeModal,
+		CallbackID:      addPlaceCallbackID,
+		PrivateMetadata: string(roomID),
+		Title:           PlainText("Add a place"),
+		Submit:          PlainText("Add"),
+		Close:           PlainText("Cancel"),
+		Blocks: []*Block{
+			Input(addPlaceName, PlainText("Name"), PlainTextInput(addPlaceName, nil), false),
+			Input(addPlaceURL, PlainText("Website"), PlainTextInput(addPlaceURL, PlainText("https://")), true),
+			Input(addPlaceTags, PlainText("Tags"), PlainTextInput(addPlaceTags, PlainText("pizza, outdoor")), true),
+			Input(addPlacePrice, PlainText("Price"), StaticSelect(addPlacePrice, PlainText("Select a price"), priceOptions...), true),
+		},
+	}
+}
+
+// handleAddModal opens a modal to add a place with details to the room the channel is bound to.
+func (h *Handler) handleAddModal(ctx context.Context, channelID, triggerID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	if err := h.client.OpenView(ctx, h.cfg.BotAccessToken, &client_slack.OpenViewRequest{
+		TriggerID: triggerID,
+		View:      addPlaceModal(roomID),
+	}); err != nil {
+		return InternalServerError(fmt.Errorf("failed to open modal: %w", err))
+	}
+	return nil
+}
+
+func (h *Handler) respondView(w http.ResponseWriter, response *ViewResponse) {
+	if response == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := respondJSON(w, response); err != nil {
+		log.Printf("[ERROR] failed to marshal response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// handleViewSubmission returns errors to show in the modal, or nil to close it.
+func (h *Handler) handleViewSubmission(ctx context.Context, view *ViewState) *ViewResponse {
+	log.Printf("[INFO] incoming view submission: %s", view.CallbackID)
+	switch view.CallbackID {
+	case addPlaceCallbackID:
+		return h.handleAddPlaceSubmission(ctx, view)
+	default:
+		log.Printf("[WARN] unknown view '%s'", view.CallbackID)
+		return nil
+	}
+}
+
+func (h *Handler) handleAddPlaceSubmission(ctx context.Context, view *ViewState) *ViewResponse {
+	roomID := rooms.ID(view.PrivateMetadata)
+
+	name := strings.TrimSpace(view.Value(addPlaceName, addPlaceName))
+	metadata := places.Metadata{
+		URL: strings.TrimSpace(view.Value(addPlaceURL, addPlaceURL)),
+	}
+	if metadata.URL != "" {
+		if u, err := url.ParseRequestURI(metadata.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return ViewErrors(map[string]string{addPlaceURL: "Must be a link like https://example.com"})
+		}
+	}
+	for _, tag := range strings.Split(view.Value(addPlaceTags, addPlaceTags), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			metadata.Tags = append(metadata.Tags, tag)
+		}
+	}
+	if price := view.Value(addPlacePrice, addPlacePrice); price != "" {
+		level, err := strconv.Atoi(price)
+		if err != nil {
+			return ViewErrors(map[string]string{addPlacePrice: "Select a price"})
+		}
+		metadata.PriceLevel = level
+	}
+
+	err := h.roller.CreatePlaceWithMetadata(ctx, roomID, name, metadata)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, lunch.ErrAlreadyExists):
+		return ViewErrors(map[string]string{addPlaceName: "A place with this name already exists"})
+	case errors.Is(err, lunch.ErrInvalid):
+		return ViewErrors(map[string]string{addPlaceName: err.Error()})
+	default:
+		log.Printf("[ERROR] failed to create place: %s", err)
+		return ViewErrors(map[string]string{addPlaceName: "Sorry, that didn't work. Try again or contact the app administrator."})
+	}
+}
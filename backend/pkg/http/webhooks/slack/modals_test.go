@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestParseRequest_viewSubmission(t *testing.T) {
+	t.Parallel()
+
+	payload := `{
+		"type": "view_submission",
+		"user": {"id": "U1", "name": "user"},
+		"view": {
+			"callback_id": "add_place",
+			"private_metadata": "room",
+			"state": {"values": {
+				"name": {"name": {"type": "plain_text_input", "value": "Pizza"}},
+				"price": {"price": {"type": "static_select", "selected_option": {"value": "2"}}}
+			}}
+		}
+	}`
+	body := url.Values{"payload": {payload}}.Encode()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	_, actions, _, _, err := ParseRequest(r, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if actions == nil || actions.Type != interactionTypeViewSubmission {
+		t.Fatalf("expected a view submission, got %+v", actions)
+	}
+	if actions.View.CallbackID != addPlaceCallbackID || actions.View.PrivateMetadata != "room" {
+		t.Errorf("unexpected view: %+v", actions.View)
+	}
+	if name := actions.View.Value(addPlaceName, addPlaceName); name != "Pizza" {
+		t.Errorf("expected name Pizza, got '%s'", name)
+	}
+	if price := actions.View.Value(addPlacePrice, addPlacePrice); price != "2" {
+		t.Errorf("expected price 2, got '%s'", price)
+	}
+	if tags := actions.View.Value(addPlaceTags, addPlaceTags); tags != "" {
+		t.Errorf("expected no tags, got '%s'", tags)
+	}
+}
+
+func TestHandleAddPlaceSubmission(t *testing.T) {
+	t.Parallel()
+
+	user := &users.User{ID: "user", Name: "user"}
+	ctx := users.NewContext(context.Background(), user)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+	h := &Handler{roller: roller}
+	roomID := rooms.ID("room")
+
+	submission := func(name, link, tags, price string) *ViewState {
+		view := &ViewState{CallbackID: addPlaceCallbackID, PrivateMetadata: string(roomID)}
+		view.State.Values = map[string]map[string]*StateValue{
+			addPlaceName: {addPlaceName: {Value: name}},
+			addPlaceURL:  {addPlaceURL: {Value: link}},
+			addPlaceTags: {addPlaceTags: {Value: tags}},
+		}
+		if price != "" {
+			view.State.Values[addPlacePrice] = map[string]*StateValue{addPlacePrice: {SelectedOption: &Option{Value: price}}}
+		}
+		return view
+	}
+
+	testCases := []struct {
+		name      string
+		view      *ViewState
+		errorsFor string
+	}{
+		{"valid", submission("Pizza", "https://pizza.example", "pizza, outdoor", "2"), ""},
+		{"duplicate", submission(" pizza ", "", "", ""), addPlaceName},
+		{"empty name", submission(" ", "", "", ""), addPlaceName},
+		{"bad url", submission("Sushi", "not a link", "", ""), addPlaceURL},
+		{"bad price", submission("Sushi", "", "", "cheap"), addPlacePrice},
+	}
+
+	for _, testCase := range testCases {
+		response := h.handleViewSubmission(ctx, testCase.view)
+		switch {
+		case testCase.errorsFor == "" && response != nil:
+			t.Errorf("%s: unexpected errors: %+v", testCase.name, response.Errors)
+		case testCase.errorsFor != "" && (response == nil || response.Errors[testCase.errorsFor] == ""):
+			t.Errorf("%s: expected an error for '%s', got %+v", testCase.name, testCase.errorsFor, response)
+		}
+	}
+
+	pp, err := roller.ListPlaces(ctx, roomID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pp) != 1 {
+		t.Fatalf("expected 1 place, got %d", len(pp))
+	}
+	if pp[0].Metadata.PriceLevel != 2 || len(pp[0].Metadata.Tags) != 2 || pp[0].Metadata.URL != "https://pizza.example" {
+		t.Errorf("unexpected metadata: %+v", pp[0].Metadata)
+	}
+}
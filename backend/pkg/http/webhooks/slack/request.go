@@ -2,21 +2,30 @@ package slack
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"lunch/pkg/claims"
 )
 
 type CommandRequest struct {
-	Command  string
-	Text     string
-	UserID   string
-	UserName string
+	Command   string
+	Text      string
+	UserID    string
+	UserName  string
+	ChannelID string
+	// TriggerID allows to open a modal in response to the command.
+	TriggerID string
 }
 
 type User struct {
@@ -24,15 +33,76 @@ type User struct {
 	Name string `json:"name"`
 }
 
+type Channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 type Action struct {
 	ActionID string `json:"action_id"`
 	Value    string `json:"value"`
 }
 
+type interactionType string
+
+const (
+	interactionTypeViewSubmission interactionType = "view_submission"
+)
+
+// https://api.slack.com/reference/interaction-payloads/views#view_submission
+type ViewState struct {
+	CallbackID      string `json:"callback_id"`
+	PrivateMetadata string `json:"private_metadata"`
+	State           struct {
+		// Values are input values by block id and action id.
+		Values map[string]map[string]*StateValue `json:"values"`
+	} `json:"state"`
+}
+
+type StateValue struct {
+	Type           string  `json:"type"`
+	Value          string  `json:"value"`
+	SelectedOption *Option `json:"selected_option"`
+}
+
+// Value returns the text or the selected option of the input, or an empty string if nothing was entered.
+func (v *ViewState) Value(blockID, actionID string) string {
+	value, ok := v.State.Values[blockID][actionID]
+	switch {
+	case !ok || value == nil:
+		return ""
+	case value.SelectedOption != nil:
+		return value.SelectedOption.Value
+	default:
+		return value.Value
+	}
+}
+
+// ActionsRequest is an interaction payload: either block actions or a view submission.
 type ActionsRequest struct {
-	User        *User     `json:"user"`
-	Actions     []*Action `json:"actions"`
-	ResponseUrl string    `json:"response_url"`
+	Type        interactionType `json:"type"`
+	User        *User           `json:"user"`
+	Channel     *Channel        `json:"channel"`
+	Actions     []*Action       `json:"actions"`
+	ResponseUrl string          `json:"response_url"`
+	View        *ViewState      `json:"view"`
+}
+
+// https://api.slack.com/events
+type Event struct {
+	Type    string `json:"type"`
+	User    string `json:"user"`
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+	TS      string `json:"ts"`
+	// Tab is set for app_home_opened events.
+	Tab string `json:"tab"`
+}
+
+// https://api.slack.com/types/event
+type EventRequest struct {
+	EventID string `json:"event_id"`
+	Event   *Event `json:"event"`
 }
 
 type ChallangeRequest struct {
@@ -41,40 +111,88 @@ type ChallangeRequest struct {
 	Type      string `json:"type"`
 }
 
-func verifySlackSignatureV0(header http.Header, body []byte, signingSecret string) error {
+// maxRequestAge is how far from now a request timestamp can be. Other requests are rejected, so that a captured
+// request can't be replayed later, and signatures only need to be remembered until the timestamp is that old.
+const maxRequestAge = 5 * time.Minute
+
+// Verifier checks that requests are signed by Slack and are not replayed. Signatures are claimed, so that a
+// request replayed to another server instance is rejected too.
+// https://api.slack.com/authentication/verifying-requests-from-slack
+type Verifier struct {
+	signingSecret string
+	claimsStore   claims.Storage
+	now           func() time.Time
+}
+
+func NewVerifier(signingSecret string, claimsStore claims.Storage) *Verifier {
+	return &Verifier{
+		signingSecret: signingSecret,
+		claimsStore:   claimsStore,
+		now:           time.Now,
+	}
+}
+
+func (v *Verifier) verify(ctx context.Context, header http.Header, body []byte) error {
 	signature := header.Get("X-Slack-Signature")
 	if signature == "" {
 		return fmt.Errorf("X-Slack-Signature is missing")
 	}
 
-	mac := header.Get("X-Slack-Request-Timestamp")
-	if mac == "" {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	if timestamp == "" {
 		return fmt.Errorf("X-Slack-Request-Timestamp is missing")
 	}
 
-	baseString := fmt.Sprintf("v0:%s:%s", mac, string(body))
-	hash := hmac.New(sha256.New, []byte(signingSecret))
-	if _, err := hash.Write([]byte(baseString)); err != nil {
-		return fmt.Errorf("failed to calculate signature: %w", err)
+	expected, err := signatureV0(timestamp, body, v.signingSecret)
+	if err != nil {
+		return err
 	}
-
-	expected := fmt.Sprintf("v0=%x", hash.Sum(nil))
-	if expected != signature {
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
 		return fmt.Errorf("invalid signature")
 	}
 
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Slack-Request-Timestamp: %w", err)
+	}
+	now := v.now()
+	sentAt := time.Unix(seconds, 0)
+	if age := now.Sub(sentAt); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("request timestamp is too far from now: %s", age)
+	}
+
+	// after that, the timestamp is too old to be accepted
+	err = v.claimsStore.Claim(ctx, fmt.Sprintf("slack/signature/%s", signature), now, sentAt.Add(maxRequestAge))
+	switch {
+	case errors.Is(err, claims.ErrClaimed):
+		return fmt.Errorf("request is replayed")
+	case err != nil:
+		return fmt.Errorf("failed to claim signature: %w", err)
+	}
+
 	return nil
 }
 
-func ParseRequest(r *http.Request, signingSecret string) (*CommandRequest, *ActionsRequest, *ChallangeRequest, error) {
+func signatureV0(timestamp string, body []byte, signingSecret string) (string, error) {
+	baseString := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
+	hash := hmac.New(sha256.New, []byte(signingSecret))
+	if _, err := hash.Write([]byte(baseString)); err != nil {
+		return "", fmt.Errorf("failed to calculate signature: %w", err)
+	}
+	return fmt.Sprintf("v0=%x", hash.Sum(nil)), nil
+}
+
+// ParseRequest reads a request from Slack. If verifier is nil, the request signature is not verified, which is
+// only meant for local development.
+func ParseRequest(r *http.Request, verifier *Verifier) (*CommandRequest, *ActionsRequest, *ChallangeRequest, *EventRequest, error) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to read request body: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to read request body: %w", err)
 	}
 
-	if len(signingSecret) > 0 {
-		if err := verifySlackSignatureV0(r.Header, body, signingSecret); err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to verify request signature: %w", err)
+	if verifier != nil {
+		if err := verifier.verify(r.Context(), r.Header, body); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to verify request signature: %w", err)
 		}
 	}
 
@@ -83,29 +201,55 @@ func ParseRequest(r *http.Request, signingSecret string) (*CommandRequest, *Acti
 	case "application/x-www-form-urlencoded":
 		values, err := url.ParseQuery(string(body))
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to parse request body: %w", err)
+			return nil, nil, nil, nil, fmt.Errorf("failed to parse request body: %w", err)
 		}
 		if payload := values.Get("payload"); payload != "" {
 			actionsRequest := &ActionsRequest{}
 			if err := json.NewDecoder(strings.NewReader(payload)).Decode(actionsRequest); err != nil {
-				return nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+				return nil, nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+			}
+			if actionsRequest.User == nil {
+				return nil, nil, nil, nil, fmt.Errorf("user is missing")
 			}
-			return nil, actionsRequest, nil, nil
+			if actionsRequest.Type == interactionTypeViewSubmission && actionsRequest.View == nil {
+				return nil, nil, nil, nil, fmt.Errorf("view is missing")
+			}
+			return nil, actionsRequest, nil, nil, nil
 		} else {
 			return &CommandRequest{
-				Command:  values.Get("command"),
-				Text:     values.Get("text"),
-				UserID:   values.Get("user_id"),
-				UserName: values.Get("user_name"),
-			}, nil, nil, nil
+				Command:   values.Get("command"),
+				Text:      values.Get("text"),
+				UserID:    values.Get("user_id"),
+				UserName:  values.Get("user_name"),
+				ChannelID: values.Get("channel_id"),
+				TriggerID: values.Get("trigger_id"),
+			}, nil, nil, nil, nil
 		}
 	case "application/json":
-		challangeReq := &ChallangeRequest{}
-		if err := json.NewDecoder(bytes.NewReader(body)).Decode(challangeReq); err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+		envelope := &struct {
+			Type string `json:"type"`
+		}{}
+		if err := json.Unmarshal(body, envelope); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+		}
+		switch envelope.Type {
+		case "event_callback":
+			eventReq := &EventRequest{}
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(eventReq); err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+			}
+			if eventReq.Event == nil {
+				return nil, nil, nil, nil, fmt.Errorf("event is missing")
+			}
+			return nil, nil, nil, eventReq, nil
+		default:
+			challangeReq := &ChallangeRequest{}
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(challangeReq); err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+			}
+			return nil, nil, challangeReq, nil, nil
 		}
-		return nil, nil, challangeReq, nil
 	default:
-		return nil, nil, nil, fmt.Errorf("unsupported content type: %s", ct)
+		return nil, nil, nil, nil, fmt.Errorf("unsupported content type: %s", ct)
 	}
 }
@@ -0,0 +1,263 @@
+package slack
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/store"
+)
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	const secret = "secret"
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	commandBody := url.Values{
+		"command":    {"/roll"},
+		"user_id":    {"U1"},
+		"user_name":  {"user"},
+		"channel_id": {"C1"},
+		"trigger_id": {"T1"},
+	}.Encode()
+	actionsBody := url.Values{
+		"payload": {`{"type": "block_actions", "user": {"id": "U1"}, "actions": [{"action_id": "boost", "value": "P1"}]}`},
+	}.Encode()
+	eventBody := `{"type": "event_callback", "event_id": "Ev1", "event": {"type": "app_mention", "user": "U1"}}`
+	challangeBody := `{"type": "url_verification", "challenge": "challenge"}`
+
+	type request struct {
+		body        string
+		contentType string
+		timestamp   string
+		// signature is calculated from the body and timestamp if empty.
+		signature string
+	}
+
+	signed := func(body, contentType string, at time.Time) request {
+		return request{body: body, contentType: contentType, timestamp: strconv.FormatInt(at.Unix(), 10)}
+	}
+
+	testCases := []struct {
+		name      string
+		requests  []request
+		unsigned  bool
+		expectErr bool
+		check     func(*CommandRequest, *ActionsRequest, *ChallangeRequest, *EventRequest) bool
+	}{
+		{
+			name:     "command",
+			requests: []request{signed(commandBody, "application/x-www-form-urlencoded", now)},
+			check: func(c *CommandRequest, a *ActionsRequest, ch *ChallangeRequest, e *EventRequest) bool {
+				return c != nil && c.Command == "/roll" && c.UserID == "U1" && c.ChannelID == "C1" && c.TriggerID == "T1"
+			},
+		},
+		{
+			name:     "actions",
+			requests: []request{signed(actionsBody, "application/x-www-form-urlencoded", now)},
+			check: func(c *CommandRequest, a *ActionsRequest, ch *ChallangeRequest, e *EventRequest) bool {
+				return a != nil && a.User.ID == "U1" && len(a.Actions) == 1 && a.Actions[0].ActionID == "boost"
+			},
+		},
+		{
+			name:     "event",
+			requests: []request{signed(eventBody, "application/json", now)},
+			check: func(c *CommandRequest, a *ActionsRequest, ch *ChallangeRequest, e *EventRequest) bool {
+				return e != nil && e.EventID == "Ev1" && e.Event.Type == "app_mention"
+			},
+		},
+		{
+			name:     "challange",
+			requests: []request{signed(challangeBody, "application/json", now)},
+			check: func(c *CommandRequest, a *ActionsRequest, ch *ChallangeRequest, e *EventRequest) bool {
+				return ch != nil && ch.Challenge == "challenge"
+			},
+		},
+		{
+			name:     "slightly old request",
+			requests: []request{signed(commandBody, "application/x-www-form-urlencoded", now.Add(-maxRequestAge+time.Second))},
+			check: func(c *CommandRequest, a *ActionsRequest, ch *ChallangeRequest, e *EventRequest) bool {
+				return c != nil
+			},
+		},
+		{
+			name:      "missing signature",
+			requests:  []request{{body: commandBody, contentType: "application/x-www-form-urlencoded", timestamp: "1"}},
+			unsigned:  true,
+			expectErr: true,
+		},
+		{
+			name: "missing timestamp",
+			requests: []request{{
+				body:        commandBody,
+				contentType: "application/x-www-form-urlencoded",
+				signature:   "v0=00",
+			}},
+			expectErr: true,
+		},
+		{
+			name: "invalid signature",
+			requests: []request{{
+				body:        commandBody,
+				contentType: "application/x-www-form-urlencoded",
+				timestamp:   strconv.FormatInt(now.Unix(), 10),
+				signature:   "v0=0123456789abcdef",
+			}},
+			expectErr: true,
+		},
+		{
+			name:      "stale timestamp",
+			requests:  []request{signed(commandBody, "application/x-www-form-urlencoded", now.Add(-maxRequestAge-time.Second))},
+			expectErr: true,
+		},
+		{
+			name:      "future timestamp",
+			requests:  []request{signed(commandBody, "application/x-www-form-urlencoded", now.Add(maxRequestAge+time.Second))},
+			expectErr: true,
+		},
+		{
+			name: "malformed timestamp",
+			requests: []request{
+				{body: commandBody, contentType: "application/x-www-form-urlencoded", timestamp: "yesterday"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "replayed request",
+			requests: []request{
+				signed(eventBody, "application/json", now),
+				signed(eventBody, "application/json", now),
+			},
+			expectErr: true,
+		},
+		{
+			name:      "unsupported content type",
+			requests:  []request{signed(eventBody, "text/plain", now)},
+			expectErr: true,
+		},
+		{
+			name:      "malformed payload",
+			requests:  []request{signed(url.Values{"payload": {"{"}}.Encode(), "application/x-www-form-urlencoded", now)},
+			expectErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			verifier := NewVerifier(secret, testClaims(t))
+			verifier.now = func() time.Time { return now }
+
+			var (
+				command   *CommandRequest
+				actions   *ActionsRequest
+				challange *ChallangeRequest
+				event     *EventRequest
+				err       error
+			)
+			for _, req := range testCase.requests {
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(req.body))
+				r.Header.Set("Content-Type", req.contentType)
+				if req.timestamp != "" {
+					r.Header.Set("X-Slack-Request-Timestamp", req.timestamp)
+				}
+				signature := req.signature
+				if signature == "" && !testCase.unsigned {
+					signature, err = signatureV0(req.timestamp, []byte(req.body), secret)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				if signature != "" {
+					r.Header.Set("X-Slack-Signature", signature)
+				}
+				command, actions, challange, event, err = ParseRequest(r, verifier)
+			}
+
+			if testCase.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !testCase.check(command, actions, challange, event) {
+				t.Errorf("unexpected request: %+v %+v %+v %+v", command, actions, challange, event)
+			}
+		})
+	}
+}
+
+func TestParseRequest_noVerifier(t *testing.T) {
+	t.Parallel()
+
+	body := url.Values{"command": {"/roll"}, "user_id": {"U1"}}.Encode()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	command, _, _, _, err := ParseRequest(r, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if command == nil || command.Command != "/roll" {
+		t.Errorf("unexpected command: %+v", command)
+	}
+}
+
+func TestVerifier_replay(t *testing.T) {
+	t.Parallel()
+
+	const secret = "secret"
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	// two server instances
+	claimsStore := testClaims(t)
+	first, second := NewVerifier(secret, claimsStore), NewVerifier(secret, claimsStore)
+
+	// stamped a bit in the future, it's accepted until the timestamp is too old
+	body := []byte(url.Values{"command": {"/roll"}, "user_id": {"U1"}}.Encode())
+	timestamp := strconv.FormatInt(now.Add(4*time.Minute).Unix(), 10)
+	signature, err := signatureV0(timestamp, body, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", signature)
+
+	first.now = func() time.Time { return now }
+	if err := first.verify(context.Background(), header, body); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	second.now = func() time.Time { return now.Add(maxRequestAge + 4*time.Minute) }
+	if err := second.verify(context.Background(), header, body); err == nil {
+		t.Error("expected the replayed request to be rejected")
+	}
+}
+
+func testClaims(t *testing.T) claims.Storage {
+	t.Helper()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.NewBolt(db)
+}
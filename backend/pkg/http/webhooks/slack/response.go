@@ -9,6 +9,11 @@ func ReplaceEphemeral(text string, sections ...*Block) *Message {
 	return NewReplaceMessage(ResponseTypeEphemeral, text, sections...)
 }
 
+// ReplaceInChannel sends a message back visible by everyone in the channel replacing the previous message.
+func ReplaceInChannel(text string, sections ...*Block) *Message {
+	return NewReplaceMessage(ResponseTypeInChannel, text, sections...)
+}
+
 // Ephemeral sends a message back visible only by the caller.
 func Ephemeral(text string, sections ...*Block) *Message {
 	return NewMessage(ResponseTypeEphemeral, text, sections...)
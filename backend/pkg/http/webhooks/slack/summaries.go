@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/rooms"
+)
+
+const (
+	// summaryClaimFor is how long an instance has to post the summary before another instance may post it.
+	summaryClaimFor = time.Minute
+	// summaryPollInterval is how often instances that didn't claim the summary look for it.
+	summaryPollInterval = time.Second
+)
+
+// roomLocks serializes notifications of the same room within the instance, without blocking other rooms.
+type roomLocks struct {
+	guard sync.Mutex
+	locks map[rooms.ID]*roomLock
+}
+
+type roomLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock blocks until the room is unlocked, and returns the function to unlock it.
+func (l *roomLocks) lock(roomID rooms.ID) func() {
+	l.guard.Lock()
+	if l.locks == nil {
+		l.locks = map[rooms.ID]*roomLock{}
+	}
+	lock, ok := l.locks[roomID]
+	if !ok {
+		lock = &roomLock{}
+		l.locks[roomID] = lock
+	}
+	lock.refs++
+	l.guard.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.guard.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, roomID)
+		}
+		l.guard.Unlock()
+	}
+}
+
+// dailyMessage updates the summary posted to the channel for the day, or posts it. Summaries are posted by
+// whichever instance claims them first, the others wait until the summary is saved.
+func (s *Handler) dailyMessage(ctx context.Context, room *lunch.Room, channel *channels.Channel, day, summaryText string, summary []*Block) (*channels.Message, error) {
+	key := fmt.Sprintf("slack/summary/%s/%s/%s", room.ID, channel.ID, day)
+	for {
+		msg, err := s.roller.GetDailyMessage(ctx, room.ID, channel.ID, day)
+		switch {
+		case err == nil:
+			if err := s.updateMessage(ctx, string(channel.ID), msg.TS, summaryText, summary...); err != nil {
+				return nil, fmt.Errorf("failed to update summary: %w", err)
+			}
+			return msg, nil
+		case !errors.Is(err, lunch.ErrNotFound):
+			return nil, err
+		}
+
+		now := time.Now()
+		err = s.claimsStore.Claim(ctx, key, now, now.Add(summaryClaimFor))
+		switch {
+		case err == nil:
+			ts, err := s.postMessage(ctx, string(channel.ID), "", summaryText, summary...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to post summary: %w", err)
+			}
+			return s.roller.SaveDailyMessage(ctx, room.ID, channel.ID, day, ts, now)
+		case !errors.Is(err, claims.ErrClaimed):
+			return nil, fmt.Errorf("failed to claim summary: %w", err)
+		}
+
+		timer := time.NewTimer(summaryPollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
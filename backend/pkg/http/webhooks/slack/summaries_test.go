@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	client_slack "lunch/pkg/slack"
+	"lunch/pkg/store"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestNotifyChannels_summaryOnce(t *testing.T) {
+	t.Parallel()
+
+	guard := &sync.Mutex{}
+	summaries, replies, updates := 0, map[string]int{}, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		guard.Lock()
+		defer guard.Unlock()
+
+		req := &client_slack.PostMessageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		switch {
+		case r.URL.Path == "/chat.update":
+			updates++
+		case req.ThreadTS == "":
+			summaries++
+		default:
+			replies[req.ThreadTS]++
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"ts":"1.1"}`))
+	}))
+	defer server.Close()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+	claimsStore := testClaims(t)
+	client := client_slack.New(&client_slack.Configuration{BaseURL: server.URL, Timeout: time.Second})
+
+	// two server instances notify about events of the room at the same time
+	instances := []*Handler{
+		{cfg: &Configuration{}, roller: roller, client: client, claimsStore: claimsStore},
+		{cfg: &Configuration{}, roller: roller, client: client, claimsStore: claimsStore},
+	}
+	room := &lunch.Room{Room: &rooms.Room{ID: "room", Name: "Room", Settings: rooms.DefaultSettings()}}
+	cc := []*channels.Channel{{ID: "C1", RoomID: room.ID, Platform: channels.PlatformSlack}}
+
+	wg := &sync.WaitGroup{}
+	for _, h := range instances {
+		h := h
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.notifyChannels(context.Background(), room, cc, "rolled"); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	guard.Lock()
+	defer guard.Unlock()
+	if summaries != 1 {
+		t.Errorf("expected one summary, got %d", summaries)
+	}
+	if updates != 1 {
+		t.Errorf("expected the summary to be updated once, got %d", updates)
+	}
+	if replies["1.1"] != 2 {
+		t.Errorf("expected both replies in the summary thread, got %+v", replies)
+	}
+}
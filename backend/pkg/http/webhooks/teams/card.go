@@ -0,0 +1,115 @@
+package teams
+
+import (
+	"fmt"
+	"log"
+)
+
+const (
+	cardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	cardVersion     = "1.4"
+	cardContentType = "application/vnd.microsoft.card.adaptive"
+)
+
+type elementType string
+
+const (
+	elementTypeTextBlock elementType = "TextBlock"
+	elementTypeFactSet   elementType = "FactSet"
+)
+
+// https://adaptivecards.io/explorer/Fact.html
+type Fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Element is an element of the card body.
+type Element struct {
+	Type   elementType `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []*Fact     `json:"facts,omitempty"`
+}
+
+// https://adaptivecards.io/explorer/TextBlock.html
+func TextBlock(format string, a ...interface{}) *Element {
+	return &Element{
+		Type: elementTypeTextBlock,
+		Text: fmt.Sprintf(format, a...),
+		Wrap: true,
+	}
+}
+
+// Heading is a bold text block.
+func Heading(format string, a ...interface{}) *Element {
+	e := TextBlock(format, a...)
+	e.Weight = "Bolder"
+	e.Size = "Medium"
+	return e
+}
+
+// https://adaptivecards.io/explorer/FactSet.html
+func FactSet(facts ...*Fact) *Element {
+	return &Element{
+		Type:  elementTypeFactSet,
+		Facts: facts,
+	}
+}
+
+// https://adaptivecards.io/explorer/AdaptiveCard.html
+type Card struct {
+	Type    string     `json:"type"`
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Body    []*Element `json:"body"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     *Card  `json:"content"`
+}
+
+// Message is both a response to an outgoing webhook, and a message posted to an incoming webhook.
+type Message struct {
+	Type        string        `json:"type"`
+	Text        string        `json:"text,omitempty"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+}
+
+// CardMessage returns a message with an adaptive card.
+func CardMessage(body ...*Element) *Message {
+	return &Message{
+		Type: "message",
+		Attachments: []*Attachment{{
+			ContentType: cardContentType,
+			Content: &Card{
+				Type:    "AdaptiveCard",
+				Schema:  cardSchema,
+				Version: cardVersion,
+				Body:    body,
+			},
+		}},
+	}
+}
+
+// TextMessage returns a plain text message.
+func TextMessage(format string, a ...interface{}) *Message {
+	return &Message{
+		Type: "message",
+		Text: fmt.Sprintf(format, a...),
+	}
+}
+
+// BadRequest tells the user that the request was invalid.
+func BadRequest(err error) *Message {
+	return TextMessage("%s", err)
+}
+
+// InternalServerError tells the user that the request failed.
+func InternalServerError(err error) *Message {
+	log.Printf("[ERROR] %s", err)
+	return TextMessage("Sorry, that didn't work. Try again or contact the app administrator.")
+}
@@ -0,0 +1,47 @@
+package teams
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Configuration struct {
+	// SecurityToken is the key outgoing webhook requests are signed with, shown when the webhook is created in Teams.
+	SecurityToken []byte
+	// Timeout is how long posting to an incoming webhook may take.
+	Timeout time.Duration
+}
+
+// Enabled returns true if Teams webhooks are configured.
+func (c *Configuration) Enabled() bool {
+	return len(c.SecurityToken) > 0
+}
+
+func (c *Configuration) Parse() error {
+	securityToken := os.Getenv("TEAMS_SECURITY_TOKEN")
+	if securityToken == "" {
+		log.Printf("[INFO] TEAMS_SECURITY_TOKEN is not set, teams webhooks are disabled")
+	} else {
+		key, err := base64.StdEncoding.DecodeString(securityToken)
+		if err != nil {
+			return fmt.Errorf("failed to decode TEAMS_SECURITY_TOKEN: %w", err)
+		}
+		c.SecurityToken = key
+	}
+
+	c.Timeout = defaultTimeout
+	if timeout := os.Getenv("TEAMS_WEBHOOK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("TEAMS_WEBHOOK_TIMEOUT must be a duration, like 10s: %w", err)
+		}
+		c.Timeout = d
+	}
+
+	return nil
+}
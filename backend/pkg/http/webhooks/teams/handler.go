@@ -0,0 +1,406 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+	service_users "lunch/pkg/users/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/sync/errgroup"
+)
+
+type Handler struct {
+	cfg          *Configuration
+	roller       *lunch.Roller
+	client       *http.Client
+	usersService *service_users.Service
+}
+
+func NewHandler(cfg *Configuration, roller *lunch.Roller, usersService *service_users.Service) http.Handler {
+	h := &Handler{
+		cfg:    cfg,
+		roller: roller,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// webhook hosts are checked before posting, redirects could lead anywhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		usersService: usersService,
+	}
+
+	roller.OnRollCreated(h.onRollCreated)
+	roller.OnBoostCreated(h.onBoostCreated)
+	roller.OnVetoCreated(h.onVetoCreated)
+	roller.OnPlaceCreated(h.onPlaceCreated)
+
+	r := chi.NewMux()
+	r.With(middleware.AllowContentType("application/json")).Post("/", h.ServeHTTP)
+
+	return r
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	activity, err := ParseRequest(r, h.cfg.SecurityToken)
+	if err != nil {
+		log.Printf("[WARN] failed to parse request: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("[INFO] incoming activity: %s %s", activity.ID, activity.Type)
+
+	user := &users.User{ID: users.TeamsID(activity.UserID()), Name: activity.From.Name}
+	if err := h.usersService.Create(r.Context(), user); err != nil {
+		log.Printf("[ERROR] failed to create user: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx := users.NewContext(r.Context(), user)
+	response := h.handleCommand(ctx, activity.ChannelID(), activity.Command())
+	if err := respondJSON(w, response); err != nil {
+		log.Printf("[ERROR] failed to marshal response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func respondJSON(w http.ResponseWriter, body interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(body)
+}
+
+func (h *Handler) handleCommand(ctx context.Context, channelID, command string) *Message {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return help()
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(command, args[0]))
+	switch strings.ToLower(args[0]) {
+	case "roll":
+		return h.handleRoll(ctx, channelID)
+	case "add":
+		return h.handleAdd(ctx, channelID, rest)
+	case "list":
+		return h.handleList(ctx, channelID)
+	case "boost":
+		return h.handleBoost(ctx, channelID, rest)
+	case "join":
+		return h.handleJoin(ctx, rest)
+	case "bind":
+		if len(args) != 3 {
+			return help()
+		}
+		return h.handleBind(ctx, channelID, args[1], args[2])
+	default:
+		return help()
+	}
+}
+
+func help() *Message {
+	return CardMessage(
+		Heading("Lunch commands"),
+		FactSet(
+			&Fact{Title: "join <room id>", Value: "join a room, ask a member for its id"},
+			&Fact{Title: "bind <room> <incoming webhook url>", Value: "bind this channel to a room, notifications are posted with the incoming webhook"},
+			&Fact{Title: "roll", Value: "roll for a lunch place"},
+			&Fact{Title: "add <place>", Value: "add a new place"},
+			&Fact{Title: "list", Value: "see places and their odds"},
+			&Fact{Title: "boost <place>", Value: "spend a point to make a place more likely"},
+		),
+	)
+}
+
+// channelRoomID returns id of the room the channel is bound to. If the channel is not bound to any room,
+// a message explaining how to bind it is returned instead.
+func (h *Handler) channelRoomID(ctx context.Context, channelID string) (rooms.ID, *Message) {
+	channel, err := h.roller.GetChannel(ctx, channels.ID(channelID))
+	switch {
+	case err == nil && channel.Platform == channels.PlatformTeams:
+		return channel.RoomID, nil
+	case err == nil, errors.Is(err, lunch.ErrNotFound):
+		return "", TextMessage("This channel is not bound to a room, use bind <room> <incoming webhook url> to bind it")
+	default:
+		return "", InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleRoll(ctx context.Context, channelID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	roll, err := h.roller.CreateRoll(ctx, roomID, time.Now())
+	switch {
+	case err == nil:
+		return CardMessage(TextBlock("You rolled **%s**", roll.Place.Name))
+	case errors.Is(err, lunch.ErrNoPoints):
+		return TextMessage("Failed to roll: no more points left")
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return TextMessage("No places to choose from, add some!")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleAdd(ctx context.Context, channelID, placeName string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	err := h.roller.CreatePlace(ctx, roomID, placeName)
+	switch {
+	case err == nil:
+		return CardMessage(TextBlock("**%s** added!", placeName))
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrAlreadyExists):
+		return BadRequest(err)
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleList(ctx context.Context, channelID string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	chances, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return TextMessage("No places to choose from, add some!")
+	default:
+		return InternalServerError(err)
+	}
+
+	sort.Slice(chances, func(i, j int) bool {
+		return chances[i].Name < chances[j].Name
+	})
+	sort.SliceStable(chances, func(i, j int) bool {
+		return chances[i].Chance < chances[j].Chance
+	})
+
+	facts := make([]*Fact, 0, len(chances))
+	for _, chance := range chances {
+		facts = append(facts, &Fact{Title: chance.Name, Value: fmt.Sprintf("%.2f%%", chance.Chance*100)})
+	}
+
+	quota, err := h.roller.Quota(ctx, roomID, time.Now())
+	if err != nil {
+		return InternalServerError(err)
+	}
+	points := TextBlock("You have **%d** points left, they reset on %s.", quota.Points, quota.ResetsAt.Format("Monday, January 2"))
+	if quota.FreeRoll {
+		points.Text += " Today's free roll is still available."
+	}
+
+	return CardMessage(Heading("Odds"), FactSet(facts...), points)
+}
+
+func (h *Handler) handleBoost(ctx context.Context, channelID, placeName string) *Message {
+	roomID, msg := h.channelRoomID(ctx, channelID)
+	if msg != nil {
+		return msg
+	}
+
+	pp, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return InternalServerError(err)
+	}
+
+	var place *lunch.Place
+	for _, p := range pp {
+		if strings.EqualFold(p.Name, placeName) {
+			place = p
+			break
+		}
+	}
+	if place == nil {
+		return TextMessage("Failed to boost: %s not found", placeName)
+	}
+
+	err = h.roller.CreateBoost(ctx, roomID, place.ID, time.Now())
+	switch {
+	case err == nil:
+		return CardMessage(TextBlock("Boosted **%s**", place.Name))
+	case errors.Is(err, lunch.ErrNoPoints):
+		return TextMessage("Failed to boost: no more points left")
+	case errors.Is(err, lunch.ErrNotFound):
+		return TextMessage("Failed to boost: place not found")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleJoin(ctx context.Context, roomID string) *Message {
+	if roomID == "" {
+		return help()
+	}
+
+	err := h.roller.JoinRoom(ctx, rooms.ID(roomID))
+	switch {
+	case err == nil:
+		room, err := h.roller.GetRoom(ctx, rooms.ID(roomID))
+		if err != nil {
+			return InternalServerError(err)
+		}
+		return TextMessage("You joined %s", room.Name)
+	case errors.Is(err, lunch.ErrNotFound):
+		return TextMessage("Room %s not found", roomID)
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) handleBind(ctx context.Context, channelID, roomName, webhookURL string) *Message {
+	if channelID == "" {
+		return TextMessage("Only channels can be bound to a room")
+	}
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return TextMessage("%s must be an incoming webhook url, like https://example.webhook.office.com/...", webhookURL)
+	}
+	sealedURL, err := sealWebhookURL(h.cfg.SecurityToken, webhookURL)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	rr, err := h.roller.ListRooms(ctx)
+	if err != nil {
+		return InternalServerError(err)
+	}
+
+	matches := []*lunch.Room{}
+	for _, room := range rr {
+		if string(room.ID) == roomName || strings.EqualFold(room.Name, roomName) {
+			matches = append(matches, room)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return TextMessage("You are not a member of %s, use join <room id> first", roomName)
+	case 1:
+	default:
+		return TextMessage("There are multiple rooms called %s, use room id instead", roomName)
+	}
+
+	room := matches[0]
+	err = h.roller.BindTeamsChannel(ctx, room.ID, channels.ID(channelID), sealedURL, time.Now())
+	switch {
+	case err == nil:
+		return TextMessage("This channel is now bound to %s", room.Name)
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return TextMessage("You are not a member of %s, or of the room this channel is bound to", room.Name)
+	case errors.Is(err, lunch.ErrAlreadyExists):
+		return TextMessage("This channel is already bound by another app")
+	default:
+		return InternalServerError(err)
+	}
+}
+
+func (h *Handler) onRollCreated(ctx context.Context, roll *lunch.Roll) error {
+	return h.notify(ctx, roll.RoomID, TextBlock("%s rolled **%s**", userName(roll.User), roll.Place.Name))
+}
+
+func (h *Handler) onBoostCreated(ctx context.Context, boost *lunch.Boost) error {
+	return h.notify(ctx, boost.RoomID, TextBlock("%s boosted **%s**", userName(boost.User), boost.Place.Name))
+}
+
+func (h *Handler) onVetoCreated(ctx context.Context, veto *lunch.Veto) error {
+	return h.notify(ctx, veto.RoomID, TextBlock("%s vetoed **%s**", userName(veto.User), veto.Place.Name))
+}
+
+func (h *Handler) onPlaceCreated(ctx context.Context, place *lunch.Place) error {
+	return h.notify(ctx, place.RoomID, TextBlock("%s added **%s**", userName(place.User), place.Name))
+}
+
+func userName(user *users.User) string {
+	if user == nil {
+		return "Someone"
+	}
+	return user.Name
+}
+
+// notify posts the card to all Teams channels bound to the room.
+func (h *Handler) notify(ctx context.Context, roomID rooms.ID, body ...*Element) error {
+	cc, err := h.roller.ListChannels(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	msg := CardMessage(body...)
+
+	// one failed channel should not stop the rest
+	var wg errgroup.Group
+	for _, channel := range cc {
+		if channel.Platform != channels.PlatformTeams || channel.WebhookURL == "" {
+			continue
+		}
+		channel := channel
+		wg.Go(func() error {
+			return lunch.Once(ctx, fmt.Sprintf("channel/%s", channel.ID), func() error {
+				return h.notifyChannel(ctx, channel, msg)
+			})
+		})
+	}
+	return wg.Wait()
+}
+
+// notifyChannel posts the card to the channel's incoming webhook.
+func (h *Handler) notifyChannel(ctx context.Context, channel *channels.Channel, msg *Message) error {
+	webhookURL, err := openWebhookURL(h.cfg.SecurityToken, channel.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("failed to open webhook url of channel %s: %w", channel.ID, err)
+	}
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return fmt.Errorf("invalid webhook url of channel %s: %w", channel.ID, err)
+	}
+	if err := h.post(ctx, webhookURL, msg); err != nil {
+		return fmt.Errorf("failed to notify channel %s: %w", channel.ID, err)
+	}
+	return nil
+}
+
+// post sends the message to an incoming webhook.
+func (h *Handler) post(ctx context.Context, webhookURL string, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package teams
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	service_users "lunch/pkg/users/service"
+	storage_users "lunch/pkg/users/storage"
+)
+
+var securityToken = []byte("security-token")
+
+func sign(body string, key []byte) string {
+	hash := hmac.New(sha256.New, key)
+	hash.Write([]byte(body))
+	return "HMAC " + base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func activityBody(userID, text string) string {
+	return fmt.Sprintf(`{
+		"type": "message",
+		"id": "1",
+		"text": "<at>Lunch</at>&nbsp;%s",
+		"from": {"id": "29:%s", "name": "%s", "aadObjectId": "%s"},
+		"conversation": {"id": "19:channel@thread.tacv2;messageid=1"},
+		"channelData": {"channel": {"id": "19:channel@thread.tacv2"}}
+	}`, text, userID, userID, userID)
+}
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	body := activityBody("user", "add  Pizza &amp; Pasta")
+
+	testCases := []struct {
+		name      string
+		auth      string
+		expectErr bool
+	}{
+		{"valid", sign(body, securityToken), false},
+		{"missing signature", "", true},
+		{"other scheme", "Bearer token", true},
+		{"wrong key", sign(body, []byte("other")), true},
+		{"malformed signature", "HMAC !!!", true},
+	}
+
+	for _, testCase := range testCases {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if testCase.auth != "" {
+			r.Header.Set("Authorization", testCase.auth)
+		}
+
+		activity, err := ParseRequest(r, securityToken)
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", testCase.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", testCase.name, err)
+		}
+		if command := activity.Command(); command != "add Pizza & Pasta" {
+			t.Errorf("%s: unexpected command '%s'", testCase.name, command)
+		}
+		if channelID := activity.ChannelID(); channelID != "19:channel@thread.tacv2" {
+			t.Errorf("%s: unexpected channel '%s'", testCase.name, channelID)
+		}
+		if userID := activity.UserID(); userID != "user" {
+			t.Errorf("%s: unexpected user '%s'", testCase.name, userID)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	posted := make(chan *Message, 10)
+	incoming := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := &Message{}
+		if err := json.NewDecoder(r.Body).Decode(msg); err != nil {
+			t.Errorf("failed to decode message: %s", err)
+		}
+		posted <- msg
+	}))
+	defer incoming.Close()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bolt, err := store.NewBolt(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersStore := storage_users.NewBolt(bolt)
+	roller := lunch.New(events.NewBoltStorage(bolt), usersStore)
+
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ownerCtx := users.NewContext(context.Background(), owner)
+	if err := roller.CreateRoom(ownerCtx, "room"); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := roller.ListRooms(ownerCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID := rr[0].ID
+
+	// webhook urls must be on teams hosts, so the client connects to the test server instead
+	transport := incoming.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, incoming.Listener.Addr().String())
+	}
+	webhookURL := "https://example.webhook.office.com/webhookb2/channel"
+
+	h := &Handler{
+		cfg:          &Configuration{SecurityToken: securityToken},
+		roller:       roller,
+		client:       &http.Client{Transport: transport},
+		usersService: service_users.New(usersStore),
+	}
+	roller.OnRollCreated(h.onRollCreated)
+	roller.OnPlaceCreated(h.onPlaceCreated)
+
+	send := func(text string) *Message {
+		body := activityBody("user", text)
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Authorization", sign(body, securityToken))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", text, w.Code)
+		}
+		msg := &Message{}
+		if err := json.NewDecoder(w.Body).Decode(msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	text := func(msg *Message) string {
+		if msg.Text != "" {
+			return msg.Text
+		}
+		texts := []string{}
+		for _, element := range msg.Attachments[0].Content.Body {
+			texts = append(texts, element.Text)
+			for _, fact := range element.Facts {
+				texts = append(texts, fact.Title, fact.Value)
+			}
+		}
+		return strings.Join(texts, " ")
+	}
+
+	waitPosted := func(expected string) {
+		if err := roller.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-posted:
+			if got := text(msg); !strings.Contains(got, expected) {
+				t.Errorf("expected notification about '%s', got '%s'", expected, got)
+			}
+		default:
+			t.Fatalf("expected notification about '%s'", expected)
+		}
+	}
+
+	testCases := []struct {
+		command  string
+		expected string
+	}{
+		{"roll", "not bound"},
+		{fmt.Sprintf("bind room %s", webhookURL), "not a member"},
+		{fmt.Sprintf("join %s", roomID), "You joined room"},
+		{"bind room http://insecure.example", "must be an incoming webhook url"},
+		{fmt.Sprintf("bind room %s", incoming.URL), "must be an incoming webhook url"},
+		{"bind room https://169.254.169.254/latest/meta-data", "must be an incoming webhook url"},
+		{fmt.Sprintf("bind room %s", webhookURL), "now bound to room"},
+		{"roll", "No places"},
+		{"add Pizza", "Pizza"},
+		{"add pizza", "already exists"},
+		{"list", "100.00%"},
+		{"boost Sushi", "Sushi not found"},
+		{"help", "Lunch commands"},
+	}
+
+	for _, testCase := range testCases {
+		if got := text(send(testCase.command)); !strings.Contains(got, testCase.expected) {
+			t.Errorf("%s: expected '%s', got '%s'", testCase.command, testCase.expected, got)
+		}
+		if testCase.command == "add Pizza" {
+			waitPosted("user added **Pizza**")
+		}
+	}
+
+	if got := text(send("roll")); !strings.Contains(got, "You rolled **Pizza**") {
+		t.Errorf("roll: unexpected response '%s'", got)
+	}
+	waitPosted("user rolled **Pizza**")
+}
@@ -0,0 +1,120 @@
+package teams
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// https://learn.microsoft.com/en-us/azure/bot-service/rest-api/bot-framework-rest-connector-api-reference#channelaccount-object
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// AADObjectID is the id of the user in Azure Active Directory, it's the same in all teams.
+	AADObjectID string `json:"aadObjectId"`
+}
+
+type Conversation struct {
+	ID string `json:"id"`
+}
+
+type ChannelData struct {
+	Channel *struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+}
+
+// Activity is a message sent to an outgoing webhook.
+// https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/add-outgoing-webhook
+type Activity struct {
+	Type         string        `json:"type"`
+	ID           string        `json:"id"`
+	Text         string        `json:"text"`
+	From         *Account      `json:"from"`
+	Conversation *Conversation `json:"conversation"`
+	ChannelData  *ChannelData  `json:"channelData"`
+}
+
+// UserID returns the id of the user who sent the message.
+func (a *Activity) UserID() string {
+	if a.From.AADObjectID != "" {
+		return a.From.AADObjectID
+	}
+	return a.From.ID
+}
+
+// ChannelID returns the id of the channel the message was sent in. Conversation ids of replies end
+// with the id of the message, like 19:abc@thread.tacv2;messageid=123.
+func (a *Activity) ChannelID() string {
+	if a.ChannelData != nil && a.ChannelData.Channel != nil && a.ChannelData.Channel.ID != "" {
+		return a.ChannelData.Channel.ID
+	}
+	if a.Conversation == nil {
+		return ""
+	}
+	return strings.SplitN(a.Conversation.ID, ";", 2)[0]
+}
+
+var mentionRE = regexp.MustCompile(`<at>.*?</at>`)
+
+// Command returns the text of the message without the mention of the webhook.
+func (a *Activity) Command() string {
+	text := mentionRE.ReplaceAllString(a.Text, "")
+	// Teams sends the text as html
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func verifyHMAC(header http.Header, body []byte, securityToken []byte) error {
+	auth := header.Get("Authorization")
+	if auth == "" {
+		return fmt.Errorf("Authorization is missing")
+	}
+
+	const scheme = "HMAC "
+	if !strings.HasPrefix(auth, scheme) {
+		return fmt.Errorf("unsupported authorization scheme")
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, scheme))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	hash := hmac.New(sha256.New, securityToken)
+	if _, err := hash.Write(body); err != nil {
+		return fmt.Errorf("failed to calculate signature: %w", err)
+	}
+	if !hmac.Equal(hash.Sum(nil), signature) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// ParseRequest reads a message sent to the outgoing webhook, and verifies that it's signed with the security token.
+func ParseRequest(r *http.Request, securityToken []byte) (*Activity, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	if err := verifyHMAC(r.Header, body, securityToken); err != nil {
+		return nil, fmt.Errorf("failed to verify request signature: %w", err)
+	}
+
+	activity := &Activity{}
+	if err := json.Unmarshal(body, activity); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload as json: %w", err)
+	}
+	if activity.From == nil {
+		return nil, fmt.Errorf("sender is missing")
+	}
+	return activity, nil
+}
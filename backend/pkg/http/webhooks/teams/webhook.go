@@ -0,0 +1,42 @@
+package teams
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"lunch/pkg/seal"
+)
+
+// validateWebhookURL returns an error unless the url is a Teams incoming webhook, so that the server doesn't
+// post to arbitrary hosts.
+func validateWebhookURL(webhookURL string) error {
+	u, err := url.ParseRequestURI(webhookURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("scheme must be https")
+	}
+	if u.Port() != "" {
+		return fmt.Errorf("port is not allowed")
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "outlook.office.com" && !strings.HasSuffix(host, ".webhook.office.com") {
+		return fmt.Errorf("host %s is not a teams webhook host", host)
+	}
+	return nil
+}
+
+// webhookURLPurpose separates keys of sealed urls from other values sealed with the security token.
+const webhookURLPurpose = "lunch/teams/webhook-url"
+
+// sealWebhookURL encrypts the url with a key derived from the security token, because anyone who knows the
+// url can post to the channel. Bindings have to be made again when the token changes.
+func sealWebhookURL(securityToken []byte, webhookURL string) (string, error) {
+	return seal.Seal(securityToken, webhookURLPurpose, webhookURL)
+}
+
+func openWebhookURL(securityToken []byte, sealed string) (string, error) {
+	return seal.Open(securityToken, webhookURLPurpose, sealed)
+}
@@ -0,0 +1,30 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"lunch/pkg/pubsub"
+)
+
+type Configuration struct {
+	// PubSub fans broadcasts out to connections of every instance.
+	PubSub pubsub.PubSub
+}
+
+func (c *Configuration) Parse() error {
+	redisURL := os.Getenv("PUBSUB_REDIS_URL")
+	if redisURL == "" {
+		log.Printf("[INFO] PUBSUB_REDIS_URL is not set, websocket broadcasts are not shared between instances")
+		c.PubSub = pubsub.NewMemory()
+		return nil
+	}
+
+	redis, err := pubsub.NewRedis(redisURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse PUBSUB_REDIS_URL: %w", err)
+	}
+	c.PubSub = redis
+	return nil
+}
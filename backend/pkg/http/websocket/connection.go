@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"io"
+	"sync"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+type connection struct {
+	conn       io.ReadWriter
+	writeGuard *sync.Mutex
+
+	user *users.User
+
+	rooms      map[rooms.ID]bool
+	roomsGuard *sync.RWMutex
+}
+
+func newConnection(conn io.ReadWriter, user *users.User) *connection {
+	return &connection{
+		conn:       conn,
+		writeGuard: &sync.Mutex{},
+		user:       user,
+		rooms:      map[rooms.ID]bool{},
+		roomsGuard: &sync.RWMutex{},
+	}
+}
+
+func (c *connection) Subscribe(roomID rooms.ID) {
+	c.roomsGuard.Lock()
+	c.rooms[roomID] = true
+	c.roomsGuard.Unlock()
+}
+
+func (c *connection) Unsubscribe(roomID rooms.ID) {
+	c.roomsGuard.Lock()
+	delete(c.rooms, roomID)
+	c.roomsGuard.Unlock()
+}
+
+func (c *connection) IsSubscribed(roomID rooms.ID) bool {
+	c.roomsGuard.RLock()
+	defer c.roomsGuard.RUnlock()
+	return c.rooms[roomID]
+}
+
+func (c *connection) Write(op ws.OpCode, resp *response) error {
+	c.writeGuard.Lock()
+	defer c.writeGuard.Unlock()
+	return writeResponse(c.conn, op, resp)
+}
+
+// WriteRaw writes the already encoded response.
+func (c *connection) WriteRaw(op ws.OpCode, resp []byte) error {
+	c.writeGuard.Lock()
+	defer c.writeGuard.Unlock()
+	return wsutil.WriteServerMessage(c.conn, op, resp)
+}
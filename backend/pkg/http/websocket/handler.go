@@ -8,11 +8,19 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/notifications"
 	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/schedules"
+	"lunch/pkg/lunch/webhooks"
+	"lunch/pkg/pubsub"
 	"lunch/pkg/users"
 
 	"github.com/go-chi/chi/v5"
@@ -21,65 +29,190 @@ import (
 	"github.com/google/uuid"
 )
 
-const roomID = "69c83096-995a-48ce-b843-80a926b0a9ec"
+// broadcastChannel is the pubsub channel broadcasts are fanned out with.
+const broadcastChannel = "lunch/websocket/broadcast"
 
 type handler struct {
 	roller *lunch.Roller
+	pubsub pubsub.PubSub
 
-	openConnections      map[string]io.ReadWriter
+	openConnections      map[string]*connection
 	openConnectionsGuard *sync.RWMutex
 }
 
-func Handler(roller *lunch.Roller) http.Handler {
+func Handler(cfg *Configuration, roller *lunch.Roller) (http.Handler, error) {
 	r := chi.NewMux()
 	h := &handler{
 		roller: roller,
+		pubsub: cfg.PubSub,
 
-		openConnections:      map[string]io.ReadWriter{},
+		openConnections:      map[string]*connection{},
 		openConnectionsGuard: &sync.RWMutex{},
 	}
+	if err := h.pubsub.Subscribe(context.Background(), broadcastChannel, h.deliver); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to broadcasts: %w", err)
+	}
 	r.Get("/", h.ServeHTTP)
-	roller.OnBoostCreated(h.onBoostCreated)
-	roller.OnPlaceCreated(h.onPlaceCreated)
-	roller.OnRollCreated(h.onRollCreated)
-	roller.OnRoomCreated(h.onRoomCreated)
-	roller.OnRoomUpdated(h.onRoomUpdated)
-	return r
+
+	// with a distributed pubsub, the instance that stored the event broadcasts it to everyone
+	opts := []lunch.SubscribeOption{}
+	if !h.pubsub.Distributed() {
+		opts = append(opts, lunch.IncludeRemote)
+	}
+	roller.OnBoostCreated(h.onBoostCreated, opts...)
+	roller.OnVetoCreated(h.onVetoCreated, opts...)
+	roller.OnPollUpdated(h.onPollUpdated, opts...)
+	roller.OnPlaceCreated(h.onPlaceCreated, opts...)
+	roller.OnPlaceDeleted(h.onPlaceDeleted, opts...)
+	roller.OnPlaceRestored(h.onPlaceRestored, opts...)
+	roller.OnPlaceUpdated(h.onPlaceUpdated, opts...)
+	roller.OnRollCreated(h.onRollCreated, opts...)
+	roller.OnRollReverted(h.onRollReverted, opts...)
+	roller.OnBoostReverted(h.onBoostReverted, opts...)
+	roller.OnRoomCreated(h.onRoomCreated, opts...)
+	roller.OnRoomUpdated(h.onRoomUpdated, opts...)
+	return r, nil
 }
 
 func (h *handler) onRoomUpdated(ctx context.Context, room *lunch.Room) error {
-	return h.broadcast(ws.OpText, &response{Rooms: []*lunch.Room{room}})
+	return h.broadcast(ctx, &response{Rooms: []*lunch.Room{room}}, roomMembersOrSubscribers(room))
 }
 
 func (h *handler) onRoomCreated(ctx context.Context, room *lunch.Room) error {
-	return h.broadcast(ws.OpText, &response{Rooms: []*lunch.Room{room}})
+	return h.broadcast(ctx, &response{Rooms: []*lunch.Room{room}}, roomMembersOrSubscribers(room))
 }
 
 func (h *handler) onBoostCreated(ctx context.Context, boost *lunch.Boost) error {
-	places, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	places, err := h.roller.ListPlaces(ctx, boost.RoomID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to list chances: %s", err)
 	}
-	return h.broadcast(ws.OpText, &response{Places: places, Boosts: []*lunch.Boost{boost}})
+	if err := h.broadcast(ctx, &response{Places: places, Boosts: []*lunch.Boost{boost}}, roomSubscribers(boost.RoomID)); err != nil {
+		return err
+	}
+	return h.pushQuotas(ctx, boost.RoomID)
+}
+
+func (h *handler) onVetoCreated(ctx context.Context, veto *lunch.Veto) error {
+	places, err := h.roller.ListPlaces(ctx, veto.RoomID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	if err := h.broadcast(ctx, &response{Places: places, Vetoes: []*lunch.Veto{veto}}, roomSubscribers(veto.RoomID)); err != nil {
+		return err
+	}
+	return h.pushQuotas(ctx, veto.RoomID)
+}
+
+func (h *handler) onPollUpdated(ctx context.Context, poll *lunch.Poll) error {
+	return h.broadcast(ctx, &response{Polls: []*lunch.Poll{poll}}, roomSubscribers(poll.RoomID))
 }
 
 func (h *handler) onPlaceCreated(ctx context.Context, place *lunch.Place) error {
-	places, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	places, err := h.roller.ListPlaces(ctx, place.RoomID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	return h.broadcast(ctx, &response{Places: places}, roomSubscribers(place.RoomID))
+}
+
+func (h *handler) onPlaceDeleted(ctx context.Context, place *lunch.Place) error {
+	places, err := h.roller.ListPlaces(ctx, place.RoomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	return h.broadcast(ctx, &response{Places: places, DeletedPlaces: []*lunch.Place{place}}, roomSubscribers(place.RoomID))
+}
+
+func (h *handler) onPlaceRestored(ctx context.Context, place *lunch.Place) error {
+	places, err := h.roller.ListPlaces(ctx, place.RoomID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to list chances: %s", err)
 	}
-	return h.broadcast(ws.OpText, &response{Places: places})
+	return h.broadcast(ctx, &response{Places: places}, roomSubscribers(place.RoomID))
+}
+
+func (h *handler) onPlaceUpdated(ctx context.Context, place *lunch.Place) error {
+	places, err := h.roller.ListPlaces(ctx, place.RoomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	return h.broadcast(ctx, &response{Places: places}, roomSubscribers(place.RoomID))
 }
 
 func (h *handler) onRollCreated(ctx context.Context, roll *lunch.Roll) error {
-	pp, err := h.roller.ListPlaces(ctx, roomID, time.Now())
+	pp, err := h.roller.ListPlaces(ctx, roll.RoomID, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to list chances: %s", err)
 	}
-	return h.broadcast(ws.OpText, &response{Places: pp, Rolls: []*lunch.Roll{roll}})
+	if err := h.broadcast(ctx, &response{Places: pp, Rolls: []*lunch.Roll{roll}}, roomSubscribers(roll.RoomID)); err != nil {
+		return err
+	}
+	return h.pushQuotas(ctx, roll.RoomID)
+}
+
+func (h *handler) onRollReverted(ctx context.Context, roll *lunch.Roll) error {
+	pp, err := h.roller.ListPlaces(ctx, roll.RoomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	if err := h.broadcast(ctx, &response{Places: pp, RevertedRolls: []*lunch.Roll{roll}}, roomSubscribers(roll.RoomID)); err != nil {
+		return err
+	}
+	return h.pushQuotas(ctx, roll.RoomID)
+}
+
+func (h *handler) onBoostReverted(ctx context.Context, boost *lunch.Boost) error {
+	pp, err := h.roller.ListPlaces(ctx, boost.RoomID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return fmt.Errorf("failed to list chances: %s", err)
+	}
+	if err := h.broadcast(ctx, &response{Places: pp, RevertedBoosts: []*lunch.Boost{boost}}, roomSubscribers(boost.RoomID)); err != nil {
+		return err
+	}
+	return h.pushQuotas(ctx, boost.RoomID)
+}
+
+// pushQuotas makes every instance write up to date quotas to its connections subscribed to the room.
+func (h *handler) pushQuotas(ctx context.Context, roomID rooms.ID) error {
+	return h.publish(ctx, &message{
+		RoomID: roomID,
+		Quotas: true,
+	})
+}
+
+// writeQuotas writes up to date quota to every connection subscribed to the room. Quotas are personal, so
+// each connection gets its own.
+func (h *handler) writeQuotas(ctx context.Context, roomID rooms.ID) {
+	now := time.Now()
+	for _, conn := range h.connections(func(conn *connection) bool { return conn.IsSubscribed(roomID) }) {
+		quota, err := h.roller.Quota(users.NewContext(ctx, conn.user), roomID, now)
+		if err != nil {
+			log.Printf("[ERROR] failed to get quota: %s", err)
+			continue
+		}
+		if err := conn.Write(ws.OpText, &response{Quota: quota}); err != nil {
+			log.Printf("[ERROR] failed to write message: %s", err)
+		}
+	}
+}
+
+// connections returns open connections that match the filter. Connections are written to after the lock is
+// released, so that a slow one doesn't block registering new ones.
+func (h *handler) connections(filter func(*connection) bool) []*connection {
+	h.openConnectionsGuard.RLock()
+	defer h.openConnectionsGuard.RUnlock()
+
+	result := []*connection{}
+	for _, conn := range h.openConnections {
+		if filter(conn) {
+			result = append(result, conn)
+		}
+	}
+	return result
 }
 
-func (h *handler) registerConnection(conn io.ReadWriter) func() {
+func (h *handler) registerConnection(conn *connection) func() {
 	id := uuid.NewString()
 
 	h.openConnectionsGuard.Lock()
@@ -93,33 +226,19 @@ func (h *handler) registerConnection(conn io.ReadWriter) func() {
 	}
 }
 
-func (h *handler) initConnection(ctx context.Context, conn io.ReadWriter) error {
-	places, err := h.roller.ListPlaces(ctx, roomID, time.Now())
-	if err != nil {
-		return fmt.Errorf("failed to list chances: %s", err)
-	}
-	boosts, err := h.roller.ListBoosts(ctx, roomID)
-	if err != nil {
-		return fmt.Errorf("failed to list boosts: %s", err)
-	}
-	rolls, err := h.roller.ListRolls(ctx, roomID)
-	if err != nil {
-		return fmt.Errorf("failed to list rolls: %s", err)
-	}
+func (h *handler) initConnection(ctx context.Context, conn *connection) error {
 	rooms, err := h.roller.ListRooms(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list rooms: %s", err)
 	}
-	return writeResponse(conn, ws.OpText, &response{
-		Places: places,
-		Boosts: boosts,
-		Rolls:  rolls,
-		Rooms:  rooms,
+	return conn.Write(ws.OpText, &response{
+		Rooms: rooms,
 	})
 }
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if _, ok := users.FromContext(r.Context()); !ok {
+	user, ok := users.FromContext(r.Context())
+	if !ok {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -128,10 +247,12 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	defer h.registerConnection(conn)()
 	defer conn.Close()
 
-	if err := h.initConnection(r.Context(), conn); err != nil {
+	connection := newConnection(conn, user)
+	defer h.registerConnection(connection)()
+
+	if err := h.initConnection(r.Context(), connection); err != nil {
 		log.Printf("failed to init connection: %s", err)
 		return
 	}
@@ -150,69 +271,273 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err := json.Unmarshal(msg, req); err != nil {
 			log.Printf("[ERROR] failed to unmarshal websocket message: %s", err)
 
-			if err := writeResponse(conn, op, &response{Error: "failed to unmarshal request"}); err != nil {
+			if err := connection.Write(op, &response{Error: "failed to unmarshal request"}); err != nil {
 				log.Printf("[ERROR] failed to write message: %s", err)
 				return
 			}
 			return
 		}
 
-		resp, err := h.handle(r.Context(), req)
+		resp, err := h.handle(r.Context(), connection, req)
 		if err != nil {
 			log.Printf("[ERROR] failed to handle websocket message: %s", err)
 
-			if err := writeResponse(conn, op, &response{ID: req.ID, Error: "internal error"}); err != nil {
+			if err := connection.Write(op, &response{ID: req.ID, Error: "internal error"}); err != nil {
 				log.Printf("[ERROR] failed to write message: %s", err)
 				return
 			}
 			return
 		}
 
-		if err := writeResponse(conn, op, resp); err != nil {
+		if err := connection.Write(op, resp); err != nil {
 			log.Printf("[ERROR] failed to write message: %s", err)
 			return
 		}
 	}
 }
 
-func (h *handler) handle(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handle(ctx context.Context, conn *connection, req *request) (*response, error) {
 	switch req.Method {
 	case methodRoomsList:
 		return h.handleRoomsList(ctx, req)
 	case methodRoomsCreate:
 		return h.handleRoomsCreate(ctx, req)
+	case methodRoomsJoin:
+		return h.handleRoomsJoin(ctx, req)
+	case methodRoomsLeave:
+		return h.handleRoomsLeave(ctx, conn, req)
+	case methodRoomsSubscribe:
+		return h.handleRoomsSubscribe(ctx, conn, req)
+	case methodRoomsUnsubscribe:
+		return h.handleRoomsUnsubscribe(ctx, conn, req)
+
+	case methodSettingsGet:
+		return h.handleSettingsGet(ctx, conn, req)
+	case methodSettingsUpdate:
+		return h.handleSettingsUpdate(ctx, conn, req)
+
+	case methodQuotaGet:
+		return h.handleQuotaGet(ctx, conn, req)
+
+	case methodScheduleGet:
+		return h.handleScheduleGet(ctx, conn, req)
+	case methodScheduleUpdate:
+		return h.handleScheduleUpdate(ctx, conn, req)
+
+	case methodNotificationsGet:
+		return h.handleNotificationsGet(ctx, conn, req)
+	case methodNotificationsUpdate:
+		return h.handleNotificationsUpdate(ctx, conn, req)
+
+	case methodWebhooksList:
+		return h.handleWebhooksList(ctx, conn, req)
+	case methodWebhooksCreate:
+		return h.handleWebhooksCreate(ctx, conn, req)
+	case methodWebhooksDelete:
+		return h.handleWebhooksDelete(ctx, conn, req)
+	case methodWebhooksDeliveries:
+		return h.handleWebhooksDeliveries(ctx, conn, req)
 
 	case methodPlacesList:
-		return h.handlePlacesList(ctx, req)
+		return h.handlePlacesList(ctx, conn, req)
 	case methodPlacesCreate:
-		return h.handlePlacesCreate(ctx, req)
+		return h.handlePlacesCreate(ctx, conn, req)
+	case methodPlacesDelete:
+		return h.handlePlacesDelete(ctx, conn, req)
+	case methodPlacesRestore:
+		return h.handlePlacesRestore(ctx, conn, req)
+	case methodPlacesUpdate:
+		return h.handlePlacesUpdate(ctx, conn, req)
 
 	case methodBoostsCreate:
-		return h.handleBoostsCreate(ctx, req)
+		return h.handleBoostsCreate(ctx, conn, req)
 	case methodBoostsList:
-		return h.handleBoostsList(ctx, req)
+		return h.handleBoostsList(ctx, conn, req)
+
+	case methodVetoesCreate:
+		return h.handleVetoesCreate(ctx, conn, req)
+	case methodVetoesList:
+		return h.handleVetoesList(ctx, conn, req)
+
+	case methodPollsOpen:
+		return h.handlePollsOpen(ctx, conn, req)
+	case methodPollsGet:
+		return h.handlePollsGet(ctx, conn, req)
+	case methodPollsVote:
+		return h.handlePollsVote(ctx, conn, req)
+	case methodPollsClose:
+		return h.handlePollsClose(ctx, conn, req)
 
 	case methodRollsCreate:
-		return h.handleRollsCreate(ctx, req)
+		return h.handleRollsCreate(ctx, conn, req)
 	case methodRollsList:
-		return h.handleRollsList(ctx, req)
+		return h.handleRollsList(ctx, conn, req)
 	default:
 		return &response{ID: req.ID, Error: fmt.Sprintf("unknown method '%s'", req.Method)}, nil
 	}
 }
 
-func (h *handler) handlePlacesCreate(ctx context.Context, req *request) (*response, error) {
+// subscribedRoomID returns room id from the request parameters. If the parameter is missing, or the
+// connection is not subscribed to the room, an error response is returned instead.
+func subscribedRoomID(conn *connection, req *request) (rooms.ID, *response) {
+	roomID, ok := req.Params["roomId"]
+	if !ok {
+		return "", &response{ID: req.ID, Error: "'roomId' parameter must be set"}
+	}
+	if !conn.IsSubscribed(rooms.ID(roomID)) {
+		return "", &response{ID: req.ID, Error: "not subscribed to the room"}
+	}
+	return rooms.ID(roomID), nil
+}
+
+func (h *handler) handlePlacesCreate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	name, ok := req.Params["name"]
 	if !ok {
 		return &response{ID: req.ID, Error: "'name' parameter must be set"}, nil
 	}
-	if err := h.roller.CreatePlace(ctx, roomID, name); err != nil {
+	err := h.roller.CreatePlace(ctx, roomID, name)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrAlreadyExists):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	default:
 		return nil, fmt.Errorf("failed to create place: %s", err)
 	}
-	return &response{ID: req.ID}, nil
 }
 
-func (h *handler) handleBoostsCreate(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handlePlacesDelete(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	placeID, ok := req.Params["placeId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
+	}
+
+	err := h.roller.DeletePlace(ctx, roomID, places.ID(placeID))
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "place not found"}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only place creator or room owner can delete it"}, nil
+	default:
+		return nil, fmt.Errorf("failed to delete place: %s", err)
+	}
+}
+
+func (h *handler) handlePlacesRestore(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	placeID, ok := req.Params["placeId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
+	}
+
+	err := h.roller.RestorePlace(ctx, roomID, places.ID(placeID))
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "place not found"}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only place creator or room owner can restore it"}, nil
+	case errors.Is(err, lunch.ErrAlreadyExists):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	default:
+		return nil, fmt.Errorf("failed to restore place: %s", err)
+	}
+}
+
+// handlePlacesUpdate updates only the place fields that are present in the request parameters.
+// Tags are comma separated.
+func (h *handler) handlePlacesUpdate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	placeID, ok := req.Params["placeId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
+	}
+
+	place, err := h.roller.GetPlace(ctx, roomID, places.ID(placeID))
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "place not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to get place: %s", err)
+	}
+
+	name := place.Name
+	if value, ok := req.Params["name"]; ok {
+		name = value
+	}
+	metadata := place.Metadata
+	if value, ok := req.Params["address"]; ok {
+		metadata.Address = value
+	}
+	if value, ok := req.Params["url"]; ok {
+		metadata.URL = value
+	}
+	if value, ok := req.Params["tags"]; ok {
+		metadata.Tags = nil
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				metadata.Tags = append(metadata.Tags, tag)
+			}
+		}
+	}
+	if value, ok := req.Params["priceLevel"]; ok {
+		priceLevel, err := strconv.Atoi(value)
+		if err != nil {
+			return &response{ID: req.ID, Error: "'priceLevel' parameter must be a number"}, nil
+		}
+		metadata.PriceLevel = priceLevel
+	}
+	if value, ok := req.Params["vegetarian"]; ok {
+		vegetarian, err := strconv.ParseBool(value)
+		if err != nil {
+			return &response{ID: req.ID, Error: "'vegetarian' parameter must be a boolean"}, nil
+		}
+		metadata.Vegetarian = vegetarian
+	}
+	if value, ok := req.Params["vegan"]; ok {
+		vegan, err := strconv.ParseBool(value)
+		if err != nil {
+			return &response{ID: req.ID, Error: "'vegan' parameter must be a boolean"}, nil
+		}
+		metadata.Vegan = vegan
+	}
+
+	err = h.roller.UpdatePlace(ctx, roomID, place.ID, name, metadata)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrAlreadyExists):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only place creator or room owner can update it"}, nil
+	default:
+		return nil, fmt.Errorf("failed to update place: %s", err)
+	}
+}
+
+func (h *handler) handleBoostsCreate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	placeID, ok := req.Params["placeId"]
 	if !ok {
 		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
@@ -224,12 +549,18 @@ func (h *handler) handleBoostsCreate(ctx context.Context, req *request) (*respon
 		return &response{ID: req.ID}, nil
 	case errors.Is(err, lunch.ErrNoPoints):
 		return &response{ID: req.ID, Error: "no points left"}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "place not found"}, nil
 	default:
 		return nil, fmt.Errorf("failed to boost: %s", err)
 	}
 }
 
-func (h *handler) handleBoostsList(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handleBoostsList(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	boosts, err := h.roller.ListBoosts(ctx, roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list rolls: %s", err)
@@ -237,7 +568,135 @@ func (h *handler) handleBoostsList(ctx context.Context, req *request) (*response
 	return &response{ID: req.ID, Boosts: boosts}, nil
 }
 
-func (h *handler) handleRollsList(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handleVetoesCreate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	placeID, ok := req.Params["placeId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
+	}
+
+	err := h.roller.CreateVeto(ctx, roomID, places.ID(placeID), time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNoPoints):
+		return &response{ID: req.ID, Error: "no points left"}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "place not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to veto: %s", err)
+	}
+}
+
+func (h *handler) handleVetoesList(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	vetoes, err := h.roller.ListVetoes(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vetoes: %s", err)
+	}
+	return &response{ID: req.ID, Vetoes: vetoes}, nil
+}
+
+func (h *handler) handlePollsOpen(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	method, ok := req.Params["method"]
+	if !ok {
+		method = string(polls.MethodApproval)
+	}
+	closesAtParam, ok := req.Params["closesAt"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'closesAt' parameter must be set"}, nil
+	}
+	closesAt, err := time.Parse(time.RFC3339, closesAtParam)
+	if err != nil {
+		return &response{ID: req.ID, Error: "'closesAt' must be an RFC 3339 time"}, nil
+	}
+
+	poll, err := h.roller.OpenPoll(ctx, roomID, polls.Method(method), closesAt, time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Polls: []*lunch.Poll{poll}}, nil
+	case errors.Is(err, lunch.ErrInvalid):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	default:
+		return nil, fmt.Errorf("failed to open poll: %s", err)
+	}
+}
+
+func (h *handler) handlePollsGet(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	poll, err := h.roller.GetPoll(ctx, roomID)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Polls: []*lunch.Poll{poll}}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID}, nil
+	default:
+		return nil, fmt.Errorf("failed to get poll: %s", err)
+	}
+}
+
+func (h *handler) handlePollsVote(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	placeID, ok := req.Params["placeId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'placeId' parameter must be set"}, nil
+	}
+
+	poll, err := h.roller.Vote(ctx, roomID, places.ID(placeID), time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Polls: []*lunch.Poll{poll}}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "poll is closed"}, nil
+	default:
+		return nil, fmt.Errorf("failed to vote: %s", err)
+	}
+}
+
+func (h *handler) handlePollsClose(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	roll, err := h.roller.ClosePoll(ctx, roomID, time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Rolls: []*lunch.Roll{roll}}, nil
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return &response{ID: req.ID, Error: "nobody voted"}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "no open poll"}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only poll creator can close it early"}, nil
+	default:
+		return nil, fmt.Errorf("failed to close poll: %s", err)
+	}
+}
+
+func (h *handler) handleRollsList(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	rolls, err := h.roller.ListRolls(ctx, roomID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list rolls: %s", err)
@@ -266,7 +725,358 @@ func (h *handler) handleRoomsCreate(ctx context.Context, req *request) (*respons
 	return &response{ID: req.ID}, nil
 }
 
-func (h *handler) handlePlacesList(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handleRoomsJoin(ctx context.Context, req *request) (*response, error) {
+	roomID, ok := req.Params["roomId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'roomId' parameter must be set"}, nil
+	}
+
+	err := h.roller.JoinRoom(ctx, rooms.ID(roomID))
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to join room: %s", err)
+	}
+}
+
+func (h *handler) handleRoomsLeave(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, ok := req.Params["roomId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'roomId' parameter must be set"}, nil
+	}
+
+	err := h.roller.LeaveRoom(ctx, rooms.ID(roomID))
+	switch {
+	case err == nil:
+		conn.Unsubscribe(rooms.ID(roomID))
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to leave room: %s", err)
+	}
+}
+
+func (h *handler) handleRoomsSubscribe(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, ok := req.Params["roomId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'roomId' parameter must be set"}, nil
+	}
+
+	room, err := h.roller.GetRoom(ctx, rooms.ID(roomID))
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to get room: %s", err)
+	}
+
+	if !room.MemberIDs[conn.user.ID] {
+		return &response{ID: req.ID, Error: "not a member of the room"}, nil
+	}
+
+	conn.Subscribe(room.ID)
+
+	places, err := h.roller.ListPlaces(ctx, room.ID, time.Now())
+	if err != nil && !errors.Is(err, lunch.ErrNoPlaces) {
+		return nil, fmt.Errorf("failed to list chances: %s", err)
+	}
+	boosts, err := h.roller.ListBoosts(ctx, room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list boosts: %s", err)
+	}
+	rolls, err := h.roller.ListRolls(ctx, room.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rolls: %s", err)
+	}
+
+	return &response{
+		ID:     req.ID,
+		Places: places,
+		Boosts: boosts,
+		Rolls:  rolls,
+		Rooms:  []*lunch.Room{room},
+	}, nil
+}
+
+func (h *handler) handleSettingsGet(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	room, err := h.roller.GetRoom(ctx, roomID)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Settings: &room.Settings}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to get room: %s", err)
+	}
+}
+
+// handleSettingsUpdate updates only the settings that are present in the request parameters.
+func (h *handler) handleSettingsUpdate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	room, err := h.roller.GetRoom(ctx, roomID)
+	switch {
+	case err == nil:
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to get room: %s", err)
+	}
+
+	settings := room.Settings
+	for key, value := range req.Params {
+		if key == "roomId" {
+			continue
+		}
+		if err := settings.Set(key, value); err != nil {
+			return &response{ID: req.ID, Error: err.Error()}, nil
+		}
+	}
+
+	err = h.roller.UpdateRoomSettings(ctx, roomID, settings)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Settings: &settings}, nil
+	case errors.Is(err, lunch.ErrInvalid):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can change settings"}, nil
+	default:
+		return nil, fmt.Errorf("failed to update settings: %s", err)
+	}
+}
+
+func (h *handler) handleQuotaGet(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+	quota, err := h.roller.Quota(ctx, roomID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota: %s", err)
+	}
+	return &response{ID: req.ID, Quota: quota}, nil
+}
+
+func (h *handler) handleScheduleGet(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	schedule, err := h.roller.GetSchedule(ctx, roomID)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Schedule: schedule}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Schedule: &schedules.Schedule{RoomID: roomID}}, nil
+	default:
+		return nil, fmt.Errorf("failed to get schedule: %s", err)
+	}
+}
+
+// handleScheduleUpdate turns the schedule off if weekdays are empty.
+func (h *handler) handleScheduleUpdate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	hour, minute, weekdays := 0, 0, []time.Weekday{}
+	if req.Params["weekdays"] != "" {
+		var err error
+		if hour, minute, err = schedules.ParseTime(req.Params["at"]); err != nil {
+			return &response{ID: req.ID, Error: err.Error()}, nil
+		}
+		if weekdays, err = schedules.ParseWeekdays(req.Params["weekdays"]); err != nil {
+			return &response{ID: req.ID, Error: err.Error()}, nil
+		}
+	}
+
+	schedule, err := h.roller.UpdateSchedule(ctx, roomID, hour, minute, weekdays, time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Schedule: schedule}, nil
+	case errors.Is(err, lunch.ErrInvalid):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can change the schedule"}, nil
+	default:
+		return nil, fmt.Errorf("failed to update schedule: %s", err)
+	}
+}
+
+func (h *handler) handleNotificationsGet(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	preferences, err := h.roller.GetNotifications(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %s", err)
+	}
+	return &response{ID: req.ID, Notifications: preferences}, nil
+}
+
+// handleNotificationsUpdate updates only the preferences that are present in the request parameters.
+// Empty quietFrom and quietTo turn quiet hours off.
+func (h *handler) handleNotificationsUpdate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	preferences, err := h.roller.GetNotifications(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %s", err)
+	}
+
+	level := preferences.Level
+	if value, ok := req.Params["level"]; ok {
+		level = notifications.Level(value)
+	}
+	quietFrom, quietTo := preferences.QuietFrom, preferences.QuietTo
+	if value, ok := req.Params["quietFrom"]; ok {
+		quietFrom = value
+	}
+	if value, ok := req.Params["quietTo"]; ok {
+		quietTo = value
+	}
+
+	preferences, err = h.roller.UpdateNotifications(ctx, roomID, level, quietFrom, quietTo, time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Notifications: preferences}, nil
+	case errors.Is(err, lunch.ErrInvalid):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room members can be notified"}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "room not found"}, nil
+	default:
+		return nil, fmt.Errorf("failed to update notification preferences: %s", err)
+	}
+}
+
+func (h *handler) handleWebhooksList(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	ww, err := h.roller.ListWebhooks(ctx, roomID)
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Webhooks: ww}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can manage webhooks"}, nil
+	default:
+		return nil, fmt.Errorf("failed to list webhooks: %s", err)
+	}
+}
+
+// handleWebhooksCreate expects a comma separated list of event types in the 'events' parameter.
+func (h *handler) handleWebhooksCreate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	url, ok := req.Params["url"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'url' parameter must be set"}, nil
+	}
+
+	eventTypes := []string{}
+	for _, eventType := range strings.Split(req.Params["events"], ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	webhook, err := h.roller.CreateWebhook(ctx, roomID, url, eventTypes, time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Webhooks: []*webhooks.Webhook{webhook}}, nil
+	case errors.Is(err, lunch.ErrInvalid):
+		return &response{ID: req.ID, Error: err.Error()}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can manage webhooks"}, nil
+	default:
+		return nil, fmt.Errorf("failed to create webhook: %s", err)
+	}
+}
+
+func (h *handler) handleWebhooksDelete(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	webhookID, ok := req.Params["webhookId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'webhookId' parameter must be set"}, nil
+	}
+
+	err := h.roller.DeleteWebhook(ctx, roomID, webhooks.ID(webhookID), time.Now())
+	switch {
+	case err == nil:
+		return &response{ID: req.ID}, nil
+	case errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: "webhook not found"}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can manage webhooks"}, nil
+	default:
+		return nil, fmt.Errorf("failed to delete webhook: %s", err)
+	}
+}
+
+// handleWebhooksDeliveries returns deliveries to all webhooks in the room, unless 'webhookId' is set.
+func (h *handler) handleWebhooksDeliveries(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	dd, err := h.roller.ListDeliveries(ctx, roomID, webhooks.ID(req.Params["webhookId"]))
+	switch {
+	case err == nil:
+		return &response{ID: req.ID, Deliveries: dd}, nil
+	case errors.Is(err, lunch.ErrNotAllowed):
+		return &response{ID: req.ID, Error: "only room owner can manage webhooks"}, nil
+	default:
+		return nil, fmt.Errorf("failed to list deliveries: %s", err)
+	}
+}
+
+func (h *handler) handleRoomsUnsubscribe(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, ok := req.Params["roomId"]
+	if !ok {
+		return &response{ID: req.ID, Error: "'roomId' parameter must be set"}, nil
+	}
+	conn.Unsubscribe(rooms.ID(roomID))
+	return &response{ID: req.ID}, nil
+}
+
+func (h *handler) handlePlacesList(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	pp, err := h.roller.ListPlaces(ctx, roomID, time.Now())
 	switch {
 	case err == nil:
@@ -278,28 +1088,90 @@ func (h *handler) handlePlacesList(ctx context.Context, req *request) (*response
 	}
 }
 
-func (h *handler) handleRollsCreate(ctx context.Context, req *request) (*response, error) {
+func (h *handler) handleRollsCreate(ctx context.Context, conn *connection, req *request) (*response, error) {
+	roomID, errResponse := subscribedRoomID(conn, req)
+	if errResponse != nil {
+		return errResponse, nil
+	}
 	roll, err := h.roller.CreateRoll(ctx, roomID, time.Now())
 	switch {
 	case err == nil:
 		return &response{ID: req.ID, Rolls: []*lunch.Roll{roll}}, nil
 	case errors.Is(err, lunch.ErrNoPoints):
 		return &response{ID: req.ID, Error: "no points left"}, nil
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return &response{ID: req.ID, Error: "no places to choose from"}, nil
+	case errors.Is(err, lunch.ErrInvalid), errors.Is(err, lunch.ErrNotAllowed), errors.Is(err, lunch.ErrNotFound):
+		return &response{ID: req.ID, Error: err.Error()}, nil
 	default:
 		return nil, fmt.Errorf("failed to roll: %s", err)
 	}
 }
 
-func (h *handler) broadcast(op ws.OpCode, resp *response) error {
-	h.openConnectionsGuard.RLock()
-	defer h.openConnectionsGuard.RUnlock()
+// message is a broadcast, that every instance writes to its own connections.
+type message struct {
+	RoomID rooms.ID `json:"roomId"`
+	// MemberIDs are room members, their connections match even if they are not subscribed to the room.
+	MemberIDs map[users.ID]bool `json:"memberIds,omitempty"`
+	// Quotas is set if matching connections get their own quotas instead of the response.
+	Quotas   bool            `json:"quotas,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
 
-	for _, conn := range h.openConnections {
-		if err := writeResponse(conn, op, resp); err != nil {
+// matches returns true if the message should be written to the connection.
+func (m *message) matches(conn *connection) bool {
+	return m.MemberIDs[conn.user.ID] || conn.IsSubscribed(m.RoomID)
+}
+
+// roomSubscribers matches connections subscribed to the room.
+func roomSubscribers(roomID rooms.ID) *message {
+	return &message{RoomID: roomID}
+}
+
+// roomMembersOrSubscribers matches connections of the room members, and connections subscribed to the room.
+func roomMembersOrSubscribers(room *lunch.Room) *message {
+	return &message{RoomID: room.ID, MemberIDs: room.MemberIDs}
+}
+
+// broadcast writes the response to every open connection that matches the message, on every instance.
+func (h *handler) broadcast(ctx context.Context, resp *response, msg *message) error {
+	bytes, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %s", err)
+	}
+	msg.Response = bytes
+	return h.publish(ctx, msg)
+}
+
+func (h *handler) publish(ctx context.Context, msg *message) error {
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %s", err)
+	}
+	if err := h.pubsub.Publish(ctx, broadcastChannel, bytes); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
+
+// deliver writes a message published by any instance to matching connections of this instance.
+func (h *handler) deliver(bytes []byte) {
+	msg := &message{}
+	if err := json.Unmarshal(bytes, msg); err != nil {
+		log.Printf("[ERROR] failed to unmarshal broadcast: %s", err)
+		return
+	}
+
+	if msg.Quotas {
+		h.writeQuotas(context.Background(), msg.RoomID)
+		return
+	}
+
+	for _, conn := range h.connections(msg.matches) {
+		if err := conn.WriteRaw(ws.OpText, msg.Response); err != nil {
 			log.Printf("[ERROR] failed to write message: %s", err)
 		}
 	}
-	return nil
 }
 
 func writeResponse(w io.Writer, op ws.OpCode, resp *response) error {
@@ -2,20 +2,54 @@ package websocket
 
 import (
 	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/notifications"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/schedules"
+	"lunch/pkg/lunch/webhooks"
 )
 
 type method string
 
 const (
-	methodUndefined    method = ""
-	methodPlacesList   method = "places/list"
-	methodPlacesCreate method = "places/create"
-	methodRollsList    method = "rolls/list"
-	methodRollsCreate  method = "rolls/create"
-	methodBoostsCreate method = "boosts/create"
-	methodBoostsList   method = "boosts/list"
-	methodRoomsList    method = "rooms/list"
-	methodRoomsCreate  method = "rooms/create"
+	methodUndefined     method = ""
+	methodPlacesList    method = "places/list"
+	methodPlacesCreate  method = "places/create"
+	methodPlacesDelete  method = "places/delete"
+	methodPlacesRestore method = "places/restore"
+	methodPlacesUpdate  method = "places/update"
+	methodRollsList     method = "rolls/list"
+	methodRollsCreate   method = "rolls/create"
+	methodBoostsCreate  method = "boosts/create"
+	methodBoostsList    method = "boosts/list"
+	methodVetoesCreate  method = "vetoes/create"
+	methodVetoesList    method = "vetoes/list"
+	methodPollsOpen     method = "polls/open"
+	methodPollsGet      method = "polls/get"
+	methodPollsVote     method = "polls/vote"
+	methodPollsClose    method = "polls/close"
+	methodRoomsList     method = "rooms/list"
+	methodRoomsCreate   method = "rooms/create"
+	methodRoomsJoin     method = "rooms/join"
+	methodRoomsLeave    method = "rooms/leave"
+
+	methodSettingsGet    method = "settings/get"
+	methodSettingsUpdate method = "settings/update"
+
+	methodQuotaGet method = "quota/get"
+
+	methodScheduleGet    method = "schedule/get"
+	methodScheduleUpdate method = "schedule/update"
+
+	methodNotificationsGet    method = "notifications/get"
+	methodNotificationsUpdate method = "notifications/update"
+
+	methodWebhooksList       method = "webhooks/list"
+	methodWebhooksCreate     method = "webhooks/create"
+	methodWebhooksDelete     method = "webhooks/delete"
+	methodWebhooksDeliveries method = "webhooks/deliveries"
+
+	methodRoomsSubscribe   method = "rooms/subscribe"
+	methodRoomsUnsubscribe method = "rooms/unsubscribe"
 )
 
 type request struct {
@@ -27,8 +61,25 @@ type request struct {
 type response struct {
 	ID     string         `json:"id,omitempty"`
 	Places []*lunch.Place `json:"places,omitempty"`
-	Rolls  []*lunch.Roll  `json:"rolls,omitempty"`
-	Boosts []*lunch.Boost `json:"boosts,omitempty"`
-	Rooms  []*lunch.Room  `json:"rooms,omitempty"`
-	Error  string         `json:"error,omitempty"`
+	// DeletedPlaces are places that were removed from the rotation.
+	DeletedPlaces []*lunch.Place `json:"deletedPlaces,omitempty"`
+	Rolls         []*lunch.Roll  `json:"rolls,omitempty"`
+	// RevertedRolls are rolls that were undone.
+	RevertedRolls []*lunch.Roll  `json:"revertedRolls,omitempty"`
+	Boosts        []*lunch.Boost `json:"boosts,omitempty"`
+	// RevertedBoosts are boosts that were undone.
+	RevertedBoosts []*lunch.Boost  `json:"revertedBoosts,omitempty"`
+	Vetoes         []*lunch.Veto   `json:"vetoes,omitempty"`
+	Polls          []*lunch.Poll   `json:"polls,omitempty"`
+	Rooms          []*lunch.Room   `json:"rooms,omitempty"`
+	Settings       *rooms.Settings `json:"settings,omitempty"`
+	Quota          *lunch.Quota    `json:"quota,omitempty"`
+	// Schedule is when the room is rolled automatically.
+	Schedule *schedules.Schedule `json:"schedule,omitempty"`
+	// Notifications are notification preferences of the user in the room.
+	Notifications *notifications.Preferences `json:"notifications,omitempty"`
+	Webhooks      []*webhooks.Webhook        `json:"webhooks,omitempty"`
+	// Deliveries is the webhook delivery log, newest first.
+	Deliveries []*webhooks.Delivery `json:"deliveries,omitempty"`
+	Error      string               `json:"error,omitempty"`
 }
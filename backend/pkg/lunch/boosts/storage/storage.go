@@ -8,12 +8,19 @@ import (
 	"lunch/pkg/lunch/boosts"
 	"lunch/pkg/lunch/events"
 	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
 )
 
 var (
-	boostCreated events.Type = "boosts/created"
+	boostCreated  events.Type = "boosts/created"
+	boostReverted events.Type = "boosts/reverted"
 )
 
+// revert is the payload of the reverted event, it points to the boost by its time.
+type revert struct {
+	Time events.UnixNanoTime `json:"time"`
+}
+
 type Storage struct {
 	eventsStorage events.Storage
 }
@@ -34,18 +41,79 @@ func (s *Storage) Create(ctx context.Context, boost *boosts.Boost) error {
 	})
 }
 
+// Revert marks the boost as reverted, so it's not returned anymore.
+func (s *Storage) Revert(ctx context.Context, boost *boosts.Boost, now time.Time) error {
+	event := &events.Event{
+		UserID:    boost.UserID,
+		PlaceID:   boost.PlaceID,
+		RoomID:    boost.RoomID,
+		Type:      boostReverted,
+		Timestamp: events.UnixNanoTime(now),
+	}
+	if err := event.MarshalPayload(&revert{Time: events.UnixNanoTime(boost.Time)}); err != nil {
+		return err
+	}
+	return s.eventsStorage.Create(ctx, event)
+}
+
 func (s *Storage) Boosts(ctx context.Context, roomID rooms.ID) ([]*boosts.Boost, error) {
-	events, err := s.eventsStorage.ByRoomID(ctx, roomID, boostCreated)
+	events, err := s.eventsStorage.ByRoomID(ctx, roomID, boostCreated, boostReverted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
+
+	type key struct {
+		UserID users.ID
+		Time   int64
+	}
+	reverted := map[key]bool{}
+	for _, event := range events {
+		boost, ok, err := Reverted(event)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			reverted[key{UserID: boost.UserID, Time: boost.Time.UnixNano()}] = true
+		}
+	}
+
 	result := make([]*boosts.Boost, 0, len(events))
 	for _, event := range events {
-		result = append(result, &boosts.Boost{
-			UserID:  event.UserID,
-			PlaceID: event.PlaceID,
-			Time:    time.Time(event.Timestamp),
-		})
+		boost, ok := Created(event)
+		if !ok || reverted[key{UserID: boost.UserID, Time: boost.Time.UnixNano()}] {
+			continue
+		}
+		result = append(result, boost)
 	}
 	return result, nil
 }
+
+// Created returns the boost recorded by the event.
+func Created(event *events.Event) (*boosts.Boost, bool) {
+	if event.Type != boostCreated {
+		return nil, false
+	}
+	return &boosts.Boost{
+		UserID:  event.UserID,
+		PlaceID: event.PlaceID,
+		RoomID:  event.RoomID,
+		Time:    time.Time(event.Timestamp),
+	}, true
+}
+
+// Reverted returns the boost reverted by the event.
+func Reverted(event *events.Event) (*boosts.Boost, bool, error) {
+	if event.Type != boostReverted {
+		return nil, false, nil
+	}
+	payload := &revert{}
+	if err := event.UnmarshalPayload(payload); err != nil {
+		return nil, false, err
+	}
+	return &boosts.Boost{
+		UserID:  event.UserID,
+		PlaceID: event.PlaceID,
+		RoomID:  event.RoomID,
+		Time:    time.Time(payload.Time),
+	}, true, nil
+}
@@ -0,0 +1,76 @@
+package channels
+
+import (
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// ID is an id of a chat channel, for example a Slack channel.
+type ID string
+
+// Platform is the chat app a channel is in.
+type Platform string
+
+const (
+	PlatformSlack Platform = "slack"
+	PlatformTeams Platform = "teams"
+)
+
+// Channel is a chat channel bound to a room.
+type Channel struct {
+	ID       ID        `json:"id"`
+	RoomID   rooms.ID  `json:"roomId"`
+	UserID   users.ID  `json:"userId"`
+	Platform Platform  `json:"platform"`
+	Time     time.Time `json:"time"`
+	// WebhookURL is where messages to the channel are posted, for platforms that can't post with the app token.
+	WebhookURL string `json:"-"`
+}
+
+// New returns a Slack channel.
+func New(id ID, roomID rooms.ID, userID users.ID, now time.Time) *Channel {
+	return &Channel{
+		ID:       id,
+		RoomID:   roomID,
+		UserID:   userID,
+		Platform: PlatformSlack,
+		Time:     now,
+	}
+}
+
+// NewTeams returns a Microsoft Teams channel, posted to with an incoming webhook.
+func NewTeams(id ID, roomID rooms.ID, userID users.ID, webhookURL string, now time.Time) *Channel {
+	return &Channel{
+		ID:         id,
+		RoomID:     roomID,
+		UserID:     userID,
+		Platform:   PlatformTeams,
+		Time:       now,
+		WebhookURL: webhookURL,
+	}
+}
+
+// Message is a message posted to a channel once a day, and updated as the day goes on.
+type Message struct {
+	ChannelID ID       `json:"channelId"`
+	RoomID    rooms.ID `json:"roomId"`
+	UserID    users.ID `json:"userId"`
+	// Day is the local date in the room time zone, like 2021-09-06.
+	Day string `json:"day"`
+	// TS is the id of the message in the chat, for example a Slack message timestamp.
+	TS   string    `json:"ts"`
+	Time time.Time `json:"time"`
+}
+
+func NewMessage(channelID ID, roomID rooms.ID, userID users.ID, day, ts string, now time.Time) *Message {
+	return &Message{
+		ChannelID: channelID,
+		RoomID:    roomID,
+		UserID:    userID,
+		Day:       day,
+		TS:        ts,
+		Time:      now,
+	}
+}
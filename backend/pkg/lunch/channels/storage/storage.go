@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+)
+
+var (
+	ErrNotFound = fmt.Errorf("not found")
+)
+
+const (
+	channelBound  events.Type = "channels/bound"
+	messagePosted events.Type = "channels/message_posted"
+)
+
+// binding is the payload of the channel bound event. Channels bound before platforms were added have no payload,
+// and are Slack channels.
+type binding struct {
+	Platform   channels.Platform `json:"platform,omitempty"`
+	WebhookURL string            `json:"webhookUrl,omitempty"`
+}
+
+// message is the payload of the message posted event.
+type message struct {
+	Day string `json:"day"`
+	TS  string `json:"ts"`
+}
+
+type Storage struct {
+	storage events.Storage
+}
+
+func New(storage events.Storage) *Storage {
+	return &Storage{
+		storage: storage,
+	}
+}
+
+// Bind binds the channel to the room. If the channel was bound to a different room before,
+// it's moved to the new one.
+func (s *Storage) Bind(ctx context.Context, channel *channels.Channel) error {
+	event := &events.Event{
+		UserID:    channel.UserID,
+		RoomID:    channel.RoomID,
+		Timestamp: events.UnixNanoTime(channel.Time),
+		Type:      channelBound,
+		Name:      string(channel.ID),
+	}
+	if err := event.MarshalPayload(&binding{
+		Platform:   channel.Platform,
+		WebhookURL: channel.WebhookURL,
+	}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Channel returns the channel with the room it's currently bound to.
+func (s *Storage) Channel(ctx context.Context, channelID channels.ID) (*channels.Channel, error) {
+	events, err := s.storage.ByType(ctx, channelBound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	bound, err := replay(events)
+	if err != nil {
+		return nil, err
+	}
+	channel, ok := bound[channelID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return channel, nil
+}
+
+// Channels returns all channels currently bound to the room.
+func (s *Storage) Channels(ctx context.Context, roomID rooms.ID) ([]*channels.Channel, error) {
+	events, err := s.storage.ByType(ctx, channelBound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	result := []*channels.Channel{}
+	bound, err := replay(events)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range bound {
+		if channel.RoomID == roomID {
+			result = append(result, channel)
+		}
+	}
+	return result, nil
+}
+
+// SaveMessage remembers the message posted to the channel for the day.
+func (s *Storage) SaveMessage(ctx context.Context, msg *channels.Message) error {
+	event := &events.Event{
+		UserID:    msg.UserID,
+		RoomID:    msg.RoomID,
+		Timestamp: events.UnixNanoTime(msg.Time),
+		Type:      messagePosted,
+		Name:      string(msg.ChannelID),
+	}
+	if err := event.MarshalPayload(&message{Day: msg.Day, TS: msg.TS}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Message returns the latest message posted to the channel for the room on the day.
+func (s *Storage) Message(ctx context.Context, roomID rooms.ID, channelID channels.ID, day string) (*channels.Message, error) {
+	ee, err := s.storage.ByRoomID(ctx, roomID, messagePosted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+
+	var result *channels.Message
+	for _, event := range ee {
+		if channels.ID(event.Name) != channelID {
+			continue
+		}
+		payload := &message{}
+		if err := event.UnmarshalPayload(payload); err != nil {
+			return nil, err
+		}
+		if payload.Day != day {
+			continue
+		}
+		result = channels.NewMessage(channelID, event.RoomID, event.UserID, payload.Day, payload.TS, time.Time(event.Timestamp))
+	}
+	if result == nil {
+		return nil, ErrNotFound
+	}
+	return result, nil
+}
+
+func replay(ee []*events.Event) (map[channels.ID]*channels.Channel, error) {
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+	result := make(map[channels.ID]*channels.Channel)
+	for _, event := range ee {
+		switch event.Type {
+		case channelBound:
+			payload := &binding{}
+			if err := event.UnmarshalPayload(payload); err != nil {
+				return nil, err
+			}
+			if payload.Platform == "" {
+				payload.Platform = channels.PlatformSlack
+			}
+			result[channels.ID(event.Name)] = &channels.Channel{
+				ID:         channels.ID(event.Name),
+				RoomID:     event.RoomID,
+				UserID:     event.UserID,
+				Platform:   payload.Platform,
+				Time:       time.Time(event.Timestamp),
+				WebhookURL: payload.WebhookURL,
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/channels"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+)
+
+func Test_ChannelRebound(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	storage := New(events.NewBoltStorage(bolt))
+
+	now := time.Now()
+	assertNoError(t, storage.Bind(context.Background(), channels.New("C1", rooms.ID("1"), users.ID("1"), now)))
+	assertNoError(t, storage.Bind(context.Background(), channels.New("C1", rooms.ID("2"), users.ID("1"), now.Add(time.Minute))))
+
+	channel, err := storage.Channel(context.Background(), "C1")
+	assertNoError(t, err)
+	assertEqual(t, rooms.ID("2"), channel.RoomID)
+
+	firstRoomChannels, err := storage.Channels(context.Background(), rooms.ID("1"))
+	assertNoError(t, err)
+	assertEqual(t, 0, len(firstRoomChannels))
+
+	secondRoomChannels, err := storage.Channels(context.Background(), rooms.ID("2"))
+	assertNoError(t, err)
+	assertEqual(t, 1, len(secondRoomChannels))
+
+	_, err = storage.Channel(context.Background(), "C2")
+	assertError(t, ErrNotFound, err)
+}
+
+func Test_ChannelPlatform(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	eventsStorage := events.NewBoltStorage(bolt)
+	storage := New(eventsStorage)
+
+	now := time.Now()
+	// channels bound before platforms were added
+	assertNoError(t, eventsStorage.Create(context.Background(), &events.Event{
+		UserID:    users.ID("1"),
+		RoomID:    rooms.ID("1"),
+		Timestamp: events.UnixNanoTime(now),
+		Type:      channelBound,
+		Name:      "C1",
+	}))
+	assertNoError(t, storage.Bind(context.Background(), channels.NewTeams("19:1@thread.tacv2", rooms.ID("1"), users.ID("1"), "https://example.com/webhook", now.Add(time.Second))))
+
+	slackChannel, err := storage.Channel(context.Background(), "C1")
+	assertNoError(t, err)
+	assertEqual(t, channels.PlatformSlack, slackChannel.Platform)
+
+	teamsChannel, err := storage.Channel(context.Background(), "19:1@thread.tacv2")
+	assertNoError(t, err)
+	assertEqual(t, channels.PlatformTeams, teamsChannel.Platform)
+	assertEqual(t, "https://example.com/webhook", teamsChannel.WebhookURL)
+}
+
+func Test_DailyMessage(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	storage := New(events.NewBoltStorage(bolt))
+
+	now := time.Now()
+	assertNoError(t, storage.SaveMessage(context.Background(), channels.NewMessage("C1", rooms.ID("1"), users.ID("1"), "2021-09-06", "1.1", now)))
+	assertNoError(t, storage.SaveMessage(context.Background(), channels.NewMessage("C2", rooms.ID("1"), users.ID("1"), "2021-09-06", "2.1", now.Add(time.Second))))
+	assertNoError(t, storage.SaveMessage(context.Background(), channels.NewMessage("C1", rooms.ID("1"), users.ID("1"), "2021-09-07", "1.2", now.Add(2*time.Second))))
+
+	msg, err := storage.Message(context.Background(), rooms.ID("1"), "C1", "2021-09-06")
+	assertNoError(t, err)
+	assertEqual(t, "1.1", msg.TS)
+
+	msg, err = storage.Message(context.Background(), rooms.ID("1"), "C1", "2021-09-07")
+	assertNoError(t, err)
+	assertEqual(t, "1.2", msg.TS)
+
+	_, err = storage.Message(context.Background(), rooms.ID("2"), "C1", "2021-09-06")
+	assertError(t, ErrNotFound, err)
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	assertError(t, nil, err)
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
+
+func assertError(t *testing.T, expected error, got error) {
+	t.Helper()
+
+	if !errors.Is(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -1,5 +1,7 @@
 package lunch
 
+import "lunch/pkg/lunch/rooms"
+
 type Type uint
 
 const (
@@ -9,6 +11,14 @@ const (
 	TypePlaceCreated
 	TypeRoomCreated
 	TypeRoomUpdated
+	TypePlaceDeleted
+	TypePlaceRestored
+	TypePlaceUpdated
+	TypeRollReverted
+	TypeBoostReverted
+	TypeVetoCreated
+	TypePollUpdated
+	TypeReminderDue
 )
 
 func (t *Type) String() string {
@@ -23,15 +33,59 @@ func (t *Type) String() string {
 		return "boost_created"
 	case TypePlaceCreated:
 		return "place_created"
+	case TypePlaceDeleted:
+		return "place_deleted"
+	case TypePlaceRestored:
+		return "place_restored"
+	case TypePlaceUpdated:
+		return "place_updated"
+	case TypeRollReverted:
+		return "roll_reverted"
+	case TypeBoostReverted:
+		return "boost_reverted"
+	case TypeVetoCreated:
+		return "veto_created"
+	case TypePollUpdated:
+		return "poll_updated"
+	case TypeReminderDue:
+		return "reminder_due"
 	default:
 		return "unknown"
 	}
 }
 
 type event struct {
-	Type  Type
-	Place *Place
-	Roll  *Roll
-	Boost *Boost
-	Room  *Room
+	Type     Type
+	Place    *Place
+	Roll     *Roll
+	Boost    *Boost
+	Veto     *Veto
+	Poll     *Poll
+	Reminder *Reminder
+	Room     *Room
+
+	// remote is set for events stored by another instance.
+	remote bool
+}
+
+// roomID returns the room the event happened in.
+func (e *event) roomID() rooms.ID {
+	switch {
+	case e.Place != nil:
+		return e.Place.RoomID
+	case e.Roll != nil:
+		return e.Roll.RoomID
+	case e.Boost != nil:
+		return e.Boost.RoomID
+	case e.Veto != nil:
+		return e.Veto.RoomID
+	case e.Poll != nil:
+		return e.Poll.RoomID
+	case e.Reminder != nil:
+		return e.Reminder.RoomID
+	case e.Room != nil:
+		return e.Room.ID
+	default:
+		return ""
+	}
 }
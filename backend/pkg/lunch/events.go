@@ -2,21 +2,68 @@ package lunch
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
 	"log"
 	"sync"
+	"time"
+)
+
+const (
+	// queueSize is how many events a subscriber queue can hold, publishers wait for room when it's full.
+	queueSize = 256
+	// enqueueTimeout is how long a publisher waits for room in a full queue before the event is dropped.
+	enqueueTimeout = 5 * time.Second
+	// queuesPerSubscriber is how many rooms a subscriber can handle events of at the same time.
+	queuesPerSubscriber = 4
+	// maxHandleAttempts is how many times a handler is called with the same event, if it keeps failing.
+	maxHandleAttempts = 3
+	retryBackoff      = 100 * time.Millisecond
 )
 
 type handler func(context.Context, *event) error
 
+// SubscribeOption changes which events a subscriber gets.
+type SubscribeOption func(*subscriber)
+
+// IncludeRemote makes the subscriber handle events stored by other server instances too. By default, events are
+// handled only by subscribers of the instance that stored them, so that side effects like posting to Slack
+// happen once.
+func IncludeRemote(s *subscriber) {
+	s.remote = true
+}
+
+// registry is an in-process events bus. Every subscriber has its own queues, events of the same room are
+// always handled by a subscriber in the order they were published.
 type registry struct {
 	handlersGuard *sync.RWMutex
-	handlers      map[Type][]handler
+	handlers      map[Type][]*subscriber
+	closed        bool
+
+	// ctx is passed to handlers, it's canceled if the registry doesn't drain in time on shutdown.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	quit    chan struct{}
+	workers *sync.WaitGroup
+
+	enqueueTimeout time.Duration
+
+	pending *pending
+	metrics *metrics
 }
 
 func newEventsRegistry() *registry {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &registry{
-		handlersGuard: &sync.RWMutex{},
-		handlers:      make(map[Type][]handler),
+		handlersGuard:  &sync.RWMutex{},
+		handlers:       make(map[Type][]*subscriber),
+		ctx:            ctx,
+		cancel:         cancel,
+		quit:           make(chan struct{}),
+		workers:        &sync.WaitGroup{},
+		enqueueTimeout: enqueueTimeout,
+		pending:        newPending(),
+		metrics:        newMetrics(),
 	}
 }
 
@@ -27,10 +74,49 @@ func (r *registry) PlaceCreated(place *Place) {
 	})
 }
 
-func (r *registry) OnPlaceCreated(fn func(context.Context, *Place) error) {
+func (r *registry) OnPlaceCreated(fn func(context.Context, *Place) error, opts ...SubscribeOption) {
 	r.sub(func(ctx context.Context, e *event) error {
 		return fn(ctx, e.Place)
-	}, TypePlaceCreated)
+	}, opts, TypePlaceCreated)
+}
+
+func (r *registry) PlaceDeleted(place *Place) {
+	r.pub(&event{
+		Type:  TypePlaceDeleted,
+		Place: place,
+	})
+}
+
+func (r *registry) OnPlaceDeleted(fn func(context.Context, *Place) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Place)
+	}, opts, TypePlaceDeleted)
+}
+
+func (r *registry) PlaceRestored(place *Place) {
+	r.pub(&event{
+		Type:  TypePlaceRestored,
+		Place: place,
+	})
+}
+
+func (r *registry) OnPlaceRestored(fn func(context.Context, *Place) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Place)
+	}, opts, TypePlaceRestored)
+}
+
+func (r *registry) PlaceUpdated(place *Place) {
+	r.pub(&event{
+		Type:  TypePlaceUpdated,
+		Place: place,
+	})
+}
+
+func (r *registry) OnPlaceUpdated(fn func(context.Context, *Place) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Place)
+	}, opts, TypePlaceUpdated)
 }
 
 func (r *registry) RoomUpdated(room *Room) {
@@ -40,10 +126,10 @@ func (r *registry) RoomUpdated(room *Room) {
 	})
 }
 
-func (r *registry) OnRoomUpdated(fn func(context.Context, *Room) error) {
+func (r *registry) OnRoomUpdated(fn func(context.Context, *Room) error, opts ...SubscribeOption) {
 	r.sub(func(ctx context.Context, e *event) error {
 		return fn(ctx, e.Room)
-	}, TypeRoomUpdated)
+	}, opts, TypeRoomUpdated)
 }
 
 func (r *registry) RoomCreated(room *Room) {
@@ -53,10 +139,10 @@ func (r *registry) RoomCreated(room *Room) {
 	})
 }
 
-func (r *registry) OnRoomCreated(fn func(context.Context, *Room) error) {
+func (r *registry) OnRoomCreated(fn func(context.Context, *Room) error, opts ...SubscribeOption) {
 	r.sub(func(ctx context.Context, e *event) error {
 		return fn(ctx, e.Room)
-	}, TypeRoomCreated)
+	}, opts, TypeRoomCreated)
 }
 
 func (r *registry) RollCreated(roll *Roll) {
@@ -66,10 +152,10 @@ func (r *registry) RollCreated(roll *Roll) {
 	})
 }
 
-func (r *registry) OnRollCreated(fn func(context.Context, *Roll) error) {
+func (r *registry) OnRollCreated(fn func(context.Context, *Roll) error, opts ...SubscribeOption) {
 	r.sub(func(ctx context.Context, e *event) error {
 		return fn(ctx, e.Roll)
-	}, TypeRollCreated)
+	}, opts, TypeRollCreated)
 }
 
 func (r *registry) BoostCreated(boost *Boost) {
@@ -79,34 +165,348 @@ func (r *registry) BoostCreated(boost *Boost) {
 	})
 }
 
-func (r *registry) OnBoostCreated(fn func(context.Context, *Boost) error) {
+func (r *registry) OnBoostCreated(fn func(context.Context, *Boost) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Boost)
+	}, opts, TypeBoostCreated)
+}
+
+func (r *registry) VetoCreated(veto *Veto) {
+	r.pub(&event{
+		Type: TypeVetoCreated,
+		Veto: veto,
+	})
+}
+
+func (r *registry) OnVetoCreated(fn func(context.Context, *Veto) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Veto)
+	}, opts, TypeVetoCreated)
+}
+
+// PollUpdated is published when a poll is opened, voted in or closed.
+func (r *registry) PollUpdated(poll *Poll) {
+	r.pub(&event{
+		Type: TypePollUpdated,
+		Poll: poll,
+	})
+}
+
+func (r *registry) OnPollUpdated(fn func(context.Context, *Poll) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Poll)
+	}, opts, TypePollUpdated)
+}
+
+func (r *registry) ReminderDue(reminder *Reminder) {
+	r.pub(&event{
+		Type:     TypeReminderDue,
+		Reminder: reminder,
+	})
+}
+
+func (r *registry) OnReminderDue(fn func(context.Context, *Reminder) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Reminder)
+	}, opts, TypeReminderDue)
+}
+
+func (r *registry) RollReverted(roll *Roll) {
+	r.pub(&event{
+		Type: TypeRollReverted,
+		Roll: roll,
+	})
+}
+
+func (r *registry) OnRollReverted(fn func(context.Context, *Roll) error, opts ...SubscribeOption) {
+	r.sub(func(ctx context.Context, e *event) error {
+		return fn(ctx, e.Roll)
+	}, opts, TypeRollReverted)
+}
+
+func (r *registry) BoostReverted(boost *Boost) {
+	r.pub(&event{
+		Type:  TypeBoostReverted,
+		Boost: boost,
+	})
+}
+
+func (r *registry) OnBoostReverted(fn func(context.Context, *Boost) error, opts ...SubscribeOption) {
 	r.sub(func(ctx context.Context, e *event) error {
 		return fn(ctx, e.Boost)
-	}, TypeBoostCreated)
+	}, opts, TypeBoostReverted)
 }
 
+// pub queues the event for every subscriber of its type. If a queue is full, pub waits for room up to
+// enqueueTimeout, and only then drops the event for that subscriber, so that a stuck subscriber doesn't hold up
+// publishers forever.
 func (r *registry) pub(evt *event) {
+	log.Printf("[INFO] event: '%s'", evt.Type.String())
+
 	r.handlersGuard.RLock()
-	handlers := r.handlers[evt.Type]
+	if r.closed {
+		r.handlersGuard.RUnlock()
+		log.Printf("[WARN] event '%s' published after shutdown, dropped", evt.Type.String())
+		r.metrics.dropped(evt.Type)
+		return
+	}
+	subscribers := make([]*subscriber, 0, len(r.handlers[evt.Type]))
+	for _, s := range r.handlers[evt.Type] {
+		if evt.remote && !s.remote {
+			continue
+		}
+		// counted before the lock is released, so that Shutdown waits for it
+		r.pending.add()
+		subscribers = append(subscribers, s)
+	}
 	r.handlersGuard.RUnlock()
 
-	log.Printf("[INFO] event: '%s'", evt.Type.String())
+	for _, s := range subscribers {
+		r.enqueue(s, evt)
+	}
+}
 
-	ctx := context.Background()
-	for _, fn := range handlers {
-		fn := fn
-		go func() {
-			if err := fn(ctx, evt); err != nil {
-				log.Printf("[ERROR] error handling %s: %s", evt.Type.String(), err)
-			}
-		}()
+func (r *registry) enqueue(s *subscriber, evt *event) {
+	queue := s.queue(evt)
+	select {
+	case queue <- evt:
+		r.metrics.queued(evt.Type)
+		return
+	default:
+	}
+
+	log.Printf("[WARN] event '%s' waits, subscriber queue is full", evt.Type.String())
+	timer := time.NewTimer(r.enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- evt:
+		r.metrics.queued(evt.Type)
+	case <-timer.C:
+		log.Printf("[ERROR] event '%s' dropped, subscriber queue is full for %s", evt.Type.String(), r.enqueueTimeout)
+		r.metrics.dropped(evt.Type)
+		r.pending.done()
 	}
 }
 
-func (r *registry) sub(fn handler, tt ...Type) {
+// Go runs fn in the background, and Shutdown waits for it like for events. fn is not run after shutdown.
+func (r *registry) Go(fn func(context.Context)) {
+	r.handlersGuard.RLock()
+	defer r.handlersGuard.RUnlock()
+
+	if r.closed {
+		log.Printf("[WARN] background task started after shutdown, dropped")
+		return
+	}
+	r.pending.add()
+	go func() {
+		defer r.pending.done()
+		fn(r.ctx)
+	}()
+}
+
+func (r *registry) sub(fn handler, opts []SubscribeOption, tt ...Type) {
+	s := newSubscriber(fn)
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	r.handlersGuard.Lock()
 	for _, t := range tt {
-		r.handlers[t] = append(r.handlers[t], fn)
+		r.handlers[t] = append(r.handlers[t], s)
+	}
+	r.handlersGuard.Unlock()
+
+	for _, queue := range s.queues {
+		r.workers.Add(1)
+		go r.work(s, queue)
+	}
+}
+
+func (r *registry) work(s *subscriber, queue <-chan *event) {
+	defer r.workers.Done()
+	for {
+		select {
+		case evt := <-queue:
+			r.handle(s.fn, evt)
+			r.metrics.dequeued(evt.Type)
+			r.pending.done()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// handle calls the handler until it succeeds, it's called maxHandleAttempts times, or the registry is
+// canceled. Parts of the handler wrapped in Once are not repeated once they succeed.
+func (r *registry) handle(fn handler, evt *event) {
+	ctx := context.WithValue(r.ctx, handledPartsKey{}, &handledParts{done: map[string]bool{}})
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, evt)
+		if err == nil {
+			r.metrics.handled(evt.Type)
+			return
+		}
+
+		if attempt >= maxHandleAttempts || r.ctx.Err() != nil {
+			log.Printf("[ERROR] error handling %s, giving up after %d attempts: %s", evt.Type.String(), attempt, err)
+			r.metrics.failed(evt.Type)
+			return
+		}
+
+		log.Printf("[WARN] error handling %s, retrying in %s: %s", evt.Type.String(), backoff, err)
+		r.metrics.retried(evt.Type)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			timer.Stop()
+			log.Printf("[ERROR] error handling %s, canceled: %s", evt.Type.String(), err)
+			r.metrics.failed(evt.Type)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+type handledPartsKey struct{}
+
+// handledParts are keys of parts of a handler that succeeded while handling an event.
+type handledParts struct {
+	guard sync.Mutex
+	done  map[string]bool
+}
+
+// Once calls fn, unless it already succeeded with the same key while handling the same event. Handlers with several
+// side effects, like messages to several users, wrap every one of them, so that retries only repeat the ones that
+// failed.
+func Once(ctx context.Context, key string, fn func() error) error {
+	parts, ok := ctx.Value(handledPartsKey{}).(*handledParts)
+	if !ok {
+		return fn()
+	}
+
+	parts.guard.Lock()
+	done := parts.done[key]
+	parts.guard.Unlock()
+	if done {
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		return err
 	}
+
+	parts.guard.Lock()
+	parts.done[key] = true
+	parts.guard.Unlock()
+	return nil
+}
+
+// Shutdown stops accepting new events, and waits for queued ones and background tasks to be handled. If ctx is done
+// first, the context passed to handlers is canceled.
+func (r *registry) Shutdown(ctx context.Context) error {
+	r.handlersGuard.Lock()
+	closed := r.closed
+	r.closed = true
 	r.handlersGuard.Unlock()
+	if closed {
+		return nil
+	}
+
+	err := r.pending.wait(ctx)
+	r.cancel()
+	close(r.quit)
+	if err != nil {
+		return fmt.Errorf("failed to drain events: %w", err)
+	}
+	r.workers.Wait()
+	return nil
+}
+
+// Wait blocks until all events published so far are handled by all subscribers.
+func (r *registry) Wait(ctx context.Context) error {
+	return r.pending.wait(ctx)
+}
+
+// Metrics returns counters of events handled by subscribers.
+func (r *registry) Metrics() *Metrics {
+	return r.metrics.snapshot()
+}
+
+type subscriber struct {
+	fn     handler
+	queues []chan *event
+	// remote is set if the subscriber handles events stored by other instances.
+	remote bool
+}
+
+func newSubscriber(fn handler) *subscriber {
+	s := &subscriber{
+		fn:     fn,
+		queues: make([]chan *event, queuesPerSubscriber),
+	}
+	for i := range s.queues {
+		s.queues[i] = make(chan *event, queueSize)
+	}
+	return s
+}
+
+// queue returns the queue for the room of the event, so that events of one room are handled one by one.
+func (s *subscriber) queue(evt *event) chan<- *event {
+	hash := fnv.New32a()
+	hash.Write([]byte(evt.roomID()))
+	return s.queues[hash.Sum32()%uint32(len(s.queues))]
+}
+
+// pending counts events that are queued or being handled.
+type pending struct {
+	guard   *sync.Mutex
+	count   int
+	waiters []chan struct{}
+}
+
+func newPending() *pending {
+	return &pending{
+		guard: &sync.Mutex{},
+	}
+}
+
+func (p *pending) add() {
+	p.guard.Lock()
+	p.count++
+	p.guard.Unlock()
+}
+
+func (p *pending) done() {
+	p.guard.Lock()
+	defer p.guard.Unlock()
+
+	p.count--
+	if p.count > 0 {
+		return
+	}
+	for _, waiter := range p.waiters {
+		close(waiter)
+	}
+	p.waiters = nil
+}
+
+func (p *pending) wait(ctx context.Context) error {
+	p.guard.Lock()
+	if p.count == 0 {
+		p.guard.Unlock()
+		return nil
+	}
+	waiter := make(chan struct{})
+	p.waiters = append(p.waiters, waiter)
+	p.guard.Unlock()
+
+	select {
+	case <-waiter:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
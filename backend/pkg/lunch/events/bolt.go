@@ -3,6 +3,8 @@ package events
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/store"
@@ -11,20 +13,56 @@ import (
 
 var _ Storage = &boltStorage{}
 
+var (
+	boltFeedsGuard = &sync.Mutex{}
+	// boltFeeds are shared by storages of the same database, the same way server instances share a table.
+	boltFeeds = map[*store.Bolt]*feed{}
+)
+
+func boltFeed(db *store.Bolt) *feed {
+	boltFeedsGuard.Lock()
+	defer boltFeedsGuard.Unlock()
+
+	f, ok := boltFeeds[db]
+	if !ok {
+		f = newFeed()
+		boltFeeds[db] = f
+	}
+	return f
+}
+
 type boltStorage struct {
 	db         *store.Bolt
 	bucketName string
+	feed       *feed
 }
 
 func NewBoltStorage(db *store.Bolt) *boltStorage {
 	return &boltStorage{
 		db:         db,
 		bucketName: "events",
+		feed:       boltFeed(db),
 	}
 }
 
 func (b *boltStorage) Create(ctx context.Context, event *Event) error {
-	return b.db.Put(ctx, b.bucketName, fmt.Sprint(event.Timestamp), event)
+	withID(event)
+	if err := b.db.Put(ctx, b.bucketName, boltKey(event), event); err != nil {
+		return err
+	}
+	b.feed.publish(b, event)
+	return nil
+}
+
+// boltKey orders events by time, and the id keeps events that happen at the same time apart.
+func boltKey(event *Event) string {
+	return fmt.Sprintf("%019d/%s/%s", time.Time(event.Timestamp).UnixNano(), event.RoomID, event.ID)
+}
+
+// Tail delivers events created with storages of the same database. Bolt database is locked by the process that
+// opened it, so there are no other writers. Events created with other storages are remote.
+func (b *boltStorage) Tail(ctx context.Context, fn func(*Event)) *Subscription {
+	return b.feed.subscribe(ctx, b, fn)
 }
 
 func (b *boltStorage) ByUserID(ctx context.Context, userID users.ID, types ...Type) ([]*Event, error) {
@@ -68,3 +106,38 @@ func (b *boltStorage) ByRoomID(ctx context.Context, roomID rooms.ID, types ...Ty
 	}
 	return result, nil
 }
+
+func (b *boltStorage) ByType(ctx context.Context, types ...Type) ([]*Event, error) {
+	events := []*Event{}
+	if err := b.db.List(ctx, b.bucketName, &events); err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	if len(types) == 0 {
+		return events, nil
+	}
+	result := []*Event{}
+	tmap := map[Type]bool{}
+	for _, t := range types {
+		tmap[t] = true
+	}
+	for _, event := range events {
+		if tmap[event.Type] {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func (b *boltStorage) ByTypeSince(ctx context.Context, after time.Time, types ...Type) ([]*Event, error) {
+	events, err := b.ByType(ctx, types...)
+	if err != nil {
+		return nil, err
+	}
+	result := []*Event{}
+	for _, event := range events {
+		if time.Time(event.Timestamp).After(after) {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"sync"
+	"time"
 
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/users"
@@ -15,20 +16,22 @@ type cache struct {
 
 	byRoomID            map[rooms.ID][]*Event
 	byRoomIDGuard       *sync.RWMutex
-	byRoomIDInitialized bool
+	byRoomIDInitialized map[rooms.ID]bool
 
 	byUserID            map[users.ID][]*Event
 	byUserIDGuard       *sync.RWMutex
-	byUserIDInitialized bool
+	byUserIDInitialized map[users.ID]bool
 }
 
 func NewCache(s Storage) *cache {
 	return &cache{
-		storage:       s,
-		byRoomID:      make(map[rooms.ID][]*Event),
-		byRoomIDGuard: &sync.RWMutex{},
-		byUserID:      make(map[users.ID][]*Event),
-		byUserIDGuard: &sync.RWMutex{},
+		storage:             s,
+		byRoomID:            make(map[rooms.ID][]*Event),
+		byRoomIDGuard:       &sync.RWMutex{},
+		byRoomIDInitialized: make(map[rooms.ID]bool),
+		byUserID:            make(map[users.ID][]*Event),
+		byUserIDGuard:       &sync.RWMutex{},
+		byUserIDInitialized: make(map[users.ID]bool),
 	}
 }
 
@@ -49,7 +52,7 @@ func (c *cache) Create(ctx context.Context, event *Event) error {
 
 func (c *cache) ByUserID(ctx context.Context, userID users.ID, types ...Type) ([]*Event, error) {
 	c.byUserIDGuard.RLock()
-	isInitialized := c.byUserIDInitialized
+	isInitialized := c.byUserIDInitialized[userID]
 	events := c.byUserID[userID]
 	c.byUserIDGuard.RUnlock()
 
@@ -61,7 +64,7 @@ func (c *cache) ByUserID(ctx context.Context, userID users.ID, types ...Type) ([
 		c.byUserIDGuard.Lock()
 		events = ee
 		c.byUserID[userID] = events
-		c.byUserIDInitialized = true
+		c.byUserIDInitialized[userID] = true
 		c.byUserIDGuard.Unlock()
 	}
 
@@ -83,7 +86,7 @@ func (c *cache) ByUserID(ctx context.Context, userID users.ID, types ...Type) ([
 
 func (c *cache) ByRoomID(ctx context.Context, roomID rooms.ID, types ...Type) ([]*Event, error) {
 	c.byRoomIDGuard.RLock()
-	isInitialized := c.byRoomIDInitialized
+	isInitialized := c.byRoomIDInitialized[roomID]
 	events := c.byRoomID[roomID]
 	c.byRoomIDGuard.RUnlock()
 
@@ -95,7 +98,7 @@ func (c *cache) ByRoomID(ctx context.Context, roomID rooms.ID, types ...Type) ([
 		c.byRoomIDGuard.Lock()
 		events = ee
 		c.byRoomID[roomID] = events
-		c.byRoomIDInitialized = true
+		c.byRoomIDInitialized[roomID] = true
 		c.byRoomIDGuard.Unlock()
 	}
 
@@ -114,3 +117,47 @@ func (c *cache) ByRoomID(ctx context.Context, roomID rooms.ID, types ...Type) ([
 	}
 	return filtered, nil
 }
+
+// ByType is not cached, it always goes to the underlying storage.
+func (c *cache) ByType(ctx context.Context, types ...Type) ([]*Event, error) {
+	return c.storage.ByType(ctx, types...)
+}
+
+// ByTypeSince is not cached, it always goes to the underlying storage.
+func (c *cache) ByTypeSince(ctx context.Context, after time.Time, types ...Type) ([]*Event, error) {
+	return c.storage.ByTypeSince(ctx, after, types...)
+}
+
+// Tail also adds events stored by others to the cache, so that it doesn't get stale.
+func (c *cache) Tail(ctx context.Context, fn func(*Event)) *Subscription {
+	return c.storage.Tail(ctx, func(event *Event) {
+		c.remember(event)
+		fn(event)
+	})
+}
+
+// remember adds the event to cached rooms and users, unless it's already there.
+func (c *cache) remember(event *Event) {
+	c.byRoomIDGuard.Lock()
+	if c.byRoomIDInitialized[event.RoomID] && !contains(c.byRoomID[event.RoomID], event) {
+		c.byRoomID[event.RoomID] = append(c.byRoomID[event.RoomID], event)
+	}
+	c.byRoomIDGuard.Unlock()
+
+	c.byUserIDGuard.Lock()
+	if c.byUserIDInitialized[event.UserID] && !contains(c.byUserID[event.UserID], event) {
+		c.byUserID[event.UserID] = append(c.byUserID[event.UserID], event)
+	}
+	c.byUserIDGuard.Unlock()
+}
+
+// contains looks for the event from the end, events created with the cache are usually the last ones.
+func contains(ee []*Event, event *Event) bool {
+	key := eventKey(event)
+	for i := len(ee) - 1; i >= 0; i-- {
+		if ee[i] == event || eventKey(ee[i]) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package events
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"lunch/pkg/lunch/rooms"
@@ -10,38 +11,128 @@ import (
 	"lunch/pkg/users"
 )
 
+const (
+	// pollInterval is how often the table is checked for events stored by other server instances.
+	pollInterval = 2 * time.Second
+	// pollLookback is how late an event can become visible after the time it was stored at.
+	pollLookback = time.Minute
+	// storedBucketSize is the time range of the 'stored_bucket' key that events are polled by, so that a poll
+	// queries a couple of partitions instead of scanning the table.
+	storedBucketSize = time.Minute
+)
+
 type dynamoDB struct {
 	db        *store.DynamoDB
 	tableName string
+
+	feed         *feed
+	poller       *poller
+	startPolling *sync.Once
 }
 
 func NewDynamoDBStore(db *store.DynamoDB, tableName string) *dynamoDB {
-	return &dynamoDB{
-		db:        db,
-		tableName: tableName,
+	d := &dynamoDB{
+		db:           db,
+		tableName:    tableName,
+		feed:         newFeed(),
+		startPolling: &sync.Once{},
 	}
+	d.poller = newPoller(d.since, pollInterval, pollLookback)
+	return d
 }
 
 func (d *dynamoDB) Create(ctx context.Context, event *Event) error {
+	withID(event)
+	if err := d.insert(ctx, event, time.Now()); err != nil {
+		return err
+	}
+	d.poller.markSeen(event)
+	d.feed.publish(d, event)
+	return nil
+}
+
+func (d *dynamoDB) insert(ctx context.Context, event *Event, storedAt time.Time) error {
 	if err := d.db.Execute(ctx, fmt.Sprintf(`
 		INSERT INTO "%s" value {
+			'id': ?,
 			'user_id': ?,
 			'room_id': ?,
 			'type': ?,
 			'timestamp': ?,
 			'place_id': ?,
-			'name': ?
+			'name': ?,
+			'payload': ?,
+			'stored_bucket': ?,
+			'stored_at': ?
 		}
-	`, d.tableName), event.UserID, event.RoomID, event.Type, time.Time(event.Timestamp).UnixNano(), event.PlaceID, event.Name); err != nil {
+	`, d.tableName), event.ID, event.UserID, event.RoomID, event.Type, time.Time(event.Timestamp).UnixNano(), event.PlaceID, event.Name, event.Payload, storedBucket(storedAt), storedAt.UnixNano()); err != nil {
 		return fmt.Errorf("failed to insert: %w", err)
 	}
 	return nil
 }
 
+// CopyTo stores all events of the table in another table, like when the key of the table changes. Copies are
+// stored at the time the events happened, so that they are not found by server instances polling the other table.
+func (d *dynamoDB) CopyTo(ctx context.Context, other *dynamoDB) error {
+	ee, err := d.ByType(ctx)
+	if err != nil {
+		return err
+	}
+	for _, event := range ee {
+		// the old key, so that copying again doesn't duplicate events
+		if event.ID == "" {
+			event.ID = fmt.Sprintf("%s/%d", event.UserID, time.Time(event.Timestamp).UnixNano())
+		}
+		if err := other.insert(ctx, event, time.Time(event.Timestamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tail delivers events created with this storage right away, and polls the table for events stored by others.
+func (d *dynamoDB) Tail(ctx context.Context, fn func(*Event)) *Subscription {
+	subscription := d.feed.subscribe(ctx, d, fn)
+	d.startPolling.Do(func() {
+		go d.poller.run(context.Background(), d.feed)
+	})
+	return subscription
+}
+
+// since returns events that were stored after the given time.
+func (d *dynamoDB) since(ctx context.Context, after time.Time) ([]*Event, error) {
+	ee := []*Event{}
+	for _, bucket := range storedBuckets(after, time.Now()) {
+		bucketEvents := []*Event{}
+		if err := d.db.Query(ctx, &bucketEvents, fmt.Sprintf(`
+			SELECT * FROM "%s"."stored_bucket.stored_at"
+			WHERE "stored_bucket" = ? AND "stored_at" > ?
+		`, d.tableName), bucket, after.UnixNano()); err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		ee = append(ee, bucketEvents...)
+	}
+	return ee, nil
+}
+
+func storedBucket(storedAt time.Time) string {
+	return storedAt.UTC().Truncate(storedBucketSize).Format(time.RFC3339)
+}
+
+// storedBuckets returns the buckets of events stored between from and to. A bucket past to is included, so that
+// events stored by instances with a clock ahead of this one are found.
+func storedBuckets(from, to time.Time) []string {
+	buckets := []string{}
+	for t := from.UTC().Truncate(storedBucketSize); !t.After(to.Add(storedBucketSize)); t = t.Add(storedBucketSize) {
+		buckets = append(buckets, storedBucket(t))
+	}
+	return buckets
+}
+
 func (d *dynamoDB) ByUserID(ctx context.Context, userID users.ID, types ...Type) ([]*Event, error) {
 	ee := []*Event{}
 	if err := d.db.Query(ctx, &ee, fmt.Sprintf(`
-		SELECT * FROM "%s"
+		SELECT * FROM "%s"."user_id.timestamp"
 		WHERE user_id = ?
 	`, d.tableName), userID); err != nil {
 		return nil, fmt.Errorf("failed to query: %w", err)
@@ -89,3 +180,42 @@ func (d *dynamoDB) ByRoomID(ctx context.Context, roomID rooms.ID, types ...Type)
 	}
 	return filteredEvents, nil
 }
+
+func (d *dynamoDB) ByType(ctx context.Context, types ...Type) ([]*Event, error) {
+	ee := []*Event{}
+	if len(types) == 0 {
+		if err := d.db.Query(ctx, &ee, fmt.Sprintf(`
+			SELECT * FROM "%s"
+		`, d.tableName)); err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		return ee, nil
+	}
+
+	for _, t := range types {
+		typeEvents := []*Event{}
+		if err := d.db.Query(ctx, &typeEvents, fmt.Sprintf(`
+			SELECT * FROM "%s"."type.timestamp"
+			WHERE "type" = ?
+		`, d.tableName), t); err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		ee = append(ee, typeEvents...)
+	}
+	return ee, nil
+}
+
+func (d *dynamoDB) ByTypeSince(ctx context.Context, after time.Time, types ...Type) ([]*Event, error) {
+	ee := []*Event{}
+	for _, t := range types {
+		typeEvents := []*Event{}
+		if err := d.db.Query(ctx, &typeEvents, fmt.Sprintf(`
+			SELECT * FROM "%s"."type.timestamp"
+			WHERE "type" = ? AND "timestamp" > ?
+		`, d.tableName), t, after.UnixNano()); err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		ee = append(ee, typeEvents...)
+	}
+	return ee, nil
+}
@@ -0,0 +1,14 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_storedBuckets(t *testing.T) {
+	from := time.Date(2021, time.September, 6, 13, 29, 30, 0, time.FixedZone("CEST", 2*60*60))
+	to := from.Add(time.Minute)
+
+	assertEqual(t, []string{"2021-09-06T11:29:00Z", "2021-09-06T11:30:00Z", "2021-09-06T11:31:00Z"}, storedBuckets(from, to))
+	assertEqual(t, "2021-09-06T11:30:00Z", storedBucket(to))
+}
@@ -11,17 +11,60 @@ import (
 	"lunch/pkg/users"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 )
 
 type Type string
 
 type Event struct {
+	// ID is unique, so that events of different rooms or users that happen at the same time don't overwrite each
+	// other. It's set when the event is stored.
+	ID        string       `dynamodbav:"id"`
 	UserID    users.ID     `dynamodbav:"user_id"`
 	RoomID    rooms.ID     `dynamodbav:"room_id"`
 	Type      Type         `dynamodbav:"type"`
 	Timestamp UnixNanoTime `dynamodbav:"timestamp,unixtime"`
 	PlaceID   places.ID    `dynamodbav:"place_id"`
 	Name      string       `dynamodbav:"name"`
+	// Payload is json encoded event specific data.
+	Payload json.RawMessage `dynamodbav:"payload" json:",omitempty"`
+
+	// remote is set for tailed events that were stored by another process.
+	remote bool
+}
+
+// withID sets a new id of the event, unless it has one.
+func withID(event *Event) {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+}
+
+// IsRemote returns true if the event was found by tailing the storage, and was stored by another storage, like
+// another server instance.
+func (e *Event) IsRemote() bool {
+	return e.remote
+}
+
+// MarshalPayload encodes v as the event payload.
+func (e *Event) MarshalPayload(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	e.Payload = payload
+	return nil
+}
+
+// UnmarshalPayload decodes the event payload into v. If the event has no payload, v is left unchanged.
+func (e *Event) UnmarshalPayload(v interface{}) error {
+	if len(e.Payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return nil
 }
 
 type UnixNanoTime time.Time
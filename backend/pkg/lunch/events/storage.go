@@ -2,6 +2,7 @@ package events
 
 import (
 	"context"
+	"time"
 
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/users"
@@ -16,4 +17,14 @@ type Storage interface {
 	// ByRoomID returns all events for a given room id.
 	// If no types are specified, all events are returned, otherwise only events of the given types are returned.
 	ByRoomID(context.Context, rooms.ID, ...Type) ([]*Event, error)
+	// ByType returns all events of the given types.
+	// If no types are specified, all events are returned.
+	ByType(context.Context, ...Type) ([]*Event, error)
+	// ByTypeSince returns events of the given types that happened after the given time.
+	ByTypeSince(context.Context, time.Time, ...Type) ([]*Event, error)
+	// Tail calls fn with every event stored from now on, one by one, until ctx is done or the subscription is
+	// closed. Events created with this storage are delivered right away. Storages that can be written by
+	// other processes also deliver their events, when they find them. fn must not store events: when it falls
+	// too far behind, storing waits for it.
+	Tail(ctx context.Context, fn func(*Event)) *Subscription
 }
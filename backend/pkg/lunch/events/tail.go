@@ -0,0 +1,288 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxQueue is how many events a subscription queues before storing more events waits for it.
+const maxQueue = 10000
+
+// Subscription calls a function with stored events one by one, in the order they were stored.
+type Subscription struct {
+	fn func(*Event)
+
+	guard   *sync.Mutex
+	cond    *sync.Cond
+	queue   []*Event
+	busy    bool
+	closed  bool
+	waiters []chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newSubscription(ctx context.Context, fn func(*Event)) *Subscription {
+	ctx, cancel := context.WithCancel(ctx)
+	guard := &sync.Mutex{}
+	s := &Subscription{
+		fn:     fn,
+		guard:  guard,
+		cond:   sync.NewCond(guard),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	go func() {
+		<-ctx.Done()
+		s.guard.Lock()
+		s.closed = true
+		s.cond.Broadcast()
+		s.guard.Unlock()
+	}()
+	return s
+}
+
+func (s *Subscription) run() {
+	defer close(s.done)
+	for {
+		s.guard.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.notifyWaiters()
+			s.guard.Unlock()
+			return
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.busy = true
+		// wake up pushes waiting for space
+		s.cond.Broadcast()
+		s.guard.Unlock()
+
+		s.fn(event)
+
+		s.guard.Lock()
+		s.busy = false
+		if len(s.queue) == 0 {
+			s.notifyWaiters()
+		}
+		s.guard.Unlock()
+	}
+}
+
+// push queues the event without waiting for it to be handled. If the queue is full, it waits until there is space
+// or the subscription is closed, so a slow subscriber slows down storing events instead of growing memory.
+func (s *Subscription) push(event *Event) bool {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+
+	for len(s.queue) >= maxQueue && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return false
+	}
+	s.queue = append(s.queue, event)
+	s.cond.Broadcast()
+	return true
+}
+
+func (s *Subscription) notifyWaiters() {
+	for _, waiter := range s.waiters {
+		close(waiter)
+	}
+	s.waiters = nil
+}
+
+// Wait blocks until all events queued so far are handled, or the subscription is closed.
+func (s *Subscription) Wait(ctx context.Context) error {
+	s.guard.Lock()
+	if s.closed || len(s.queue) == 0 && !s.busy {
+		s.guard.Unlock()
+		return nil
+	}
+	waiter := make(chan struct{})
+	s.waiters = append(s.waiters, waiter)
+	s.guard.Unlock()
+
+	select {
+	case <-waiter:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the subscription, and waits for the event being handled. Queued events are dropped.
+func (s *Subscription) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// feed delivers created events to subscriptions. Storages that write to the same place share a feed, each of them is
+// an origin, and events are remote for subscriptions of other origins.
+type feed struct {
+	guard         *sync.Mutex
+	subscriptions []*feedSubscription
+}
+
+type feedSubscription struct {
+	*Subscription
+	origin interface{}
+}
+
+func newFeed() *feed {
+	return &feed{
+		guard: &sync.Mutex{},
+	}
+}
+
+func (f *feed) subscribe(ctx context.Context, origin interface{}, fn func(*Event)) *Subscription {
+	s := newSubscription(ctx, fn)
+	f.guard.Lock()
+	f.subscriptions = append(f.subscriptions, &feedSubscription{Subscription: s, origin: origin})
+	f.guard.Unlock()
+	return s
+}
+
+// publish delivers the event created by the origin. Events found by polling have no origin, and are remote for
+// everyone.
+func (f *feed) publish(origin interface{}, event *Event) {
+	f.guard.Lock()
+	defer f.guard.Unlock()
+
+	remote := *event
+	remote.remote = true
+
+	active := f.subscriptions[:0]
+	for _, s := range f.subscriptions {
+		e := event
+		if origin == nil || s.origin != origin {
+			e = &remote
+		}
+		if s.push(e) {
+			active = append(active, s)
+		}
+	}
+	f.subscriptions = active
+}
+
+// poller finds events stored by other writers, like other server instances or migrations, by polling a storage for
+// recent events. Events are stored with the time they happened at, and can become visible a bit later, so every poll
+// looks back a little, and events that were already seen are skipped.
+type poller struct {
+	since    func(context.Context, time.Time) ([]*Event, error)
+	interval time.Duration
+	lookback time.Duration
+	now      func() time.Time
+
+	guard *sync.Mutex
+	// seen are keys of events that were delivered, with the time they can be forgotten after.
+	seen   map[string]time.Time
+	primed bool
+}
+
+func newPoller(since func(context.Context, time.Time) ([]*Event, error), interval, lookback time.Duration) *poller {
+	return &poller{
+		since:    since,
+		interval: interval,
+		lookback: lookback,
+		now:      time.Now,
+		guard:    &sync.Mutex{},
+		seen:     map[string]time.Time{},
+	}
+}
+
+// markSeen remembers the event, so it's not delivered again when it's found by polling.
+func (p *poller) markSeen(event *Event) {
+	p.guard.Lock()
+	p.seen[eventKey(event)] = latest(p.now(), time.Time(event.Timestamp))
+	p.guard.Unlock()
+}
+
+// run polls the storage until ctx is done, and publishes new events to the feed.
+func (p *poller) run(ctx context.Context, f *feed) {
+	if err := p.poll(ctx, f); err != nil {
+		log.Printf("[ERROR] events: failed to poll: %s", err)
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.poll(ctx, f); err != nil {
+				log.Printf("[ERROR] events: failed to poll: %s", err)
+			}
+		}
+	}
+}
+
+func (p *poller) poll(ctx context.Context, f *feed) error {
+	now := p.now()
+	ee, err := p.since(ctx, now.Add(-p.lookback))
+	if err != nil {
+		return err
+	}
+
+	sortByTime(ee)
+
+	p.guard.Lock()
+	fresh := []*Event{}
+	for _, event := range ee {
+		key := eventKey(event)
+		if _, ok := p.seen[key]; ok {
+			continue
+		}
+		p.seen[key] = latest(now, time.Time(event.Timestamp))
+		fresh = append(fresh, event)
+	}
+	// events older than the lookback are never returned again
+	for key, forgetAfter := range p.seen {
+		if now.Sub(forgetAfter) > 2*p.lookback {
+			delete(p.seen, key)
+		}
+	}
+	// events stored before the first poll happened before anyone subscribed
+	if !p.primed {
+		p.primed = true
+		fresh = nil
+	}
+	p.guard.Unlock()
+
+	for _, event := range fresh {
+		f.publish(nil, event)
+	}
+	return nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func eventKey(event *Event) string {
+	if event.ID != "" {
+		return event.ID
+	}
+	return fmt.Sprintf("%d/%s/%s/%s/%s/%s", time.Time(event.Timestamp).UnixNano(), event.Type, event.RoomID, event.UserID, event.PlaceID, event.Name)
+}
+
+func sortByTime(ee []*Event) {
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"lunch/pkg/store"
+)
+
+func Test_TailBolt(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	storage := NewBoltStorage(bolt)
+	ctx := context.Background()
+	now := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC)
+
+	assertNoError(t, storage.Create(ctx, &Event{UserID: "1", RoomID: "1", Type: "test/before", Timestamp: UnixNanoTime(now)}))
+
+	guard := &sync.Mutex{}
+	tailed := []Type{}
+	subscription := storage.Tail(ctx, func(event *Event) {
+		guard.Lock()
+		tailed = append(tailed, event.Type)
+		guard.Unlock()
+	})
+
+	// events are tailed in the order they are stored, not in the order of their timestamps
+	assertNoError(t, storage.Create(ctx, &Event{UserID: "1", RoomID: "1", Type: "test/first", Timestamp: UnixNanoTime(now.Add(time.Hour))}))
+	assertNoError(t, storage.Create(ctx, &Event{UserID: "1", RoomID: "2", Type: "test/second", Timestamp: UnixNanoTime(now.Add(time.Minute))}))
+	assertNoError(t, subscription.Wait(ctx))
+
+	guard.Lock()
+	assertEqual(t, []Type{"test/first", "test/second"}, tailed)
+	guard.Unlock()
+
+	subscription.Close()
+	assertNoError(t, storage.Create(ctx, &Event{UserID: "1", RoomID: "1", Type: "test/after", Timestamp: UnixNanoTime(now.Add(2 * time.Hour))}))
+	assertNoError(t, subscription.Wait(ctx))
+
+	guard.Lock()
+	assertEqual(t, 2, len(tailed))
+	guard.Unlock()
+}
+
+func Test_TailBoltShared(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	// two storages of the same database, like two server instances
+	first, second := NewBoltStorage(bolt), NewBoltStorage(bolt)
+	ctx := context.Background()
+	now := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC)
+
+	guard := &sync.Mutex{}
+	tailed := map[bool][]Type{}
+	subscription := first.Tail(ctx, func(event *Event) {
+		guard.Lock()
+		tailed[event.IsRemote()] = append(tailed[event.IsRemote()], event.Type)
+		guard.Unlock()
+	})
+	defer subscription.Close()
+
+	assertNoError(t, first.Create(ctx, &Event{UserID: "1", RoomID: "1", Type: "test/local", Timestamp: UnixNanoTime(now)}))
+	assertNoError(t, second.Create(ctx, &Event{UserID: "1", RoomID: "1", Type: "test/remote", Timestamp: UnixNanoTime(now.Add(time.Second))}))
+	assertNoError(t, subscription.Wait(ctx))
+
+	guard.Lock()
+	assertEqual(t, map[bool][]Type{false: {"test/local"}, true: {"test/remote"}}, tailed)
+	guard.Unlock()
+}
+
+func Test_Poller(t *testing.T) {
+	now := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC)
+
+	stored := []*Event{
+		{UserID: "1", RoomID: "1", Type: "test/before", Timestamp: UnixNanoTime(now)},
+	}
+	p := newPoller(func(ctx context.Context, since time.Time) ([]*Event, error) {
+		result := []*Event{}
+		for _, event := range stored {
+			if time.Time(event.Timestamp).After(since) {
+				result = append(result, event)
+			}
+		}
+		return result, nil
+	}, time.Second, time.Minute)
+	p.now = func() time.Time { return now }
+
+	f := newFeed()
+	guard := &sync.Mutex{}
+	polled := []Type{}
+	subscription := f.subscribe(context.Background(), "local", func(event *Event) {
+		if !event.IsRemote() {
+			t.Errorf("expected polled event %s to be remote", event.Type)
+		}
+		guard.Lock()
+		polled = append(polled, event.Type)
+		guard.Unlock()
+	})
+	defer subscription.Close()
+
+	// the first poll skips events stored before it
+	assertNoError(t, p.poll(context.Background(), f))
+
+	// events stored by this process are published when they are created
+	local := &Event{UserID: "1", RoomID: "1", Type: "test/local", Timestamp: UnixNanoTime(now.Add(time.Second))}
+	p.markSeen(local)
+	stored = append(stored,
+		local,
+		&Event{UserID: "2", RoomID: "1", Type: "test/remote", Timestamp: UnixNanoTime(now.Add(2 * time.Second))},
+	)
+
+	now = now.Add(5 * time.Second)
+	assertNoError(t, p.poll(context.Background(), f))
+	now = now.Add(5 * time.Second)
+	assertNoError(t, p.poll(context.Background(), f))
+	assertNoError(t, subscription.Wait(context.Background()))
+
+	guard.Lock()
+	assertEqual(t, []Type{"test/remote"}, polled)
+	guard.Unlock()
+}
+
+func Test_SubscriptionFull(t *testing.T) {
+	release := make(chan struct{})
+	s := newSubscription(context.Background(), func(*Event) {
+		<-release
+	})
+	defer s.Close()
+
+	pushed := make(chan struct{})
+	go func() {
+		// one is handled, the rest fill the queue
+		for i := 0; i < maxQueue+2; i++ {
+			s.push(&Event{Type: "test/full"})
+		}
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("expected push to wait for space in the queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-pushed
+	assertNoError(t, s.Wait(context.Background()))
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	assertError(t, nil, err)
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
+
+func assertError(t *testing.T, expected error, got error) {
+	t.Helper()
+
+	if !errors.Is(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
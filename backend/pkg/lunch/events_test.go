@@ -0,0 +1,336 @@
+package lunch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/rolls"
+	storage_rolls "lunch/pkg/lunch/rolls/storage"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func testPlace(roomID rooms.ID, name string) *Place {
+	return &Place{Place: &places.Place{RoomID: roomID, Name: name}}
+}
+
+func TestRegistry_orderedPerRoom(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	guard := &sync.Mutex{}
+	handled := map[rooms.ID][]string{}
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		// the first events take longer, so that they would be overtaken if handled concurrently
+		if place.Name == "0" {
+			time.Sleep(10 * time.Millisecond)
+		}
+		guard.Lock()
+		handled[place.RoomID] = append(handled[place.RoomID], place.Name)
+		guard.Unlock()
+		return nil
+	})
+
+	roomIDs := []rooms.ID{"1", "2", "3"}
+	for i := 0; i < 50; i++ {
+		for _, roomID := range roomIDs {
+			r.PlaceCreated(testPlace(roomID, fmt.Sprint(i)))
+		}
+	}
+	assertNoError(t, r.Wait(context.Background()))
+
+	for _, roomID := range roomIDs {
+		assertEqual(t, 50, len(handled[roomID]))
+		for i, name := range handled[roomID] {
+			assertEqual(t, fmt.Sprint(i), name)
+		}
+	}
+
+	metrics := r.Metrics()
+	assertEqual(t, int64(150), metrics.Handled["place_created"])
+	assertEqual(t, int64(0), metrics.Queued["place_created"])
+}
+
+func TestRegistry_retries(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	calls := map[string]int{}
+	guard := &sync.Mutex{}
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		guard.Lock()
+		defer guard.Unlock()
+		calls[place.Name]++
+		if place.Name == "broken" || calls[place.Name] < 2 {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+
+	r.PlaceCreated(testPlace("1", "flaky"))
+	r.PlaceCreated(testPlace("2", "broken"))
+	assertNoError(t, r.Wait(context.Background()))
+
+	assertEqual(t, 2, calls["flaky"])
+	assertEqual(t, maxHandleAttempts, calls["broken"])
+
+	metrics := r.Metrics()
+	assertEqual(t, int64(1), metrics.Handled["place_created"])
+	assertEqual(t, int64(1), metrics.Failed["place_created"])
+	assertEqual(t, int64(1+maxHandleAttempts-1), metrics.Retried["place_created"])
+}
+
+func TestRegistry_shutdown(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	handled := make(chan string, 10)
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		time.Sleep(10 * time.Millisecond)
+		handled <- place.Name
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		r.PlaceCreated(testPlace("1", fmt.Sprint(i)))
+	}
+	assertNoError(t, r.Shutdown(context.Background()))
+	assertEqual(t, 5, len(handled))
+
+	// published after shutdown
+	r.PlaceCreated(testPlace("1", "late"))
+	assertNoError(t, r.Wait(context.Background()))
+	assertEqual(t, 5, len(handled))
+	assertEqual(t, int64(1), r.Metrics().Dropped["place_created"])
+
+	assertNoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRegistry_shutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	canceled := make(chan struct{})
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	r.PlaceCreated(testPlace("1", "stuck"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assertError(t, context.DeadlineExceeded, r.Shutdown(ctx))
+
+	<-canceled
+	assertNoError(t, r.Wait(context.Background()))
+	assertEqual(t, int64(1), r.Metrics().Failed["place_created"])
+}
+
+func TestRegistry_fullQueue(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+	r.enqueueTimeout = 10 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		if place.Name == "handled" {
+			close(started)
+		}
+		<-release
+		return nil
+	})
+
+	// one is being handled, the queue is filled, and the last one doesn't fit in time
+	r.PlaceCreated(testPlace("1", "handled"))
+	<-started
+	for i := 0; i < queueSize+1; i++ {
+		r.PlaceCreated(testPlace("1", fmt.Sprint(i)))
+	}
+	assertEqual(t, int64(1), r.Metrics().Dropped["place_created"])
+
+	close(release)
+	assertNoError(t, r.Wait(context.Background()))
+	assertEqual(t, int64(queueSize+1), r.Metrics().Handled["place_created"])
+}
+
+func TestRegistry_fullQueueWaits(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		if place.Name == "handled" {
+			close(started)
+		}
+		<-release
+		return nil
+	})
+
+	r.PlaceCreated(testPlace("1", "handled"))
+	<-started
+	for i := 0; i < queueSize; i++ {
+		r.PlaceCreated(testPlace("1", fmt.Sprint(i)))
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	// waits until the handler frees up room
+	r.PlaceCreated(testPlace("1", "last"))
+
+	assertNoError(t, r.Wait(context.Background()))
+	assertEqual(t, int64(0), r.Metrics().Dropped["place_created"])
+	assertEqual(t, int64(queueSize+2), r.Metrics().Handled["place_created"])
+}
+
+func TestOnce(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	calls := map[string]int{}
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		if err := Once(ctx, "first", func() error {
+			calls["first"]++
+			return nil
+		}); err != nil {
+			return err
+		}
+		return Once(ctx, "second", func() error {
+			calls["second"]++
+			if calls["second"] < 2 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+	})
+	r.PlaceCreated(testPlace("1", "place"))
+	r.PlaceCreated(testPlace("1", "other"))
+
+	assertNoError(t, r.Wait(context.Background()))
+	// the first part isn't repeated when the second one is retried, but runs again for the next event
+	assertEqual(t, 2, calls["first"])
+	assertEqual(t, 3, calls["second"])
+}
+
+func TestRegistry_publishDuringShutdown(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		if place.Name == "first" {
+			close(started)
+			<-release
+			r.PlaceCreated(testPlace(place.RoomID, "second"))
+		}
+		return nil
+	})
+	r.PlaceCreated(testPlace("1", "first"))
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- r.Shutdown(context.Background())
+	}()
+	// let shutdown start before the handler publishes
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	assertNoError(t, <-shutdown)
+	assertEqual(t, int64(1), r.Metrics().Handled["place_created"])
+	assertEqual(t, int64(1), r.Metrics().Dropped["place_created"])
+}
+
+func TestRegistry_go(t *testing.T) {
+	t.Parallel()
+
+	r := newEventsRegistry()
+
+	done := make(chan struct{})
+	r.Go(func(ctx context.Context) {
+		time.Sleep(10 * time.Millisecond)
+		close(done)
+	})
+	assertNoError(t, r.Shutdown(context.Background()))
+
+	select {
+	case <-done:
+	default:
+		t.Error("expected shutdown to wait for the task")
+	}
+
+	r.Go(func(ctx context.Context) {
+		t.Error("expected task not to run after shutdown")
+	})
+}
+
+func TestRoller_storedEvents(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC)
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	eventsStorage := events.NewBoltStorage(bolt)
+	roller := New(eventsStorage, storage_users.NewBolt(bolt))
+
+	// another server instance, with its own storage of the same database
+	other := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	guard := &sync.Mutex{}
+	handled := []string{}
+	other.OnPlaceCreated(func(ctx context.Context, place *Place) error {
+		guard.Lock()
+		handled = append(handled, "place "+place.Name)
+		guard.Unlock()
+		return nil
+	}, IncludeRemote)
+	other.OnRollCreated(func(ctx context.Context, roll *Roll) error {
+		guard.Lock()
+		handled = append(handled, "roll "+roll.Place.Name)
+		guard.Unlock()
+		return nil
+	}, IncludeRemote)
+	other.OnRollCreated(func(ctx context.Context, roll *Roll) error {
+		t.Errorf("unexpected remote roll handled without IncludeRemote")
+		return nil
+	})
+
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+	pp, err := roller.ListPlaces(ctx, roomID, now)
+	assertNoError(t, err)
+
+	// events stored without the roller, like by a migration, reach subscribers too
+	assertNoError(t, storage_rolls.New(eventsStorage).Create(ctx, rolls.NewRoll("migration", roomID, pp[0].ID, now)))
+
+	assertNoError(t, other.Wait(context.Background()))
+
+	// subscribers are independent, so their events can be handled in any order
+	guard.Lock()
+	sort.Strings(handled)
+	assertEqual(t, []string{"place place", "roll place"}, handled)
+	guard.Unlock()
+}
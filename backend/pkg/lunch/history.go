@@ -1,40 +1,83 @@
 package lunch
 
 import (
+	"fmt"
 	"math"
 	"time"
 
 	"lunch/pkg/lunch/boosts"
 	"lunch/pkg/lunch/places"
 	"lunch/pkg/lunch/rolls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/vetoes"
 	"lunch/pkg/users"
 )
 
 const (
-	hoursInADay     = 24
-	totalPoints     = 1
-	boostMultiplier = 5
+	hoursInADay = 24
 )
 
+// date is a calendar day.
+type date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func dateOf(t time.Time) date {
+	year, month, day := t.Date()
+	return date{Year: year, Month: month, Day: day}
+}
+
 type rollsHistory struct {
-	ThisWeekBoosts  []*boosts.Boost
-	RollsPerWeekday map[time.Weekday][]*rolls.Roll
-	LastRolled      map[places.ID]time.Time
-	ActiveBoosts    map[places.ID]int
+	Settings          rooms.Settings
+	ThisPeriodBoosts  []*boosts.Boost
+	ThisPeriodVetoes  []*vetoes.Veto
+	ThisPeriodRollsBy map[date][]*rolls.Roll
+	LastRolled        map[places.ID]time.Time
+	ActiveBoosts      map[places.ID]int
+	ActiveVetoes      map[places.ID]bool
+}
+
+// periodStart returns the beginning of the period t belongs to, in t's location.
+// Weeks start on Monday.
+func periodStart(t time.Time, period rooms.Period) time.Time {
+	year, month, day := t.Date()
+	switch period {
+	case rooms.PeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	}
 }
 
-func buildHistory(allRolls []*rolls.Roll, allBoosts []*boosts.Boost, now time.Time) *rollsHistory {
-	year, week := now.ISOWeek()
-	rollsPerWeekday := map[time.Weekday][]*rolls.Roll{}
+// periodEnd returns the beginning of the next period after the one t belongs to, in t's location.
+func periodEnd(t time.Time, period rooms.Period) time.Time {
+	start := periodStart(t, period)
+	switch period {
+	case rooms.PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 7)
+	}
+}
+
+// buildHistory groups rolls and boosts by days and periods, counted in the room time zone.
+func buildHistory(allRolls []*rolls.Roll, allBoosts []*boosts.Boost, allVetoes []*vetoes.Veto, settings rooms.Settings, now time.Time) *rollsHistory {
+	now = now.In(settings.Location())
+	from, to := periodStart(now, settings.Period), periodEnd(now, settings.Period)
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(to)
+	}
+
+	rollsByDate := map[date][]*rolls.Roll{}
 	lastRolled := map[places.ID]time.Time{}
 	var latestRoll *rolls.Roll
 	for _, roll := range allRolls {
-		rollYear, rollWeek := roll.Time.ISOWeek()
-		sameYear := rollYear == year
-		sameWeek := rollWeek == week
-		if sameYear && sameWeek {
-			weekday := roll.Time.Weekday()
-			rollsPerWeekday[weekday] = append(rollsPerWeekday[weekday], roll)
+		if inPeriod(roll.Time) {
+			day := dateOf(roll.Time.In(now.Location()))
+			rollsByDate[day] = append(rollsByDate[day], roll)
 		}
 
 		if roll.Time.After(lastRolled[roll.PlaceID]) {
@@ -48,14 +91,11 @@ func buildHistory(allRolls []*rolls.Roll, allBoosts []*boosts.Boost, now time.Ti
 		}
 	}
 
-	thisWeekBoosts := []*boosts.Boost{}
+	thisPeriodBoosts := []*boosts.Boost{}
 	activeBoosts := map[places.ID]int{}
 	for _, boost := range allBoosts {
-		boostYear, boostWeek := boost.Time.ISOWeek()
-		sameYear := boostYear == year
-		sameWeek := boostWeek == week
-		if sameYear && sameWeek {
-			thisWeekBoosts = append(thisWeekBoosts, boost)
+		if inPeriod(boost.Time) {
+			thisPeriodBoosts = append(thisPeriodBoosts, boost)
 		}
 
 		// boosts lasts until the next roll
@@ -66,11 +106,36 @@ func buildHistory(allRolls []*rolls.Roll, allBoosts []*boosts.Boost, now time.Ti
 		}
 	}
 
+	thisPeriodVetoes := []*vetoes.Veto{}
+	activeVetoes := map[places.ID]bool{}
+	for _, veto := range allVetoes {
+		if inPeriod(veto.Time) {
+			thisPeriodVetoes = append(thisPeriodVetoes, veto)
+		}
+
+		// vetoes last until the end of the day of the next roll, so that rerolls respect them too
+		var nextRoll *rolls.Roll
+		for _, roll := range allRolls {
+			if !roll.Time.After(veto.Time) {
+				continue
+			}
+			if nextRoll == nil || roll.Time.Before(nextRoll.Time) {
+				nextRoll = roll
+			}
+		}
+		if nextRoll == nil || dateOf(nextRoll.Time.In(now.Location())) == dateOf(now) {
+			activeVetoes[veto.PlaceID] = true
+		}
+	}
+
 	return &rollsHistory{
-		RollsPerWeekday: rollsPerWeekday,
-		ThisWeekBoosts:  thisWeekBoosts,
-		LastRolled:      lastRolled,
-		ActiveBoosts:    activeBoosts,
+		Settings:          settings,
+		ThisPeriodRollsBy: rollsByDate,
+		ThisPeriodBoosts:  thisPeriodBoosts,
+		ThisPeriodVetoes:  thisPeriodVetoes,
+		LastRolled:        lastRolled,
+		ActiveBoosts:      activeBoosts,
+		ActiveVetoes:      activeVetoes,
 	}
 }
 
@@ -82,9 +147,16 @@ func (h *rollsHistory) CanBoost(userID users.ID, now time.Time) error {
 	return nil
 }
 
+func (h *rollsHistory) CanVeto(userID users.ID, now time.Time) error {
+	if h.pointsLeft(userID) <= 0 {
+		return ErrNoPoints
+	}
+
+	return nil
+}
+
 func (h *rollsHistory) CanRoll(userID users.ID, now time.Time) error {
-	firstRollToday := len(h.RollsPerWeekday[now.Weekday()]) == 0
-	if firstRollToday {
+	if h.freeRollAvailable(now) {
 		// anyone can make the first roll a day
 		return nil
 	}
@@ -96,24 +168,55 @@ func (h *rollsHistory) CanRoll(userID users.ID, now time.Time) error {
 	return nil
 }
 
+func (h *rollsHistory) freeRollAvailable(now time.Time) bool {
+	today := dateOf(now.In(h.Settings.Location()))
+	firstRollToday := len(h.ThisPeriodRollsBy[today]) == 0
+	return firstRollToday && h.Settings.FreeDailyRoll
+}
+
+// Quota returns points left for the user, and when they are reset.
+func (h *rollsHistory) Quota(userID users.ID, now time.Time) *Quota {
+	points := h.pointsLeft(userID)
+	if points < 0 {
+		// rules can be changed in the middle of a period
+		points = 0
+	}
+	return &Quota{
+		Points:   points,
+		FreeRoll: h.freeRollAvailable(now),
+		ResetsAt: periodEnd(now.In(h.Settings.Location()), h.Settings.Period),
+	}
+}
+
 func (h *rollsHistory) pointsLeft(userID users.ID) int {
-	points := totalPoints
+	points := h.Settings.Points
 
-	for _, boost := range h.ThisWeekBoosts {
+	for _, boost := range h.ThisPeriodBoosts {
 		if boost.UserID == userID {
 			// Boost costs one point
 			points--
 		}
 	}
 
-	for _, rolls := range h.RollsPerWeekday {
-		if len(rolls) <= 1 {
+	for _, veto := range h.ThisPeriodVetoes {
+		if veto.UserID == userID {
+			// Veto costs one point
+			points--
+		}
+	}
+
+	for _, rolls := range h.ThisPeriodRollsBy {
+		if h.Settings.FreeDailyRoll {
 			// first roll a day is always allowed
-			continue
+			rolls = rolls[1:]
 		}
 
-		for _, roll := range rolls[1:] {
-			// consecutive rolls a day are rerolls, only one reroll per week is allowed
+		for _, roll := range rolls {
+			if roll.PollID != "" {
+				// poll results are agreed on by everyone, they are free
+				continue
+			}
+			// consecutive rolls a day are rerolls, they cost one point
 			if roll.UserID == userID {
 				points--
 			}
@@ -123,29 +226,55 @@ func (h *rollsHistory) pointsLeft(userID users.ID) int {
 	return points
 }
 
+// validateRules returns an error if the points rules in the settings don't make sense.
+func validateRules(settings rooms.Settings) error {
+	if settings.Points < 0 {
+		return fmt.Errorf("points must not be negative: %w", ErrInvalid)
+	}
+	if settings.UndoMinutes < 0 || !isFinite(settings.UndoMinutes) {
+		return fmt.Errorf("undo window must not be negative: %w", ErrInvalid)
+	}
+	if settings.ReminderMinutes < 0 {
+		return fmt.Errorf("reminder must not be after the roll: %w", ErrInvalid)
+	}
+	if settings.BoostMultiplier < 1 || !isFinite(settings.BoostMultiplier) {
+		return fmt.Errorf("boost multiplier must be at least 1: %w", ErrInvalid)
+	}
+	if settings.Timezone != "" {
+		if _, err := time.LoadLocation(settings.Timezone); err != nil {
+			return fmt.Errorf("unknown time zone '%s': %w", settings.Timezone, ErrInvalid)
+		}
+	}
+	switch settings.Period {
+	case rooms.PeriodWeek, rooms.PeriodMonth:
+		return nil
+	default:
+		return fmt.Errorf("unknown period '%s': %w", settings.Period, ErrInvalid)
+	}
+}
+
+// isFinite returns false for NaN and infinities, that make weights meaningless.
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
 // getWeights returns a list of weights for places to choose from.
 // higher weights means higher chance of choosing a place.
 //
-// weight are distributed in a way so that the most recent rolls get the lowest weight.
-func (h *rollsHistory) getWeights(allPlaces map[places.ID]*places.Place, now time.Time) map[places.ID]float64 {
+// base weights are defined by the strategy, and multiplied for every active boost. vetoed places get zero weight.
+func (h *rollsHistory) getWeights(allPlaces map[places.ID]*places.Place, strategy WeightStrategy, now time.Time) map[places.ID]float64 {
 	placesTotal := len(allPlaces)
 	weights := make(map[places.ID]float64, placesTotal)
 	for placeID, place := range allPlaces {
-		lastRolledAt, wasRolled := h.LastRolled[place.ID]
-		if !wasRolled {
-			weights[placeID] = float64(placesTotal)
-		} else {
-			rolledAgo := now.Sub(lastRolledAt)
-			rolledDaysAgo := int(math.Floor(rolledAgo.Hours() / hoursInADay))
-			if rolledDaysAgo >= placesTotal {
-				weights[placeID] = float64(placesTotal)
-			} else {
-				weights[placeID] = float64(rolledDaysAgo) + 1
-			}
+		if h.ActiveVetoes[place.ID] {
+			weights[placeID] = 0
+			continue
 		}
 
+		weights[placeID] = strategy.Weight(h.LastRolled[place.ID], placesTotal, now)
+
 		for i := 0; i < h.ActiveBoosts[place.ID]; i++ {
-			weights[placeID] *= boostMultiplier
+			weights[placeID] *= h.Settings.BoostMultiplier
 		}
 	}
 	return weights
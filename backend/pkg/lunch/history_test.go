@@ -7,6 +7,8 @@ import (
 	"lunch/pkg/lunch/boosts"
 	"lunch/pkg/lunch/places"
 	"lunch/pkg/lunch/rolls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/vetoes"
 	"lunch/pkg/users"
 )
 
@@ -55,15 +57,18 @@ func TestHistory(t *testing.T) {
 				users.ID("2"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts: []*boosts.Boost{
+				Settings:         rooms.DefaultSettings(),
+				ThisPeriodVetoes: []*vetoes.Veto{},
+				ActiveVetoes:     map[places.ID]bool{},
+				ThisPeriodBoosts: []*boosts.Boost{
 					{
 						UserID:  users.ID("1"),
 						PlaceID: places.ID("1"),
 						Time:    today,
 					},
 				},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{
-					time.Monday: {
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{
+					dateOf(today): {
 						{
 							UserID:  users.ID("1"),
 							PlaceID: places.ID("1"),
@@ -107,15 +112,18 @@ func TestHistory(t *testing.T) {
 				users.ID("2"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts: []*boosts.Boost{
+				Settings:         rooms.DefaultSettings(),
+				ThisPeriodVetoes: []*vetoes.Veto{},
+				ActiveVetoes:     map[places.ID]bool{},
+				ThisPeriodBoosts: []*boosts.Boost{
 					{
 						UserID:  users.ID("1"),
 						PlaceID: places.ID("1"),
 						Time:    today.Add(time.Minute),
 					},
 				},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{
-					time.Monday: {
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{
+					dateOf(today): {
 						{
 							UserID:  users.ID("1"),
 							PlaceID: places.ID("1"),
@@ -159,9 +167,12 @@ func TestHistory(t *testing.T) {
 				users.ID("2"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts: []*boosts.Boost{},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{
-					time.Monday: {
+				Settings:         rooms.DefaultSettings(),
+				ThisPeriodVetoes: []*vetoes.Veto{},
+				ActiveVetoes:     map[places.ID]bool{},
+				ThisPeriodBoosts: []*boosts.Boost{},
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{
+					dateOf(today): {
 						{
 							UserID:  users.ID("1"),
 							PlaceID: places.ID("1"),
@@ -203,15 +214,18 @@ func TestHistory(t *testing.T) {
 				users.ID("2"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts: []*boosts.Boost{
+				Settings:         rooms.DefaultSettings(),
+				ThisPeriodVetoes: []*vetoes.Veto{},
+				ActiveVetoes:     map[places.ID]bool{},
+				ThisPeriodBoosts: []*boosts.Boost{
 					{
 						UserID:  users.ID("1"),
 						PlaceID: places.ID("1"),
 						Time:    today,
 					},
 				},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{},
-				LastRolled:      map[places.ID]time.Time{},
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{},
+				LastRolled:        map[places.ID]time.Time{},
 				ActiveBoosts: map[places.ID]int{
 					places.ID("1"): 1,
 				},
@@ -240,9 +254,12 @@ func TestHistory(t *testing.T) {
 				users.ID("2"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts: []*boosts.Boost{},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{
-					time.Monday: {
+				Settings:         rooms.DefaultSettings(),
+				ThisPeriodVetoes: []*vetoes.Veto{},
+				ActiveVetoes:     map[places.ID]bool{},
+				ThisPeriodBoosts: []*boosts.Boost{},
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{
+					dateOf(today): {
 						{
 							UserID:  users.ID("1"),
 							PlaceID: places.ID("1"),
@@ -269,10 +286,13 @@ func TestHistory(t *testing.T) {
 				users.ID("1"): 1,
 			},
 			expected: &rollsHistory{
-				ThisWeekBoosts:  []*boosts.Boost{},
-				RollsPerWeekday: map[time.Weekday][]*rolls.Roll{},
-				LastRolled:      map[places.ID]time.Time{},
-				ActiveBoosts:    map[places.ID]int{},
+				Settings:          rooms.DefaultSettings(),
+				ThisPeriodVetoes:  []*vetoes.Veto{},
+				ActiveVetoes:      map[places.ID]bool{},
+				ThisPeriodBoosts:  []*boosts.Boost{},
+				ThisPeriodRollsBy: map[date][]*rolls.Roll{},
+				LastRolled:        map[places.ID]time.Time{},
+				ActiveBoosts:      map[places.ID]int{},
 			},
 		},
 	}
@@ -282,7 +302,7 @@ func TestHistory(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			actual := buildHistory(tc.rolls, tc.boosts, tc.time)
+			actual := buildHistory(tc.rolls, tc.boosts, nil, rooms.DefaultSettings(), tc.time)
 			assertEqual(t, tc.expected, actual)
 
 			for uID, expected := range tc.canBoost {
@@ -299,3 +319,286 @@ func TestHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestHistory_rules(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+	oneDay := 24 * time.Hour
+
+	threePointsPerWeek := rooms.DefaultSettings()
+	threePointsPerWeek.Points = 3
+
+	monthly := rooms.DefaultSettings()
+	monthly.Period = rooms.PeriodMonth
+
+	noFreeRolls := rooms.DefaultSettings()
+	noFreeRolls.FreeDailyRoll = false
+
+	testCases := []struct {
+		name       string
+		settings   rooms.Settings
+		boosts     []*boosts.Boost
+		rolls      []*rolls.Roll
+		time       time.Time
+		canRoll    error
+		pointsLeft int
+	}{
+		{
+			name:     "three points per week, two spent",
+			settings: threePointsPerWeek,
+			boosts: []*boosts.Boost{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+			},
+			rolls: []*rolls.Roll{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today.Add(time.Minute)},
+			},
+			time:       today.Add(time.Hour),
+			canRoll:    nil,
+			pointsLeft: 1,
+		},
+		{
+			name:     "monthly period, point spent last week",
+			settings: monthly,
+			boosts: []*boosts.Boost{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+			},
+			time:       today.Add(7 * oneDay),
+			canRoll:    nil,
+			pointsLeft: 0,
+		},
+		{
+			name:     "monthly period, point spent last month",
+			settings: monthly,
+			boosts: []*boosts.Boost{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+			},
+			time:       today.Add(30 * oneDay),
+			canRoll:    nil,
+			pointsLeft: 1,
+		},
+		{
+			name:     "no free rolls, first roll costs a point",
+			settings: noFreeRolls,
+			rolls: []*rolls.Roll{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+			},
+			time:       today.Add(oneDay),
+			canRoll:    ErrNoPoints,
+			pointsLeft: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := buildHistory(tc.rolls, tc.boosts, nil, tc.settings, tc.time)
+			assertEqual(t, tc.canRoll, actual.CanRoll(users.ID("1"), tc.time))
+			assertEqual(t, tc.pointsLeft, actual.pointsLeft(users.ID("1")))
+		})
+	}
+}
+
+func TestHistory_timezones(t *testing.T) {
+	t.Parallel()
+
+	// server runs in UTC, office is in Stockholm
+	stockholm := rooms.DefaultSettings()
+	stockholm.Timezone = "Europe/Stockholm"
+
+	stockholmMonthly := stockholm
+	stockholmMonthly.Period = rooms.PeriodMonth
+
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name       string
+		settings   rooms.Settings
+		boosts     []*boosts.Boost
+		rolls      []*rolls.Roll
+		time       time.Time
+		canRoll    error
+		pointsLeft int
+	}{
+		{
+			name:     "spring forward, rolls after local midnight are the same day",
+			settings: stockholm,
+			rolls: []*rolls.Roll{
+				// 2021-03-28 00:30 CET
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.March, 27, 23, 30)},
+				// 2021-03-28 23:00 CEST
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.March, 28, 21, 0)},
+			},
+			time:       utc(2021, time.March, 28, 21, 30),
+			canRoll:    ErrNoPoints,
+			pointsLeft: 0,
+		},
+		{
+			name:     "fall back, 25 hours day is one day",
+			settings: stockholm,
+			rolls: []*rolls.Roll{
+				// 2021-10-31 00:10 CEST
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.October, 30, 22, 10)},
+				// 2021-10-31 23:50 CET
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.October, 31, 22, 50)},
+			},
+			time:       utc(2021, time.October, 31, 22, 55),
+			canRoll:    ErrNoPoints,
+			pointsLeft: 0,
+		},
+		{
+			name:     "week starts at local midnight on monday",
+			settings: stockholm,
+			boosts: []*boosts.Boost{
+				// 2021-03-28 23:30 CEST, sunday
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.March, 28, 21, 30)},
+			},
+			rolls: []*rolls.Roll{
+				// 2021-03-28 23:40 CEST, sunday
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.March, 28, 21, 40)},
+			},
+			// 2021-03-29 00:30 CEST, monday
+			time:       utc(2021, time.March, 28, 22, 30),
+			canRoll:    nil,
+			pointsLeft: 1,
+		},
+		{
+			name:     "week spans new year",
+			settings: stockholm,
+			boosts: []*boosts.Boost{
+				// 2020-12-31 23:30 CET, thursday
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2020, time.December, 31, 22, 30)},
+			},
+			// 2021-01-01 10:00 CET, friday
+			time:       utc(2021, time.January, 1, 9, 0),
+			canRoll:    nil,
+			pointsLeft: 0,
+		},
+		{
+			name:     "new week after new year",
+			settings: stockholm,
+			boosts: []*boosts.Boost{
+				// 2021-01-03 23:30 CET, sunday
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2021, time.January, 3, 22, 30)},
+			},
+			// 2021-01-04 00:30 CET, monday
+			time:       utc(2021, time.January, 3, 23, 30),
+			canRoll:    nil,
+			pointsLeft: 1,
+		},
+		{
+			name:     "new month starts at local midnight on new year",
+			settings: stockholmMonthly,
+			boosts: []*boosts.Boost{
+				// 2020-12-31 23:30 CET
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2020, time.December, 31, 22, 30)},
+			},
+			// 2021-01-01 00:30 CET
+			time:       utc(2020, time.December, 31, 23, 30),
+			canRoll:    nil,
+			pointsLeft: 1,
+		},
+		{
+			name:     "boost after local midnight on new year belongs to january",
+			settings: stockholmMonthly,
+			boosts: []*boosts.Boost{
+				// 2021-01-01 00:30 CET
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: utc(2020, time.December, 31, 23, 30)},
+			},
+			// 2021-01-01 10:00 CET
+			time:       utc(2021, time.January, 1, 9, 0),
+			canRoll:    nil,
+			pointsLeft: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := buildHistory(tc.rolls, tc.boosts, nil, tc.settings, tc.time)
+			assertEqual(t, tc.canRoll, actual.CanRoll(users.ID("1"), tc.time))
+			assertEqual(t, tc.pointsLeft, actual.pointsLeft(users.ID("1")))
+		})
+	}
+}
+
+func TestHistory_vetoes(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 7, 9, 0, 0, 0, time.UTC) // Tuesday
+	oneDay := 24 * time.Hour
+
+	testCases := []struct {
+		name         string
+		vetoes       []*vetoes.Veto
+		rolls        []*rolls.Roll
+		time         time.Time
+		activeVetoes map[places.ID]bool
+		pointsLeft   int
+	}{
+		{
+			name: "veto lasts until the next roll",
+			vetoes: []*vetoes.Veto{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today.Add(-oneDay)},
+			},
+			time:         today,
+			activeVetoes: map[places.ID]bool{places.ID("1"): true},
+			pointsLeft:   0,
+		},
+		{
+			name: "veto lasts for rerolls on the same day",
+			vetoes: []*vetoes.Veto{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today},
+			},
+			rolls: []*rolls.Roll{
+				{UserID: users.ID("2"), PlaceID: places.ID("2"), Time: today.Add(time.Minute)},
+			},
+			time:         today.Add(time.Hour),
+			activeVetoes: map[places.ID]bool{places.ID("1"): true},
+			pointsLeft:   0,
+		},
+		{
+			name: "veto expires the day after the next roll",
+			vetoes: []*vetoes.Veto{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today.Add(-oneDay)},
+			},
+			rolls: []*rolls.Roll{
+				{UserID: users.ID("2"), PlaceID: places.ID("2"), Time: today.Add(-oneDay).Add(time.Minute)},
+			},
+			time:         today,
+			activeVetoes: map[places.ID]bool{},
+			pointsLeft:   0,
+		},
+		{
+			name: "roll before the veto doesn't count",
+			vetoes: []*vetoes.Veto{
+				{UserID: users.ID("1"), PlaceID: places.ID("1"), Time: today.Add(-oneDay)},
+			},
+			rolls: []*rolls.Roll{
+				{UserID: users.ID("2"), PlaceID: places.ID("2"), Time: today.Add(-oneDay).Add(-time.Minute)},
+			},
+			time:         today,
+			activeVetoes: map[places.ID]bool{places.ID("1"): true},
+			pointsLeft:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := buildHistory(tc.rolls, nil, tc.vetoes, rooms.DefaultSettings(), tc.time)
+			assertEqual(t, tc.activeVetoes, actual.ActiveVetoes)
+			assertEqual(t, tc.pointsLeft, actual.pointsLeft(users.ID("1")))
+			assertEqual(t, ErrNoPoints, actual.CanVeto(users.ID("1"), tc.time))
+		})
+	}
+}
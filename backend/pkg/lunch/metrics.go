@@ -0,0 +1,69 @@
+package lunch
+
+import "sync"
+
+// Metrics are counters of events handled by subscribers, by event type.
+type Metrics struct {
+	// Queued is how many events are waiting in subscriber queues, or being handled.
+	Queued map[string]int64 `json:"queued"`
+	// Handled is how many events were handled successfully.
+	Handled map[string]int64 `json:"handled"`
+	// Retried is how many times a handler failed and was called again.
+	Retried map[string]int64 `json:"retried"`
+	// Failed is how many events were not handled after all attempts.
+	Failed map[string]int64 `json:"failed"`
+	// Dropped is how many events were published after shutdown, or to a full subscriber queue.
+	Dropped map[string]int64 `json:"dropped"`
+}
+
+type metrics struct {
+	guard   *sync.Mutex
+	current *Metrics
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		guard: &sync.Mutex{},
+		current: &Metrics{
+			Queued:  map[string]int64{},
+			Handled: map[string]int64{},
+			Retried: map[string]int64{},
+			Failed:  map[string]int64{},
+			Dropped: map[string]int64{},
+		},
+	}
+}
+
+func (m *metrics) queued(t Type)   { m.inc(m.current.Queued, t, 1) }
+func (m *metrics) dequeued(t Type) { m.inc(m.current.Queued, t, -1) }
+func (m *metrics) handled(t Type)  { m.inc(m.current.Handled, t, 1) }
+func (m *metrics) retried(t Type)  { m.inc(m.current.Retried, t, 1) }
+func (m *metrics) failed(t Type)   { m.inc(m.current.Failed, t, 1) }
+func (m *metrics) dropped(t Type)  { m.inc(m.current.Dropped, t, 1) }
+
+func (m *metrics) inc(counters map[string]int64, t Type, delta int64) {
+	m.guard.Lock()
+	counters[t.String()] += delta
+	m.guard.Unlock()
+}
+
+func (m *metrics) snapshot() *Metrics {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+
+	return &Metrics{
+		Queued:  copyCounters(m.current.Queued),
+		Handled: copyCounters(m.current.Handled),
+		Retried: copyCounters(m.current.Retried),
+		Failed:  copyCounters(m.current.Failed),
+		Dropped: copyCounters(m.current.Dropped),
+	}
+}
+
+func copyCounters(counters map[string]int64) map[string]int64 {
+	result := make(map[string]int64, len(counters))
+	for k, v := range counters {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package lunch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"lunch/pkg/lunch/notifications"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// UpdateNotifications changes how the user wants to be notified about what happens in the room.
+// Only room members can change their preferences.
+func (r *Roller) UpdateNotifications(ctx context.Context, roomID rooms.ID, level notifications.Level, quietFrom, quietTo string, now time.Time) (*notifications.Preferences, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !room.MemberIDs[user.ID] {
+		return nil, fmt.Errorf("only room members can be notified: %w", ErrNotAllowed)
+	}
+
+	preferences, err := notifications.New(roomID, user.ID, level, quietFrom, quietTo, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, ErrInvalid)
+	}
+
+	if err := r.notificationsStore.Update(ctx, preferences); err != nil {
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	return preferences, nil
+}
+
+// GetNotifications returns how the user wants to be notified about what happens in the room.
+func (r *Roller) GetNotifications(ctx context.Context, roomID rooms.ID) (*notifications.Preferences, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	allPreferences, err := r.notificationsStore.Preferences(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	if preferences, ok := allPreferences[user.ID]; ok {
+		return preferences, nil
+	}
+	return notifications.Default(roomID, user.ID), nil
+}
+
+// Recipients returns room members who want to be notified about the topic now.
+// Members who never changed their preferences are notified about everything.
+func (r *Roller) Recipients(ctx context.Context, roomID rooms.ID, topic notifications.Topic, now time.Time) ([]*users.User, error) {
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	allPreferences, err := r.notificationsStore.Preferences(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	loc := room.Settings.Location()
+	result := []*users.User{}
+	for _, member := range room.Members {
+		if member == nil {
+			continue
+		}
+		preferences, ok := allPreferences[member.ID]
+		if !ok {
+			preferences = notifications.Default(roomID, member.ID)
+		}
+		if preferences.Wants(topic, now, loc) {
+			result = append(result, member)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// Level is what a user wants to be notified about.
+type Level string
+
+const (
+	// LevelAll notifies about rolls, boosts, vetoes, new places and reminders.
+	LevelAll Level = "all"
+	// LevelRolls notifies only about rolls and reminders before them.
+	LevelRolls Level = "rolls"
+	// LevelNone turns notifications off.
+	LevelNone Level = "none"
+)
+
+// Topic is what a notification is about.
+type Topic string
+
+const (
+	TopicRolls Topic = "rolls"
+	TopicOther Topic = "other"
+)
+
+// Preferences are how a user wants to be notified about what happens in a room.
+type Preferences struct {
+	RoomID rooms.ID `json:"roomId"`
+	UserID users.ID `json:"userId"`
+	Level  Level    `json:"level"`
+	// QuietFrom and QuietTo are local times of the day in the room time zone, like 18:00 and 09:00,
+	// between which nothing is sent. Empty means no quiet hours.
+	QuietFrom string    `json:"quietFrom,omitempty"`
+	QuietTo   string    `json:"quietTo,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Default returns preferences of a member who never changed them.
+func Default(roomID rooms.ID, userID users.ID) *Preferences {
+	return &Preferences{
+		RoomID: roomID,
+		UserID: userID,
+		Level:  LevelAll,
+	}
+}
+
+func New(roomID rooms.ID, userID users.ID, level Level, quietFrom, quietTo string, now time.Time) (*Preferences, error) {
+	switch level {
+	case LevelAll, LevelRolls, LevelNone:
+	default:
+		return nil, fmt.Errorf("'%s' must be one of all, rolls or none", level)
+	}
+	if (quietFrom == "") != (quietTo == "") {
+		return nil, fmt.Errorf("quiet hours must have both start and end")
+	}
+	if quietFrom != "" {
+		if _, err := parseClock(quietFrom); err != nil {
+			return nil, err
+		}
+		if _, err := parseClock(quietTo); err != nil {
+			return nil, err
+		}
+	}
+	return &Preferences{
+		RoomID:    roomID,
+		UserID:    userID,
+		Level:     level,
+		QuietFrom: quietFrom,
+		QuietTo:   quietTo,
+		Time:      now,
+	}, nil
+}
+
+// Wants returns true if the user wants to be notified about the topic at the given time,
+// with the local time taken in loc.
+func (p *Preferences) Wants(topic Topic, now time.Time, loc *time.Location) bool {
+	switch p.Level {
+	case LevelNone:
+		return false
+	case LevelRolls:
+		if topic != TopicRolls {
+			return false
+		}
+	}
+	return !p.quiet(now.In(loc))
+}
+
+func (p *Preferences) quiet(now time.Time) bool {
+	if p.QuietFrom == "" || p.QuietTo == "" {
+		return false
+	}
+	from, err := parseClock(p.QuietFrom)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(p.QuietTo)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	// quiet over midnight
+	return minute >= from || minute < to
+}
+
+// parseClock returns minutes since midnight of a time like 18:00.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' must be a time like 18:00", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
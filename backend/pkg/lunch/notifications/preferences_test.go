@@ -0,0 +1,122 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWants(t *testing.T) {
+	t.Parallel()
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evening := time.Date(2021, time.September, 6, 19, 0, 0, 0, stockholm)
+	noon := time.Date(2021, time.September, 6, 12, 0, 0, 0, stockholm)
+
+	testCases := []struct {
+		name        string
+		preferences *Preferences
+		topic       Topic
+		now         time.Time
+		loc         *time.Location
+		expected    bool
+	}{
+		{
+			name:        "default",
+			preferences: Default("room", "user"),
+			topic:       TopicOther,
+			now:         evening,
+			loc:         stockholm,
+			expected:    true,
+		},
+		{
+			name:        "rolls only, roll",
+			preferences: &Preferences{Level: LevelRolls},
+			topic:       TopicRolls,
+			now:         noon,
+			loc:         stockholm,
+			expected:    true,
+		},
+		{
+			name:        "rolls only, boost",
+			preferences: &Preferences{Level: LevelRolls},
+			topic:       TopicOther,
+			now:         noon,
+			loc:         stockholm,
+			expected:    false,
+		},
+		{
+			name:        "none",
+			preferences: &Preferences{Level: LevelNone},
+			topic:       TopicRolls,
+			now:         noon,
+			loc:         stockholm,
+			expected:    false,
+		},
+		{
+			name:        "quiet over midnight",
+			preferences: &Preferences{Level: LevelAll, QuietFrom: "18:00", QuietTo: "09:00"},
+			topic:       TopicRolls,
+			now:         evening,
+			loc:         stockholm,
+			expected:    false,
+		},
+		{
+			name:        "not quiet over midnight",
+			preferences: &Preferences{Level: LevelAll, QuietFrom: "18:00", QuietTo: "09:00"},
+			topic:       TopicRolls,
+			now:         noon,
+			loc:         stockholm,
+			expected:    true,
+		},
+		{
+			name:        "quiet during the day",
+			preferences: &Preferences{Level: LevelAll, QuietFrom: "11:00", QuietTo: "13:00"},
+			topic:       TopicRolls,
+			now:         noon,
+			loc:         stockholm,
+			expected:    false,
+		},
+		{
+			name:        "quiet hours in the room time zone",
+			preferences: &Preferences{Level: LevelAll, QuietFrom: "11:00", QuietTo: "13:00"},
+			topic:       TopicRolls,
+			now:         noon,
+			loc:         time.UTC,
+			expected:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.preferences.Wants(tc.topic, tc.now, tc.loc); got != tc.expected {
+				t.Errorf("\nexpected: %+v\ngot: %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	if _, err := New("room", "user", "some", "", "", now); err == nil {
+		t.Error("expected an error for unknown level")
+	}
+	if _, err := New("room", "user", LevelAll, "18:00", "", now); err == nil {
+		t.Error("expected an error for half of quiet hours")
+	}
+	if _, err := New("room", "user", LevelAll, "25:00", "09:00", now); err == nil {
+		t.Error("expected an error for invalid time")
+	}
+	if _, err := New("room", "user", LevelRolls, "18:00", "09:00", now); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
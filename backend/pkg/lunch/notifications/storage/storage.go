@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/notifications"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+const (
+	preferencesUpdated events.Type = "notifications/updated"
+)
+
+type Storage struct {
+	eventsStorage events.Storage
+}
+
+func New(eventsStorage events.Storage) *Storage {
+	return &Storage{
+		eventsStorage: eventsStorage,
+	}
+}
+
+// Update replaces preferences of the user in the room.
+func (s *Storage) Update(ctx context.Context, preferences *notifications.Preferences) error {
+	event := &events.Event{
+		UserID:    preferences.UserID,
+		RoomID:    preferences.RoomID,
+		Type:      preferencesUpdated,
+		Timestamp: events.UnixNanoTime(preferences.Time),
+	}
+	if err := event.MarshalPayload(preferences); err != nil {
+		return err
+	}
+	return s.eventsStorage.Create(ctx, event)
+}
+
+// Preferences returns preferences of users in the room who changed them.
+func (s *Storage) Preferences(ctx context.Context, roomID rooms.ID) (map[users.ID]*notifications.Preferences, error) {
+	ee, err := s.eventsStorage.ByRoomID(ctx, roomID, preferencesUpdated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+
+	result := map[users.ID]*notifications.Preferences{}
+	for _, event := range ee {
+		preferences := &notifications.Preferences{}
+		if err := event.UnmarshalPayload(preferences); err != nil {
+			return nil, err
+		}
+		preferences.RoomID = event.RoomID
+		preferences.UserID = event.UserID
+		preferences.Time = time.Time(event.Timestamp)
+		result[event.UserID] = preferences
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package lunch
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/notifications"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestRecipients(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	owner := testUser()
+	member := testUser()
+	stranger := testUser()
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	usersStore := storage_users.NewBolt(bolt)
+	for _, u := range []*users.User{owner, member, stranger} {
+		assertNoError(t, usersStore.Create(testContext(u), u))
+	}
+	roller := New(events.NewBoltStorage(bolt), usersStore)
+
+	assertNoError(t, roller.CreateRoom(testContext(owner), "room"))
+	rr, err := roller.ListRooms(testContext(owner))
+	assertNoError(t, err)
+	roomID := rr[0].ID
+	assertNoError(t, roller.JoinRoom(testContext(member), roomID))
+
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "UTC"
+	assertNoError(t, roller.UpdateRoomSettings(testContext(owner), roomID, settings))
+
+	_, err = roller.UpdateNotifications(testContext(stranger), roomID, notifications.LevelAll, "", "", today)
+	assertError(t, ErrNotAllowed, err)
+
+	_, err = roller.UpdateNotifications(testContext(member), roomID, "some", "", "", today)
+	assertError(t, ErrInvalid, err)
+
+	_, err = roller.UpdateNotifications(testContext(member), roomID, notifications.LevelRolls, "", "", today)
+	assertNoError(t, err)
+
+	recipients, err := roller.Recipients(testContext(owner), roomID, notifications.TopicRolls, today)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(recipients))
+
+	recipients, err = roller.Recipients(testContext(owner), roomID, notifications.TopicOther, today)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(recipients))
+	assertEqual(t, owner.ID, recipients[0].ID)
+
+	_, err = roller.UpdateNotifications(testContext(owner), roomID, notifications.LevelAll, "11:00", "13:00", today.Add(time.Second))
+	assertNoError(t, err)
+
+	recipients, err = roller.Recipients(testContext(owner), roomID, notifications.TopicRolls, today)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(recipients))
+	assertEqual(t, member.ID, recipients[0].ID)
+
+	preferences, err := roller.GetNotifications(testContext(owner), roomID)
+	assertNoError(t, err)
+	assertEqual(t, "11:00", preferences.QuietFrom)
+}
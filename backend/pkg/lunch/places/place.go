@@ -18,6 +18,23 @@ type Place struct {
 	UserID    users.ID  `json:"userId"`
 	RoomID    rooms.ID  `json:"roomId"`
 	IsDeleted bool      `json:"-"`
+	Metadata  Metadata  `json:"metadata"`
+}
+
+const (
+	MinPriceLevel = 0
+	MaxPriceLevel = 4
+)
+
+// Metadata is optional information about a place.
+type Metadata struct {
+	Address string   `json:"address,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// PriceLevel is from 1 (cheap) to 4 (expensive), 0 means unknown.
+	PriceLevel int  `json:"priceLevel,omitempty"`
+	Vegetarian bool `json:"vegetarian,omitempty"`
+	Vegan      bool `json:"vegan,omitempty"`
 }
 
 func NewPlace(roomID rooms.ID, userID users.ID, name string) *Place {
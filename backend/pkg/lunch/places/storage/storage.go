@@ -19,6 +19,17 @@ const (
 	placeCreated  events.Type = "places/created"
 	placeDeleted  events.Type = "places/deleted"
 	placeRestored events.Type = "places/restored"
+	placeUpdated  events.Type = "places/updated"
+)
+
+// Change is what an event did to a place.
+type Change string
+
+const (
+	ChangeCreated  Change = "created"
+	ChangeDeleted  Change = "deleted"
+	ChangeRestored Change = "restored"
+	ChangeUpdated  Change = "updated"
 )
 
 type Storage struct {
@@ -51,15 +62,35 @@ func (s *Storage) Delete(ctx context.Context, userID users.ID, place *places.Pla
 	})
 }
 
+// Update stores the new name and metadata of the place.
+func (s *Storage) Update(ctx context.Context, userID users.ID, place *places.Place) error {
+	event := &events.Event{
+		UserID:    userID,
+		RoomID:    place.RoomID,
+		Timestamp: events.UnixNanoTime(time.Now()),
+		Type:      placeUpdated,
+		PlaceID:   place.ID,
+		Name:      place.Name,
+	}
+	if err := event.MarshalPayload(place.Metadata); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
 func (s *Storage) Create(ctx context.Context, place *places.Place) error {
-	return s.storage.Create(ctx, &events.Event{
+	event := &events.Event{
 		UserID:    place.UserID,
 		RoomID:    place.RoomID,
 		Timestamp: events.UnixNanoTime(place.Time),
 		Type:      placeCreated,
 		PlaceID:   place.ID,
 		Name:      place.Name,
-	})
+	}
+	if err := event.MarshalPayload(place.Metadata); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
 }
 
 func (s *Storage) Place(ctx context.Context, roomID rooms.ID, placeID places.ID) (*places.Place, error) {
@@ -75,7 +106,7 @@ func (s *Storage) Place(ctx context.Context, roomID rooms.ID, placeID places.ID)
 }
 
 func (s *Storage) Places(ctx context.Context, roomID rooms.ID) (map[places.ID]*places.Place, error) {
-	events, err := s.storage.ByRoomID(ctx, roomID, placeCreated, placeDeleted, placeRestored)
+	events, err := s.storage.ByRoomID(ctx, roomID, placeCreated, placeDeleted, placeRestored, placeUpdated)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
@@ -84,20 +115,52 @@ func (s *Storage) Places(ctx context.Context, roomID rooms.ID) (map[places.ID]*p
 	})
 	result := make(map[places.ID]*places.Place)
 	for _, event := range events {
+		place, ok := result[event.PlaceID]
+		if !ok && event.Type != placeCreated {
+			// nothing to change if the place was never created
+			continue
+		}
 		switch event.Type {
 		case placeCreated:
-			result[event.PlaceID] = &places.Place{
+			place = &places.Place{
 				ID:     event.PlaceID,
 				Name:   event.Name,
 				UserID: event.UserID,
 				Time:   time.Time(event.Timestamp),
 				RoomID: event.RoomID,
 			}
+			if err := event.UnmarshalPayload(&place.Metadata); err != nil {
+				return nil, err
+			}
+			result[event.PlaceID] = place
+		case placeUpdated:
+			metadata := places.Metadata{}
+			if err := event.UnmarshalPayload(&metadata); err != nil {
+				return nil, err
+			}
+			place.Name = event.Name
+			place.Metadata = metadata
 		case placeDeleted:
-			result[event.PlaceID].IsDeleted = true
+			place.IsDeleted = true
 		case placeRestored:
-			result[event.PlaceID].IsDeleted = false
+			place.IsDeleted = false
 		}
 	}
 	return result, nil
 }
+
+// PlaceChange returns what the event did to the place with the event place id.
+func PlaceChange(event *events.Event) (Change, bool) {
+	switch event.Type {
+	case placeCreated:
+		return ChangeCreated, true
+	case placeDeleted:
+		return ChangeDeleted, true
+	case placeRestored:
+		return ChangeRestored, true
+	case placeUpdated:
+		return ChangeUpdated, true
+	default:
+		return "", false
+	}
+}
@@ -60,6 +60,50 @@ func Test_PlaceDeleted(t *testing.T) {
 	assertEqual(t, true, deletedPlace.IsDeleted)
 }
 
+func Test_PlaceUpdated(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	storage := New(events.NewBoltStorage(bolt))
+
+	place := places.NewPlace(rooms.ID("1"), users.ID("1"), "tset")
+	assertNoError(t, storage.Create(context.Background(), place))
+
+	place.Name = "test"
+	place.Metadata = places.Metadata{
+		URL:        "https://example.com",
+		Tags:       []string{"thai"},
+		PriceLevel: 2,
+		Vegan:      true,
+	}
+	assertNoError(t, storage.Update(context.Background(), users.ID("2"), place))
+
+	updatedPlace, err := storage.Place(context.Background(), place.RoomID, place.ID)
+	assertNoError(t, err)
+	assertEqual(t, "test", updatedPlace.Name)
+	assertEqual(t, place.Metadata, updatedPlace.Metadata)
+	assertEqual(t, users.ID("1"), updatedPlace.UserID)
+}
+
+func Test_PlaceDeletedWithoutCreate(t *testing.T) {
+	file, err := ioutil.TempFile("", "test-bolt")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	storage := New(events.NewBoltStorage(bolt))
+
+	// the place was never created, like when its create event was lost
+	place := places.NewPlace(rooms.ID("1"), users.ID("1"), "test")
+	assertNoError(t, storage.Delete(context.Background(), users.ID("2"), place))
+
+	pp, err := storage.Places(context.Background(), place.RoomID)
+	assertNoError(t, err)
+	assertEqual(t, 0, len(pp))
+}
+
 func assertNoError(t *testing.T, err error) {
 	t.Helper()
 
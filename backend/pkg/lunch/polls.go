@@ -0,0 +1,210 @@
+package lunch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/places"
+	storage_places "lunch/pkg/lunch/places/storage"
+	"lunch/pkg/lunch/polls"
+	storage_polls "lunch/pkg/lunch/polls/storage"
+	"lunch/pkg/lunch/rolls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// OpenPoll starts a vote for a lunch place in the room, that accepts votes until closesAt.
+// There can be only one open poll in a room at a time.
+func (r *Roller) OpenPoll(ctx context.Context, roomID rooms.ID, method polls.Method, closesAt time.Time, now time.Time) (*Poll, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	switch method {
+	case polls.MethodApproval, polls.MethodRankedChoice:
+	default:
+		return nil, fmt.Errorf("unknown poll method '%s': %w", method, ErrInvalid)
+	}
+
+	if !closesAt.After(now) {
+		return nil, fmt.Errorf("poll must close in the future: %w", ErrInvalid)
+	}
+
+	if closesAt.Sub(now) > polls.MaxDuration {
+		return nil, fmt.Errorf("poll must close within %s: %w", polls.MaxDuration, ErrInvalid)
+	}
+
+	latest, err := r.pollsStore.Latest(ctx, roomID)
+	switch {
+	case errors.Is(err, storage_polls.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	case !latest.IsClosed:
+		return nil, fmt.Errorf("there is a poll in progress: %w", ErrInvalid)
+	}
+
+	poll := polls.NewPoll(roomID, user.ID, method, closesAt, now)
+	if err := r.pollsStore.Create(ctx, poll); err != nil {
+		return nil, fmt.Errorf("failed to store poll: %w", err)
+	}
+
+	view, err := r.pollView(ctx, poll)
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// GetPoll returns the latest poll in the room.
+func (r *Roller) GetPoll(ctx context.Context, roomID rooms.ID) (*Poll, error) {
+	poll, err := r.pollsStore.Latest(ctx, roomID)
+	if errors.Is(err, storage_polls.ErrNotFound) {
+		return nil, fmt.Errorf("no polls in the room: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	}
+	return r.pollView(ctx, poll)
+}
+
+// Vote adds the place to the user's ballot in the open poll, or removes it if it's already there.
+// In ranked choice polls, places are ranked in order they were voted for.
+func (r *Roller) Vote(ctx context.Context, roomID rooms.ID, placeID places.ID, now time.Time) (*Poll, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	poll, err := r.pollsStore.Latest(ctx, roomID)
+	if errors.Is(err, storage_polls.ErrNotFound) {
+		return nil, fmt.Errorf("no polls in the room: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	}
+
+	if !poll.IsOpen(now) {
+		return nil, fmt.Errorf("poll is closed: %w", ErrNotAllowed)
+	}
+
+	place, err := r.placesStore.Place(ctx, roomID, placeID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return nil, fmt.Errorf("place not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+
+	if place.IsDeleted {
+		return nil, fmt.Errorf("place is deleted: %w", ErrNotFound)
+	}
+
+	if err := r.pollsStore.Vote(ctx, poll, user.ID, placeID, now); err != nil {
+		return nil, fmt.Errorf("failed to store vote: %w", err)
+	}
+	poll.Toggle(user.ID, placeID)
+
+	view, err := r.pollView(ctx, poll)
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// ClosePoll records the winner of the latest poll as a roll, so that it affects chances the same way.
+// Polls that stopped accepting votes are closed by the scheduler, or by anyone.
+// Only the poll creator can close it before it's due. Ties are resolved randomly. If nobody voted, the poll is
+// closed and ErrNoPlaces is returned.
+func (r *Roller) ClosePoll(ctx context.Context, roomID rooms.ID, now time.Time) (*Roll, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	poll, err := r.pollsStore.Latest(ctx, roomID)
+	if errors.Is(err, storage_polls.ErrNotFound) {
+		return nil, fmt.Errorf("no polls in the room: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get poll: %w", err)
+	}
+
+	if poll.IsClosed {
+		return nil, fmt.Errorf("poll is already closed: %w", ErrNotFound)
+	}
+
+	if poll.IsOpen(now) && poll.UserID != user.ID {
+		return nil, fmt.Errorf("only poll creator can close it early: %w", ErrNotAllowed)
+	}
+
+	leaders := poll.Leaders()
+	if len(leaders) > 0 {
+		r.randGuard.Lock()
+		poll.WinnerID = leaders[r.rand.Intn(len(leaders))]
+		r.randGuard.Unlock()
+	}
+	poll.IsClosed = true
+
+	if err := r.pollsStore.Close(ctx, poll, user.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to close poll: %w", err)
+	}
+
+	if poll.WinnerID == "" {
+		return nil, ErrNoPlaces
+	}
+
+	roll := rolls.NewRoll(user.ID, roomID, poll.WinnerID, now)
+	roll.PollID = poll.ID
+	if err := r.rollsStore.Create(ctx, roll); err != nil {
+		return nil, fmt.Errorf("failed to store roll: %w", err)
+	}
+
+	winner, err := r.placesStore.Place(ctx, roomID, poll.WinnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+
+	return &Roll{
+		Roll:  roll,
+		User:  user,
+		Place: winner,
+	}, nil
+}
+
+// DuePolls returns polls of all rooms that stopped accepting votes, but are not closed yet.
+func (r *Roller) DuePolls(ctx context.Context, now time.Time) ([]*polls.Poll, error) {
+	pp, err := r.pollsStore.Due(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due polls: %w", err)
+	}
+	return pp, nil
+}
+
+func (r *Roller) pollView(ctx context.Context, poll *polls.Poll) (*Poll, error) {
+	allPlaces, err := r.placesStore.Places(ctx, poll.RoomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list places: %w", err)
+	}
+
+	allUsers, err := r.listUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	pp := make([]*places.Place, 0, len(allPlaces))
+	for _, place := range filterNonDeletedPlaces(allPlaces) {
+		pp = append(pp, place)
+	}
+	sort.Slice(pp, func(i, j int) bool {
+		return pp[i].Name < pp[j].Name
+	})
+
+	return &Poll{
+		Poll:   poll,
+		User:   allUsers[poll.UserID],
+		Places: pp,
+		Votes:  poll.Tally(),
+	}, nil
+}
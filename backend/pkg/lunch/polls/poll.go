@@ -0,0 +1,101 @@
+package polls
+
+import (
+	"time"
+
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+
+	"github.com/google/uuid"
+)
+
+type ID string
+
+// Method defines how votes are counted.
+type Method string
+
+const (
+	// MethodApproval lets everyone vote for any number of places, the place with the most votes wins.
+	MethodApproval Method = "approval"
+	// MethodRankedChoice lets everyone rank places, the winner is found with instant-runoff.
+	MethodRankedChoice Method = "ranked"
+)
+
+// MaxDuration is how long a poll can accept votes for.
+const MaxDuration = 24 * time.Hour
+
+type Poll struct {
+	ID       ID        `json:"id"`
+	RoomID   rooms.ID  `json:"roomId"`
+	UserID   users.ID  `json:"userId"`
+	Method   Method    `json:"method"`
+	Time     time.Time `json:"time"`
+	ClosesAt time.Time `json:"closesAt"`
+	// Ballots are places every user voted for, in order of preference.
+	Ballots map[users.ID][]places.ID `json:"ballots"`
+	// IsClosed is true when the result of the poll is recorded.
+	IsClosed bool `json:"isClosed"`
+	// WinnerID is the place that won the poll, empty if nobody voted.
+	WinnerID places.ID `json:"winnerId,omitempty"`
+}
+
+func NewPoll(roomID rooms.ID, userID users.ID, method Method, closesAt time.Time, now time.Time) *Poll {
+	return &Poll{
+		ID:       ID(uuid.NewString()),
+		RoomID:   roomID,
+		UserID:   userID,
+		Method:   method,
+		Time:     now,
+		ClosesAt: closesAt,
+		Ballots:  map[users.ID][]places.ID{},
+	}
+}
+
+// IsOpen returns true if the poll accepts votes at the given time.
+func (p *Poll) IsOpen(now time.Time) bool {
+	return !p.IsClosed && now.Before(p.ClosesAt)
+}
+
+// Toggle adds the place to the end of the user's ballot, or removes it if it's already there.
+func (p *Poll) Toggle(userID users.ID, placeID places.ID) {
+	ballot := p.Ballots[userID]
+	for i, id := range ballot {
+		if id == placeID {
+			p.Ballots[userID] = append(ballot[:i:i], ballot[i+1:]...)
+			if len(p.Ballots[userID]) == 0 {
+				delete(p.Ballots, userID)
+			}
+			return
+		}
+	}
+	p.Ballots[userID] = append(ballot, placeID)
+}
+
+// Tally returns the number of approvals of every place for approval polls, and the number of
+// first preferences for ranked choice polls.
+func (p *Poll) Tally() map[places.ID]int {
+	counts := map[places.ID]int{}
+	for _, ballot := range p.Ballots {
+		switch p.Method {
+		case MethodRankedChoice:
+			counts[ballot[0]]++
+		default:
+			for _, placeID := range ballot {
+				counts[placeID]++
+			}
+		}
+	}
+	return counts
+}
+
+// Leaders returns places with the best result, sorted by id. There is more than one leader in case of a tie,
+// and none if nobody voted.
+func (p *Poll) Leaders() []places.ID {
+	switch p.Method {
+	case MethodRankedChoice:
+		return instantRunoff(p.Ballots)
+	default:
+		return best(p.Tally())
+	}
+}
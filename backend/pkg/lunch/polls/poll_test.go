@@ -0,0 +1,122 @@
+package polls
+
+import (
+	"reflect"
+	"testing"
+
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/users"
+)
+
+func TestLeaders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		method   Method
+		ballots  map[users.ID][]places.ID
+		expected []places.ID
+	}{
+		{
+			name:     "no votes",
+			method:   MethodApproval,
+			ballots:  map[users.ID][]places.ID{},
+			expected: nil,
+		},
+		{
+			name:   "approval",
+			method: MethodApproval,
+			ballots: map[users.ID][]places.ID{
+				"1": {"a", "b"},
+				"2": {"b", "c"},
+				"3": {"c", "b"},
+			},
+			expected: []places.ID{"b"},
+		},
+		{
+			name:   "approval tie",
+			method: MethodApproval,
+			ballots: map[users.ID][]places.ID{
+				"1": {"b"},
+				"2": {"a"},
+			},
+			expected: []places.ID{"a", "b"},
+		},
+		{
+			name:   "ranked choice, majority on first preferences",
+			method: MethodRankedChoice,
+			ballots: map[users.ID][]places.ID{
+				"1": {"a", "b"},
+				"2": {"a", "c"},
+				"3": {"b", "a"},
+			},
+			expected: []places.ID{"a"},
+		},
+		{
+			name:   "ranked choice, runoff",
+			method: MethodRankedChoice,
+			ballots: map[users.ID][]places.ID{
+				"1": {"a", "b"},
+				"2": {"a"},
+				"3": {"b", "c"},
+				"4": {"c", "b"},
+				"5": {"c", "b"},
+			},
+			// b is eliminated first, then a
+			expected: []places.ID{"c"},
+		},
+		{
+			name:   "ranked choice, place without first preferences is eliminated",
+			method: MethodRankedChoice,
+			ballots: map[users.ID][]places.ID{
+				"1": {"a", "c"},
+				"2": {"b", "c"},
+			},
+			expected: []places.ID{"a", "b"},
+		},
+		{
+			name:   "ranked choice, exhausted ballots",
+			method: MethodRankedChoice,
+			ballots: map[users.ID][]places.ID{
+				"1": {"a"},
+				"2": {"a"},
+				"3": {"b"},
+				"4": {"c", "b"},
+			},
+			expected: []places.ID{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			poll := &Poll{Method: tc.method, Ballots: tc.ballots}
+			assertEqual(t, tc.expected, poll.Leaders())
+		})
+	}
+}
+
+func TestToggle(t *testing.T) {
+	t.Parallel()
+
+	poll := &Poll{Ballots: map[users.ID][]places.ID{}}
+	poll.Toggle("1", "a")
+	poll.Toggle("1", "b")
+	poll.Toggle("1", "c")
+	poll.Toggle("1", "b")
+	assertEqual(t, []places.ID{"a", "c"}, poll.Ballots["1"])
+
+	poll.Toggle("1", "a")
+	poll.Toggle("1", "c")
+	assertEqual(t, map[users.ID][]places.ID{}, poll.Ballots)
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+var (
+	ErrNotFound = fmt.Errorf("not found")
+)
+
+const (
+	pollOpened events.Type = "polls/opened"
+	pollVoted  events.Type = "polls/voted"
+	pollClosed events.Type = "polls/closed"
+)
+
+type opened struct {
+	ID       polls.ID            `json:"id"`
+	Method   polls.Method        `json:"method"`
+	ClosesAt events.UnixNanoTime `json:"closesAt"`
+}
+
+// reference is the payload of votes and closes, it points to the poll.
+type reference struct {
+	ID polls.ID `json:"id"`
+}
+
+type Storage struct {
+	storage events.Storage
+}
+
+func New(storage events.Storage) *Storage {
+	return &Storage{
+		storage: storage,
+	}
+}
+
+func (s *Storage) Create(ctx context.Context, poll *polls.Poll) error {
+	event := &events.Event{
+		UserID:    poll.UserID,
+		RoomID:    poll.RoomID,
+		Timestamp: events.UnixNanoTime(poll.Time),
+		Type:      pollOpened,
+	}
+	if err := event.MarshalPayload(&opened{
+		ID:       poll.ID,
+		Method:   poll.Method,
+		ClosesAt: events.UnixNanoTime(poll.ClosesAt),
+	}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Vote toggles the place on the user's ballot.
+func (s *Storage) Vote(ctx context.Context, poll *polls.Poll, userID users.ID, placeID places.ID, now time.Time) error {
+	event := &events.Event{
+		UserID:    userID,
+		RoomID:    poll.RoomID,
+		Timestamp: events.UnixNanoTime(now),
+		Type:      pollVoted,
+		PlaceID:   placeID,
+	}
+	if err := event.MarshalPayload(&reference{ID: poll.ID}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Close records the winner of the poll.
+func (s *Storage) Close(ctx context.Context, poll *polls.Poll, userID users.ID, now time.Time) error {
+	event := &events.Event{
+		UserID:    userID,
+		RoomID:    poll.RoomID,
+		Timestamp: events.UnixNanoTime(now),
+		Type:      pollClosed,
+		PlaceID:   poll.WinnerID,
+	}
+	if err := event.MarshalPayload(&reference{ID: poll.ID}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Latest returns the most recently opened poll in the room.
+func (s *Storage) Latest(ctx context.Context, roomID rooms.ID) (*polls.Poll, error) {
+	pp, err := s.Polls(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pp) == 0 {
+		return nil, ErrNotFound
+	}
+	return pp[len(pp)-1], nil
+}
+
+// Polls returns all polls of the room, in order they were opened.
+func (s *Storage) Polls(ctx context.Context, roomID rooms.ID) ([]*polls.Poll, error) {
+	events, err := s.storage.ByRoomID(ctx, roomID, pollOpened, pollVoted, pollClosed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return replay(events)
+}
+
+// Due returns polls of all rooms that are not closed, but stopped accepting votes before now. Polls opened
+// more than polls.MaxDuration before now are closed by then, and are not looked at.
+func (s *Storage) Due(ctx context.Context, now time.Time) ([]*polls.Poll, error) {
+	events, err := s.storage.ByTypeSince(ctx, now.Add(-polls.MaxDuration), pollOpened, pollClosed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	pp, err := replay(events)
+	if err != nil {
+		return nil, err
+	}
+	result := []*polls.Poll{}
+	for _, poll := range pp {
+		if !poll.IsClosed && !poll.IsOpen(now) {
+			result = append(result, poll)
+		}
+	}
+	return result, nil
+}
+
+func replay(events []*events.Event) ([]*polls.Poll, error) {
+	sort.Slice(events, func(i, j int) bool {
+		return time.Time(events[i].Timestamp).Before(time.Time(events[j].Timestamp))
+	})
+
+	result := []*polls.Poll{}
+	byID := map[polls.ID]*polls.Poll{}
+	for _, event := range events {
+		switch event.Type {
+		case pollOpened:
+			payload := &opened{}
+			if err := event.UnmarshalPayload(payload); err != nil {
+				return nil, err
+			}
+			poll := &polls.Poll{
+				ID:       payload.ID,
+				RoomID:   event.RoomID,
+				UserID:   event.UserID,
+				Method:   payload.Method,
+				Time:     time.Time(event.Timestamp),
+				ClosesAt: time.Time(payload.ClosesAt),
+				Ballots:  map[users.ID][]places.ID{},
+			}
+			byID[poll.ID] = poll
+			result = append(result, poll)
+		case pollVoted, pollClosed:
+			payload := &reference{}
+			if err := event.UnmarshalPayload(payload); err != nil {
+				return nil, err
+			}
+			poll, ok := byID[payload.ID]
+			if !ok {
+				continue
+			}
+			if event.Type == pollVoted {
+				poll.Toggle(event.UserID, event.PlaceID)
+			} else {
+				poll.IsClosed = true
+				poll.WinnerID = event.PlaceID
+			}
+		}
+	}
+	return result, nil
+}
+
+// PollID returns the poll the event opened, voted in or closed.
+func PollID(event *events.Event) (polls.ID, bool, error) {
+	switch event.Type {
+	case pollOpened:
+		payload := &opened{}
+		if err := event.UnmarshalPayload(payload); err != nil {
+			return "", false, err
+		}
+		return payload.ID, true, nil
+	case pollVoted, pollClosed:
+		payload := &reference{}
+		if err := event.UnmarshalPayload(payload); err != nil {
+			return "", false, err
+		}
+		return payload.ID, true, nil
+	default:
+		return "", false, nil
+	}
+}
@@ -0,0 +1,101 @@
+package polls
+
+import (
+	"sort"
+
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/users"
+)
+
+// best returns places with the highest count, sorted by id.
+func best(counts map[places.ID]int) []places.ID {
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	return withCount(counts, max)
+}
+
+func withCount(counts map[places.ID]int, count int) []places.ID {
+	result := []places.ID{}
+	for placeID, c := range counts {
+		if c == count {
+			result = append(result, placeID)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// instantRunoff counts first preferences of every ballot. If a place has the majority, it wins. Otherwise
+// the place with the fewest votes is eliminated, and its ballots are moved to the next preference.
+func instantRunoff(ballots map[users.ID][]places.ID) []places.ID {
+	eliminated := map[places.ID]bool{}
+	for {
+		counts := map[places.ID]int{}
+		for _, ballot := range ballots {
+			for _, placeID := range ballot {
+				if !eliminated[placeID] {
+					// places without first preferences are still in the race
+					counts[placeID] += 0
+				}
+			}
+		}
+
+		total := 0
+		for _, ballot := range ballots {
+			for _, placeID := range ballot {
+				if eliminated[placeID] {
+					continue
+				}
+				counts[placeID]++
+				total++
+				break
+			}
+		}
+
+		if len(counts) == 0 {
+			return nil
+		}
+
+		min, max := total, 0
+		for _, count := range counts {
+			if count < min {
+				min = count
+			}
+			if count > max {
+				max = count
+			}
+		}
+
+		if 2*max > total || min == max {
+			return withCount(counts, max)
+		}
+
+		// ties for the last place are broken by the number of times places are mentioned on all ballots
+		mentions := map[places.ID]int{}
+		for _, ballot := range ballots {
+			for _, placeID := range ballot {
+				if counts[placeID] == min && !eliminated[placeID] {
+					mentions[placeID]++
+				}
+			}
+		}
+		fewestMentions := len(ballots)
+		for _, count := range mentions {
+			if count < fewestMentions {
+				fewestMentions = count
+			}
+		}
+		for _, placeID := range withCount(mentions, fewestMentions) {
+			eliminated[placeID] = true
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package lunch
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/store"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestPoll(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+
+	creator := testUser()
+	voter := testUser()
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreatePlace(testContext(creator), roomID, "place1"))
+	assertNoError(t, roller.CreatePlace(testContext(creator), roomID, "place2"))
+	places, err := roller.ListPlaces(testContext(creator), roomID, today)
+	assertNoError(t, err)
+
+	_, err = roller.OpenPoll(testContext(creator), roomID, polls.MethodApproval, today, today)
+	assertError(t, ErrInvalid, err)
+	_, err = roller.OpenPoll(testContext(creator), roomID, polls.MethodApproval, today.Add(polls.MaxDuration+time.Minute), today)
+	assertError(t, ErrInvalid, err)
+
+	_, err = roller.OpenPoll(testContext(creator), roomID, polls.MethodApproval, today.Add(time.Hour), today)
+	assertNoError(t, err)
+
+	_, err = roller.OpenPoll(testContext(creator), roomID, polls.MethodApproval, today.Add(time.Hour), today.Add(time.Second))
+	assertError(t, ErrInvalid, err)
+
+	_, err = roller.Vote(testContext(creator), roomID, places[0].ID, today.Add(time.Minute))
+	assertNoError(t, err)
+	_, err = roller.Vote(testContext(voter), roomID, places[0].ID, today.Add(2*time.Minute))
+	assertNoError(t, err)
+	poll, err := roller.Vote(testContext(voter), roomID, places[1].ID, today.Add(3*time.Minute))
+	assertNoError(t, err)
+	assertEqual(t, 2, poll.Votes[places[0].ID])
+	assertEqual(t, 1, poll.Votes[places[1].ID])
+
+	// only the creator can close the poll early
+	_, err = roller.ClosePoll(testContext(voter), roomID, today.Add(4*time.Minute))
+	assertError(t, ErrNotAllowed, err)
+
+	_, err = roller.Vote(testContext(voter), roomID, places[1].ID, today.Add(time.Hour))
+	assertError(t, ErrNotAllowed, err)
+
+	roll, err := roller.ClosePoll(testContext(voter), roomID, today.Add(time.Hour))
+	assertNoError(t, err)
+	assertEqual(t, places[0].ID, roll.Place.ID)
+	assertEqual(t, poll.ID, roll.PollID)
+
+	rolls, err := roller.ListRolls(testContext(creator), roomID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(rolls))
+	assertEqual(t, poll.ID, rolls[0].PollID)
+
+	// the winner is less likely to be rolled, but the poll result doesn't cost points
+	chances, err := roller.ListPlaces(testContext(creator), roomID, today.Add(time.Hour+time.Minute))
+	assertNoError(t, err)
+	for _, place := range chances {
+		if place.ID == places[0].ID {
+			assertEqual(t, 1.0/3.0, place.Chance)
+		}
+	}
+	quota, err := roller.Quota(testContext(voter), roomID, today.Add(time.Hour))
+	assertNoError(t, err)
+	assertEqual(t, 1, quota.Points)
+
+	_, err = roller.ClosePoll(testContext(creator), roomID, today.Add(2*time.Hour))
+	assertError(t, ErrNotFound, err)
+}
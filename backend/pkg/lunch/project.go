@@ -0,0 +1,187 @@
+package lunch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	storage_boosts "lunch/pkg/lunch/boosts/storage"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/places"
+	storage_places "lunch/pkg/lunch/places/storage"
+	"lunch/pkg/lunch/polls"
+	storage_polls "lunch/pkg/lunch/polls/storage"
+	storage_rolls "lunch/pkg/lunch/rolls/storage"
+	storage_rooms "lunch/pkg/lunch/rooms/storage"
+	storage_vetoes "lunch/pkg/lunch/vetoes/storage"
+	"lunch/pkg/users"
+)
+
+// project publishes domain events for the stored event. It's called with every event in the order it was stored,
+// including events stored by other processes, so subscribers see exactly what is in the storage.
+func (r *Roller) project(stored *events.Event) {
+	ctx := users.NewContext(context.Background(), users.System)
+	ee, err := r.projectEvent(ctx, stored)
+	if err != nil {
+		log.Printf("[ERROR] failed to project '%s' event: %s", stored.Type, err)
+		return
+	}
+	for _, e := range ee {
+		e.remote = stored.IsRemote()
+		r.pub(e)
+	}
+}
+
+// projectEvent returns domain events for the stored event, in the order they should be published.
+func (r *Roller) projectEvent(ctx context.Context, stored *events.Event) ([]*event, error) {
+	if change, ok := storage_places.PlaceChange(stored); ok {
+		e, err := r.projectPlace(ctx, stored, change)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{e}, nil
+	}
+
+	if change, ok := storage_rooms.RoomChange(stored); ok {
+		room, err := r.GetRoom(ctx, stored.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if change == storage_rooms.ChangeCreated {
+			return []*event{{Type: TypeRoomCreated, Room: room}}, nil
+		}
+		return []*event{{Type: TypeRoomUpdated, Room: room}}, nil
+	}
+
+	pollID, ok, err := storage_polls.PollID(stored)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		poll, err := r.projectPoll(ctx, stored, pollID)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypePollUpdated, Poll: poll}}, nil
+	}
+
+	if roll, ok, err := storage_rolls.Created(stored); err != nil {
+		return nil, err
+	} else if ok {
+		user, place, err := r.eventRefs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypeRollCreated, Roll: &Roll{Roll: roll, User: user, Place: place}}}, nil
+	}
+
+	if roll, ok, err := storage_rolls.Reverted(stored); err != nil {
+		return nil, err
+	} else if ok {
+		user, place, err := r.eventRefs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypeRollReverted, Roll: &Roll{Roll: roll, User: user, Place: place}}}, nil
+	}
+
+	if boost, ok := storage_boosts.Created(stored); ok {
+		user, place, err := r.eventRefs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypeBoostCreated, Boost: &Boost{Boost: boost, User: user, Place: place}}}, nil
+	}
+
+	if boost, ok, err := storage_boosts.Reverted(stored); err != nil {
+		return nil, err
+	} else if ok {
+		user, place, err := r.eventRefs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypeBoostReverted, Boost: &Boost{Boost: boost, User: user, Place: place}}}, nil
+	}
+
+	if veto, ok := storage_vetoes.Created(stored); ok {
+		user, place, err := r.eventRefs(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		return []*event{{Type: TypeVetoCreated, Veto: &Veto{Veto: veto, User: user, Place: place}}}, nil
+	}
+
+	return nil, nil
+}
+
+// projectPlace returns the place event, with the place as it was right after the stored event.
+func (r *Roller) projectPlace(ctx context.Context, stored *events.Event, change storage_places.Change) (*event, error) {
+	allUsers, err := r.listUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	current, err := r.placesStore.Place(ctx, stored.RoomID, stored.PlaceID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return nil, fmt.Errorf("place %s not found: %w", stored.PlaceID, ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+
+	place := *current
+	view := &Place{
+		Place: &place,
+		User:  allUsers[place.UserID],
+		Actor: allUsers[stored.UserID],
+	}
+	switch change {
+	case storage_places.ChangeCreated, storage_places.ChangeUpdated:
+		place.Name = stored.Name
+		place.Metadata = places.Metadata{}
+		if err := stored.UnmarshalPayload(&place.Metadata); err != nil {
+			return nil, err
+		}
+		if change == storage_places.ChangeCreated {
+			return &event{Type: TypePlaceCreated, Place: view}, nil
+		}
+		return &event{Type: TypePlaceUpdated, Place: view}, nil
+	case storage_places.ChangeDeleted:
+		place.IsDeleted = true
+		return &event{Type: TypePlaceDeleted, Place: view}, nil
+	default:
+		place.IsDeleted = false
+		return &event{Type: TypePlaceRestored, Place: view}, nil
+	}
+}
+
+func (r *Roller) projectPoll(ctx context.Context, stored *events.Event, pollID polls.ID) (*Poll, error) {
+	pp, err := r.pollsStore.Polls(ctx, stored.RoomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list polls: %w", err)
+	}
+	for _, poll := range pp {
+		if poll.ID == pollID {
+			return r.pollView(ctx, poll)
+		}
+	}
+	return nil, fmt.Errorf("poll %s not found: %w", pollID, ErrNotFound)
+}
+
+// eventRefs returns the user who stored the event, and the place it refers to, if any.
+func (r *Roller) eventRefs(ctx context.Context, stored *events.Event) (*users.User, *places.Place, error) {
+	allUsers, err := r.listUsers(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	if stored.PlaceID == "" {
+		return allUsers[stored.UserID], nil, nil
+	}
+
+	place, err := r.placesStore.Place(ctx, stored.RoomID, stored.PlaceID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return allUsers[stored.UserID], nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to get place: %w", err)
+	}
+	return allUsers[stored.UserID], place, nil
+}
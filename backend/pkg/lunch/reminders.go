@@ -0,0 +1,112 @@
+package lunch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"lunch/pkg/lunch/notifications"
+	"lunch/pkg/lunch/reminders"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// MuteReminders turns reminders in the room off or back on for the user.
+func (r *Roller) MuteReminders(ctx context.Context, roomID rooms.ID, muted bool, now time.Time) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	if _, err := r.GetRoom(ctx, roomID); err != nil {
+		return err
+	}
+
+	if err := r.remindersStore.Mute(ctx, roomID, user.ID, muted, now); err != nil {
+		return fmt.Errorf("failed to mute reminders: %w", err)
+	}
+	return nil
+}
+
+// RemindersMuted returns true if the user turned reminders in the room off.
+func (r *Roller) RemindersMuted(ctx context.Context, roomID rooms.ID) (bool, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("expected to find who in the context")
+	}
+
+	muted, err := r.remindersStore.Muted(ctx, roomID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get muted users: %w", err)
+	}
+	return muted[user.ID], nil
+}
+
+// DueReminders returns rooms that should be reminded in (from, to] about their scheduled roll.
+func (r *Roller) DueReminders(ctx context.Context, from, to time.Time) ([]rooms.ID, error) {
+	allSchedules, err := r.schedulesStore.Schedules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	result := []rooms.ID{}
+	for _, schedule := range allSchedules {
+		settings, err := r.roomSettings(ctx, schedule.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if settings.ReminderMinutes == 0 {
+			continue
+		}
+		before := settings.ReminderBefore()
+		if schedule.Due(from.Add(before), to.Add(before), settings.Location()) {
+			result = append(result, schedule.RoomID)
+		}
+	}
+	return result, nil
+}
+
+// Remind notifies room members who want to hear about rolls, and didn't turn reminders off, about the next
+// scheduled roll.
+func (r *Roller) Remind(ctx context.Context, roomID rooms.ID, now time.Time) error {
+	schedule, err := r.GetSchedule(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	rollsAt, ok := schedule.Next(now, room.Settings.Location())
+	if !ok {
+		return fmt.Errorf("schedule is off: %w", ErrNotFound)
+	}
+
+	muted, err := r.remindersStore.Muted(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to get muted users: %w", err)
+	}
+
+	recipients, err := r.Recipients(ctx, roomID, notifications.TopicRolls, now)
+	if err != nil {
+		return err
+	}
+
+	reminder := &Reminder{
+		Reminder: reminders.New(roomID, rollsAt),
+		Room:     room.Room,
+		Users:    []*users.User{},
+	}
+	for _, recipient := range recipients {
+		if muted[recipient.ID] {
+			continue
+		}
+		reminder.Users = append(reminder.Users, recipient)
+	}
+
+	r.ReminderDue(reminder)
+
+	return nil
+}
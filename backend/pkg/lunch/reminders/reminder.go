@@ -0,0 +1,21 @@
+package reminders
+
+import (
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+)
+
+// Reminder tells room members that the room is about to be rolled automatically.
+type Reminder struct {
+	RoomID rooms.ID `json:"roomId"`
+	// RollsAt is when the room is rolled.
+	RollsAt time.Time `json:"rollsAt"`
+}
+
+func New(roomID rooms.ID, rollsAt time.Time) *Reminder {
+	return &Reminder{
+		RoomID:  roomID,
+		RollsAt: rollsAt,
+	}
+}
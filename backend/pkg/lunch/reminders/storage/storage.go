@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+var (
+	remindersMuted   events.Type = "reminders/muted"
+	remindersUnmuted events.Type = "reminders/unmuted"
+)
+
+// Storage keeps track of users who opted out of reminders in a room.
+type Storage struct {
+	eventsStorage events.Storage
+}
+
+func New(eventsStorage events.Storage) *Storage {
+	return &Storage{
+		eventsStorage: eventsStorage,
+	}
+}
+
+// Mute turns reminders in the room off or back on for the user.
+func (s *Storage) Mute(ctx context.Context, roomID rooms.ID, userID users.ID, muted bool, now time.Time) error {
+	eventType := remindersUnmuted
+	if muted {
+		eventType = remindersMuted
+	}
+	return s.eventsStorage.Create(ctx, &events.Event{
+		UserID:    userID,
+		RoomID:    roomID,
+		Type:      eventType,
+		Timestamp: events.UnixNanoTime(now),
+	})
+}
+
+// Muted returns users who turned reminders in the room off.
+func (s *Storage) Muted(ctx context.Context, roomID rooms.ID) (map[users.ID]bool, error) {
+	ee, err := s.eventsStorage.ByRoomID(ctx, roomID, remindersMuted, remindersUnmuted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+
+	result := map[users.ID]bool{}
+	for _, event := range ee {
+		switch event.Type {
+		case remindersMuted:
+			result[event.UserID] = true
+		case remindersUnmuted:
+			delete(result, event.UserID)
+		}
+	}
+	return result, nil
+}
@@ -5,48 +5,128 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"lunch/pkg/lunch/boosts"
 	storage_boosts "lunch/pkg/lunch/boosts/storage"
+	"lunch/pkg/lunch/channels"
+	storage_channels "lunch/pkg/lunch/channels/storage"
 	"lunch/pkg/lunch/events"
+	storage_notifications "lunch/pkg/lunch/notifications/storage"
 	"lunch/pkg/lunch/places"
 	storage_places "lunch/pkg/lunch/places/storage"
+	storage_polls "lunch/pkg/lunch/polls/storage"
+	storage_reminders "lunch/pkg/lunch/reminders/storage"
 	"lunch/pkg/lunch/rolls"
 	storage_rolls "lunch/pkg/lunch/rolls/storage"
 	"lunch/pkg/lunch/rooms"
 	storage_rooms "lunch/pkg/lunch/rooms/storage"
+	storage_schedules "lunch/pkg/lunch/schedules/storage"
+	"lunch/pkg/lunch/vetoes"
+	storage_vetoes "lunch/pkg/lunch/vetoes/storage"
+	storage_webhooks "lunch/pkg/lunch/webhooks/storage"
 	"lunch/pkg/users"
 	storage_users "lunch/pkg/users/storage"
 )
 
 var (
-	ErrNoPoints = fmt.Errorf("no points left")
-	ErrNoPlaces = fmt.Errorf("no places to choose from")
+	ErrNoPoints      = fmt.Errorf("no points left")
+	ErrNoPlaces      = fmt.Errorf("no places to choose from")
+	ErrNotFound      = fmt.Errorf("not found")
+	ErrNotAllowed    = fmt.Errorf("not allowed")
+	ErrInvalid       = fmt.Errorf("invalid")
+	ErrAlreadyExists = fmt.Errorf("already exists")
 )
 
 type Roller struct {
 	*registry
 
-	placesStore *storage_places.Storage
-	rollsStore  *storage_rolls.Storage
-	boostsStore *storage_boosts.Storage
-	usersStore  storage_users.Storage
-	roomsStore  *storage_rooms.Storage
+	placesStore        *storage_places.Storage
+	rollsStore         *storage_rolls.Storage
+	boostsStore        *storage_boosts.Storage
+	vetoesStore        *storage_vetoes.Storage
+	pollsStore         *storage_polls.Storage
+	schedulesStore     *storage_schedules.Storage
+	remindersStore     *storage_reminders.Storage
+	notificationsStore *storage_notifications.Storage
+	usersStore         storage_users.Storage
+	roomsStore         *storage_rooms.Storage
+	channelsStore      *storage_channels.Storage
+	webhooksStore      *storage_webhooks.Storage
+
+	// subscription projects stored events to the events registry.
+	subscription *events.Subscription
+
+	// rand is not safe for concurrent use, rolls of different rooms are created at the same time.
+	randGuard *sync.Mutex
+	rand      *rand.Rand
+}
+
+// Option changes how the roller is set up.
+type Option func(*options)
+
+type options struct {
+	webhooksKey []byte
+}
+
+// WithWebhooksKey sets the key webhook secrets are encrypted with before they are stored. Webhooks can't be
+// created without it.
+func WithWebhooksKey(key []byte) Option {
+	return func(o *options) {
+		o.webhooksKey = key
+	}
+}
+
+func New(eventsStorage events.Storage, usersStore storage_users.Storage, opts ...Option) *Roller {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	r := &Roller{
+		registry:           newEventsRegistry(),
+		placesStore:        storage_places.New(eventsStorage),
+		rollsStore:         storage_rolls.New(eventsStorage),
+		boostsStore:        storage_boosts.New(eventsStorage),
+		vetoesStore:        storage_vetoes.New(eventsStorage),
+		pollsStore:         storage_polls.New(eventsStorage),
+		schedulesStore:     storage_schedules.New(eventsStorage),
+		remindersStore:     storage_reminders.New(eventsStorage),
+		notificationsStore: storage_notifications.New(eventsStorage),
+		roomsStore:         storage_rooms.New(eventsStorage),
+		channelsStore:      storage_channels.New(eventsStorage),
+		webhooksStore:      storage_webhooks.New(eventsStorage, o.webhooksKey),
+		usersStore:         usersStore,
+		randGuard:          &sync.Mutex{},
+		rand:               rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	r.sub(r.enqueueDeliveries, nil, webhookTypes...)
+	r.subscription = eventsStorage.Tail(context.Background(), r.project)
 
-	rand *rand.Rand
+	return r
 }
 
-func New(eventsStorage events.Storage, usersStore storage_users.Storage) *Roller {
-	return &Roller{
-		registry:    newEventsRegistry(),
-		placesStore: storage_places.New(eventsStorage),
-		rollsStore:  storage_rolls.New(eventsStorage),
-		boostsStore: storage_boosts.New(eventsStorage),
-		roomsStore:  storage_rooms.New(eventsStorage),
-		usersStore:  usersStore,
-		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+// Wait blocks until all events stored so far are published, and handled by all subscribers.
+func (r *Roller) Wait(ctx context.Context) error {
+	if err := r.subscription.Wait(ctx); err != nil {
+		return err
 	}
+	return r.registry.Wait(ctx)
+}
+
+// Shutdown waits for background tasks, publishes events stored so far, stops following the storage, and waits for
+// subscribers to handle published events.
+func (r *Roller) Shutdown(ctx context.Context) error {
+	err := r.Wait(ctx)
+	r.subscription.Close()
+	if err != nil {
+		return err
+	}
+	return r.registry.Shutdown(ctx)
 }
 
 func (r *Roller) CreateRoom(ctx context.Context, name string) error {
@@ -60,14 +140,6 @@ func (r *Roller) CreateRoom(ctx context.Context, name string) error {
 		return fmt.Errorf("failed to store place: %w", err)
 	}
 
-	r.RoomCreated(&Room{
-		Room: room,
-		User: user,
-		Members: []*users.User{
-			user,
-		},
-	})
-
 	return nil
 }
 
@@ -79,31 +151,15 @@ func (r *Roller) LeaveRoom(ctx context.Context, roomID rooms.ID) error {
 
 	room, err := r.roomsStore.Room(ctx, roomID)
 	if errors.Is(err, storage_rooms.ErrNotFound) {
-		return fmt.Errorf("room not found")
+		return fmt.Errorf("room not found: %w", ErrNotFound)
 	} else if err != nil {
 		return fmt.Errorf("failed to get room: %w", err)
 	}
 
-	if err := r.roomsStore.Leave(ctx, user, roomID); err != nil {
+	if err := r.roomsStore.Leave(ctx, user, room.ID); err != nil {
 		return fmt.Errorf("failed to join room: %w", err)
 	}
 
-	allUsers, err := r.usersStore.List(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list users: %w", err)
-	}
-
-	roomView := &Room{
-		Room: room,
-		User: allUsers[room.UserID],
-	}
-
-	for uid := range room.MemberIDs {
-		roomView.Members = append(roomView.Members, allUsers[uid])
-	}
-
-	r.RoomUpdated(roomView)
-
 	return nil
 }
 
@@ -115,18 +171,43 @@ func (r *Roller) JoinRoom(ctx context.Context, roomID rooms.ID) error {
 
 	room, err := r.roomsStore.Room(ctx, roomID)
 	if errors.Is(err, storage_rooms.ErrNotFound) {
-		return fmt.Errorf("room not found")
+		return fmt.Errorf("room not found: %w", ErrNotFound)
 	} else if err != nil {
 		return fmt.Errorf("failed to get room: %w", err)
 	}
 
-	if err := r.roomsStore.Join(ctx, user, roomID); err != nil {
+	if err := r.roomsStore.Join(ctx, user, room.ID); err != nil {
 		return fmt.Errorf("failed to join room: %w", err)
 	}
 
-	allUsers, err := r.usersStore.List(ctx)
+	return nil
+}
+
+// listUsers returns all users by id, including the system user, who isn't stored.
+func (r *Roller) listUsers(ctx context.Context) (map[users.ID]*users.User, error) {
+	stored, err := r.usersStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[users.ID]*users.User, len(stored)+1)
+	for id, user := range stored {
+		result[id] = user
+	}
+	result[users.System.ID] = users.System
+	return result, nil
+}
+
+func (r *Roller) GetRoom(ctx context.Context, roomID rooms.ID) (*Room, error) {
+	room, err := r.roomsStore.Room(ctx, roomID)
+	if errors.Is(err, storage_rooms.ErrNotFound) {
+		return nil, fmt.Errorf("room not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	allUsers, err := r.listUsers(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list users: %w", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
 	roomView := &Room{
@@ -138,11 +219,51 @@ func (r *Roller) JoinRoom(ctx context.Context, roomID rooms.ID) error {
 		roomView.Members = append(roomView.Members, allUsers[uid])
 	}
 
-	r.RoomUpdated(roomView)
+	return roomView, nil
+}
+
+// UpdateRoomSettings replaces the room settings. Only the room owner can change them.
+func (r *Roller) UpdateRoomSettings(ctx context.Context, roomID rooms.ID, settings rooms.Settings) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	if err := validateStrategy(settings); err != nil {
+		return err
+	}
+
+	if err := validateRules(settings); err != nil {
+		return err
+	}
+
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if room.UserID != user.ID {
+		return fmt.Errorf("only room owner can change settings: %w", ErrNotAllowed)
+	}
+
+	if err := r.roomsStore.UpdateSettings(ctx, user, roomID, settings); err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
 
 	return nil
 }
 
+// roomSettings returns settings of the room, or the default settings if the room doesn't exist.
+func (r *Roller) roomSettings(ctx context.Context, roomID rooms.ID) (rooms.Settings, error) {
+	room, err := r.roomsStore.Room(ctx, roomID)
+	if errors.Is(err, storage_rooms.ErrNotFound) {
+		return rooms.DefaultSettings(), nil
+	} else if err != nil {
+		return rooms.Settings{}, fmt.Errorf("failed to get room: %w", err)
+	}
+	return room.Settings, nil
+}
+
 func (r *Roller) ListRooms(ctx context.Context) ([]*Room, error) {
 	user, ok := users.FromContext(ctx)
 	if !ok {
@@ -152,7 +273,7 @@ func (r *Roller) ListRooms(ctx context.Context) ([]*Room, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to list rooms: %w", err)
 	}
-	allUsers, err := r.usersStore.List(ctx)
+	allUsers, err := r.listUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -174,25 +295,285 @@ func (r *Roller) ListRooms(ctx context.Context) ([]*Room, error) {
 	return result, nil
 }
 
+// BindChannel binds a chat channel to the room, so that commands from the channel are applied to the room.
+// Only room members can bind channels.
+func (r *Roller) BindChannel(ctx context.Context, roomID rooms.ID, channelID channels.ID, now time.Time) error {
+	return r.bindChannel(ctx, channels.New(channelID, roomID, "", now))
+}
+
+// BindTeamsChannel binds a Microsoft Teams channel to the room. Notifications are posted to the channel
+// with its incoming webhook.
+func (r *Roller) BindTeamsChannel(ctx context.Context, roomID rooms.ID, channelID channels.ID, webhookURL string, now time.Time) error {
+	return r.bindChannel(ctx, channels.NewTeams(channelID, roomID, "", webhookURL, now))
+}
+
+func (r *Roller) bindChannel(ctx context.Context, channel *channels.Channel) error {
+	roomID := channel.RoomID
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	room, err := r.roomsStore.Room(ctx, roomID)
+	if errors.Is(err, storage_rooms.ErrNotFound) {
+		return fmt.Errorf("room not found: %w", ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to get room: %w", err)
+	}
+
+	if !room.MemberIDs[user.ID] {
+		return fmt.Errorf("only room members can bind channels: %w", ErrNotAllowed)
+	}
+
+	current, err := r.channelsStore.Channel(ctx, channel.ID)
+	switch {
+	case errors.Is(err, storage_channels.ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("failed to get channel: %w", err)
+	case current.Platform != channel.Platform:
+		// ids of different platforms could collide
+		return fmt.Errorf("channel is bound on %s: %w", current.Platform, ErrAlreadyExists)
+	}
+
+	if current != nil && current.RoomID != roomID && current.UserID != user.ID {
+		currentRoom, err := r.roomsStore.Room(ctx, current.RoomID)
+		switch {
+		case errors.Is(err, storage_rooms.ErrNotFound):
+		case err != nil:
+			return fmt.Errorf("failed to get current room: %w", err)
+		case !currentRoom.MemberIDs[user.ID]:
+			return fmt.Errorf("only members of the current room can move the channel: %w", ErrNotAllowed)
+		}
+	}
+
+	channel.UserID = user.ID
+	if err := r.channelsStore.Bind(ctx, channel); err != nil {
+		return fmt.Errorf("failed to bind channel: %w", err)
+	}
+
+	return nil
+}
+
+// GetChannel returns the channel and the room it's bound to.
+func (r *Roller) GetChannel(ctx context.Context, channelID channels.ID) (*channels.Channel, error) {
+	channel, err := r.channelsStore.Channel(ctx, channelID)
+	if errors.Is(err, storage_channels.ErrNotFound) {
+		return nil, fmt.Errorf("channel not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	return channel, nil
+}
+
+// ListChannels returns channels bound to the room.
+func (r *Roller) ListChannels(ctx context.Context, roomID rooms.ID) ([]*channels.Channel, error) {
+	cc, err := r.channelsStore.Channels(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channels: %w", err)
+	}
+	return cc, nil
+}
+
+// GetDailyMessage returns the message posted to the channel for the room on the day.
+func (r *Roller) GetDailyMessage(ctx context.Context, roomID rooms.ID, channelID channels.ID, day string) (*channels.Message, error) {
+	msg, err := r.channelsStore.Message(ctx, roomID, channelID, day)
+	if errors.Is(err, storage_channels.ErrNotFound) {
+		return nil, fmt.Errorf("message not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	return msg, nil
+}
+
+// SaveDailyMessage remembers the message posted to the channel for the room on the day, so it can be updated later.
+func (r *Roller) SaveDailyMessage(ctx context.Context, roomID rooms.ID, channelID channels.ID, day, ts string, now time.Time) (*channels.Message, error) {
+	msg := channels.NewMessage(channelID, roomID, users.System.ID, day, ts, now)
+	if err := r.channelsStore.SaveMessage(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to save message: %w", err)
+	}
+	return msg, nil
+}
+
 func (r *Roller) CreatePlace(ctx context.Context, roomID rooms.ID, name string) error {
+	return r.CreatePlaceWithMetadata(ctx, roomID, name, places.Metadata{})
+}
+
+// CreatePlaceWithMetadata adds a place to the room. Names are unique in the room, ignoring case.
+func (r *Roller) CreatePlaceWithMetadata(ctx context.Context, roomID rooms.ID, name string, metadata places.Metadata) error {
 	user, ok := users.FromContext(ctx)
 	if !ok {
 		return fmt.Errorf("expected to find who in the context")
 	}
 
+	name = strings.TrimSpace(name)
+	if err := validatePlace(name, metadata); err != nil {
+		return err
+	}
+	if err := r.checkPlaceName(ctx, roomID, "", name); err != nil {
+		return err
+	}
+
 	place := places.NewPlace(roomID, user.ID, name)
+	place.Metadata = metadata
 	if err := r.placesStore.Create(ctx, place); err != nil {
 		return fmt.Errorf("failed to store place: %w", err)
 	}
 
-	r.PlaceCreated(&Place{
+	return nil
+}
+
+func (r *Roller) GetPlace(ctx context.Context, roomID rooms.ID, placeID places.ID) (*Place, error) {
+	place, err := r.placesStore.Place(ctx, roomID, placeID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return nil, fmt.Errorf("place not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+
+	allUsers, err := r.listUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return &Place{
 		Place: place,
-		User:  user,
-	})
+		User:  allUsers[place.UserID],
+	}, nil
+}
+
+// UpdatePlace renames the place and replaces its metadata. Only the place creator or the room owner can update it.
+func (r *Roller) UpdatePlace(ctx context.Context, roomID rooms.ID, placeID places.ID, name string, metadata places.Metadata) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	name = strings.TrimSpace(name)
+	if err := validatePlace(name, metadata); err != nil {
+		return err
+	}
+
+	place, err := r.editablePlace(ctx, user, roomID, placeID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkPlaceName(ctx, roomID, placeID, name); err != nil {
+		return err
+	}
+
+	place.Name = name
+	place.Metadata = metadata
+	if err := r.placesStore.Update(ctx, user.ID, place); err != nil {
+		return fmt.Errorf("failed to update place: %w", err)
+	}
+
+	return nil
+}
+
+func validatePlace(name string, metadata places.Metadata) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty: %w", ErrInvalid)
+	}
+	if metadata.PriceLevel < places.MinPriceLevel || metadata.PriceLevel > places.MaxPriceLevel {
+		return fmt.Errorf("price level must be between %d and %d: %w", places.MinPriceLevel, places.MaxPriceLevel, ErrInvalid)
+	}
+	return nil
+}
+
+// checkPlaceName returns ErrAlreadyExists if a place in the room other than placeID has the same name, ignoring case.
+// Deleted places are ignored.
+func (r *Roller) checkPlaceName(ctx context.Context, roomID rooms.ID, placeID places.ID, name string) error {
+	allPlaces, err := r.placesStore.Places(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list places: %w", err)
+	}
+	for _, place := range filterNonDeletedPlaces(allPlaces) {
+		if place.ID != placeID && strings.EqualFold(place.Name, name) {
+			return fmt.Errorf("place '%s': %w", place.Name, ErrAlreadyExists)
+		}
+	}
+	return nil
+}
+
+// DeletePlace removes the place from the rotation. Only the place creator or the room owner can delete it.
+func (r *Roller) DeletePlace(ctx context.Context, roomID rooms.ID, placeID places.ID) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	place, err := r.editablePlace(ctx, user, roomID, placeID)
+	if err != nil {
+		return err
+	}
+
+	if place.IsDeleted {
+		return nil
+	}
+
+	if err := r.placesStore.Delete(ctx, user.ID, place); err != nil {
+		return fmt.Errorf("failed to delete place: %w", err)
+	}
+
+	return nil
+}
+
+// RestorePlace returns deleted place back to the rotation. Only the place creator or the room owner can restore it.
+func (r *Roller) RestorePlace(ctx context.Context, roomID rooms.ID, placeID places.ID) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	place, err := r.editablePlace(ctx, user, roomID, placeID)
+	if err != nil {
+		return err
+	}
+
+	if !place.IsDeleted {
+		return nil
+	}
+
+	if err := r.checkPlaceName(ctx, roomID, placeID, place.Name); err != nil {
+		return err
+	}
+
+	if err := r.placesStore.Restore(ctx, user.ID, place); err != nil {
+		return fmt.Errorf("failed to restore place: %w", err)
+	}
 
 	return nil
 }
 
+// editablePlace returns the place if the user is allowed to change it: the user either created the place,
+// or owns the room.
+func (r *Roller) editablePlace(ctx context.Context, user *users.User, roomID rooms.ID, placeID places.ID) (*places.Place, error) {
+	place, err := r.placesStore.Place(ctx, roomID, placeID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return nil, fmt.Errorf("place not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get place: %w", err)
+	}
+
+	if place.UserID == user.ID {
+		return place, nil
+	}
+
+	room, err := r.roomsStore.Room(ctx, roomID)
+	if errors.Is(err, storage_rooms.ErrNotFound) {
+		return nil, fmt.Errorf("only place creator can change it: %w", ErrNotAllowed)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	if room.UserID != user.ID {
+		return nil, fmt.Errorf("only place creator or room owner can change it: %w", ErrNotAllowed)
+	}
+
+	return place, nil
+}
+
 func (r *Roller) ListRolls(ctx context.Context, roomID rooms.ID) ([]*Roll, error) {
 	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
 	if err != nil {
@@ -207,7 +588,7 @@ func (r *Roller) ListRolls(ctx context.Context, roomID rooms.ID) ([]*Roll, error
 		return nil, fmt.Errorf("failed to list places: %w", err)
 	}
 
-	allUsers, err := r.usersStore.List(ctx)
+	allUsers, err := r.listUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -230,7 +611,7 @@ func (r *Roller) ListBoosts(ctx context.Context, roomID rooms.ID) ([]*Boost, err
 		return nil, fmt.Errorf("failed to list boosts: %w", err)
 	}
 
-	allUsers, err := r.usersStore.List(ctx)
+	allUsers, err := r.listUsers(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -252,6 +633,85 @@ func (r *Roller) ListBoosts(ctx context.Context, roomID rooms.ID) ([]*Boost, err
 	return boosts, nil
 }
 
+func (r *Roller) ListVetoes(ctx context.Context, roomID rooms.ID) ([]*Veto, error) {
+	allVetoes, err := r.vetoesStore.Vetoes(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vetoes: %w", err)
+	}
+
+	allUsers, err := r.listUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	allPlaces, err := r.placesStore.Places(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list places: %w", err)
+	}
+
+	vetoes := make([]*Veto, 0, len(allVetoes))
+	for _, v := range allVetoes {
+		vetoes = append(vetoes, &Veto{
+			Veto:  v,
+			User:  allUsers[v.UserID],
+			Place: allPlaces[v.PlaceID],
+		})
+	}
+
+	return vetoes, nil
+}
+
+// Today returns rolls, boosts and vetoes of the day in the room time zone.
+func (r *Roller) Today(ctx context.Context, roomID rooms.ID, now time.Time) (*Day, error) {
+	settings, err := r.roomSettings(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	loc := settings.Location()
+	now = now.In(loc)
+	today := dateOf(now)
+
+	allRolls, err := r.ListRolls(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	allBoosts, err := r.ListBoosts(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	allVetoes, err := r.ListVetoes(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	day := &Day{
+		RoomID: roomID,
+		Date:   now.Format(dayLayout),
+		Rolls:  []*Roll{},
+		Boosts: []*Boost{},
+		Vetoes: []*Veto{},
+	}
+	for _, roll := range allRolls {
+		if dateOf(roll.Time.In(loc)) == today {
+			day.Rolls = append(day.Rolls, roll)
+		}
+	}
+	for _, boost := range allBoosts {
+		if dateOf(boost.Time.In(loc)) == today {
+			day.Boosts = append(day.Boosts, boost)
+		}
+	}
+	for _, veto := range allVetoes {
+		if dateOf(veto.Time.In(loc)) == today {
+			day.Vetoes = append(day.Vetoes, veto)
+		}
+	}
+	sort.Slice(day.Rolls, func(i, j int) bool {
+		return day.Rolls[i].Time.Before(day.Rolls[j].Time)
+	})
+	return day, nil
+}
+
 func filterNonDeletedPlaces(pp map[places.ID]*places.Place) map[places.ID]*places.Place {
 	result := make(map[places.ID]*places.Place, len(pp))
 	for id, place := range pp {
@@ -262,6 +722,31 @@ func filterNonDeletedPlaces(pp map[places.ID]*places.Place) map[places.ID]*place
 	return result
 }
 
+// history returns rolls history of the room.
+func (r *Roller) history(ctx context.Context, roomID rooms.ID, now time.Time) (*rollsHistory, error) {
+	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rolls: %w", err)
+	}
+
+	allBoosts, err := r.boostsStore.Boosts(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list boosts: %w", err)
+	}
+
+	allVetoes, err := r.vetoesStore.Vetoes(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vetoes: %w", err)
+	}
+
+	settings, err := r.roomSettings(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildHistory(allRolls, allBoosts, allVetoes, settings, now), nil
+}
+
 func (r *Roller) ListPlaces(ctx context.Context, roomID rooms.ID, now time.Time) ([]*Place, error) {
 	allPlaces, err := r.placesStore.Places(ctx, roomID)
 	if err != nil {
@@ -274,23 +759,17 @@ func (r *Roller) ListPlaces(ctx context.Context, roomID rooms.ID, now time.Time)
 		return nil, ErrNoPlaces
 	}
 
-	allBoosts, err := r.boostsStore.Boosts(ctx, roomID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list boosts: %w", err)
-	}
-
-	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
+	allUsers, err := r.listUsers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list rolls: %w", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	allUsers, err := r.usersStore.List(ctx)
+	history, err := r.history(ctx, roomID, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, err
 	}
 
-	history := buildHistory(allRolls, allBoosts, now)
-	weights := history.getWeights(allPlaces, now)
+	weights := history.getWeights(allPlaces, weightStrategy(history.Settings), now)
 	weightsSum := 0.0
 	for _, weight := range weights {
 		weightsSum += weight
@@ -302,7 +781,10 @@ func (r *Roller) ListPlaces(ctx context.Context, roomID rooms.ID, now time.Time)
 			continue
 		}
 
-		chance := weight / weightsSum
+		chance := 0.0
+		if weightsSum > 0 {
+			chance = weight / weightsSum
+		}
 		views = append(views, &Place{
 			Place:  allPlaces[i],
 			User:   allUsers[allPlaces[i].UserID],
@@ -313,6 +795,23 @@ func (r *Roller) ListPlaces(ctx context.Context, roomID rooms.ID, now time.Time)
 	return views, nil
 }
 
+// Quota returns how many points the user has left in the room.
+func (r *Roller) Quota(ctx context.Context, roomID rooms.ID, now time.Time) (*Quota, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	history, err := r.history(ctx, roomID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := history.Quota(user.ID, now)
+	quota.RoomID = roomID
+	return quota, nil
+}
+
 func (r *Roller) CreateBoost(ctx context.Context, roomID rooms.ID, placeID places.ID, now time.Time) error {
 	user, ok := users.FromContext(ctx)
 	if !ok {
@@ -320,35 +819,74 @@ func (r *Roller) CreateBoost(ctx context.Context, roomID rooms.ID, placeID place
 	}
 
 	place, err := r.placesStore.Place(ctx, roomID, placeID)
-	if err != nil {
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return fmt.Errorf("place not found: %w", ErrNotFound)
+	} else if err != nil {
 		return fmt.Errorf("failed to get place: %w", err)
 	}
 
-	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
-	if err != nil {
-		return fmt.Errorf("failed to list rolls: %w", err)
+	if place.IsDeleted {
+		return fmt.Errorf("place is deleted: %w", ErrNotFound)
 	}
 
-	allBoosts, err := r.boostsStore.Boosts(ctx, roomID)
+	history, err := r.history(ctx, roomID, now)
 	if err != nil {
-		return fmt.Errorf("failed to list boosts: %w", err)
+		return err
 	}
 
-	history := buildHistory(allRolls, allBoosts, now)
 	if err := history.CanBoost(user.ID, now); err != nil {
 		return fmt.Errorf("can't boost any more: %w", err)
 	}
 
+	allPlaces, err := r.placesStore.Places(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list places: %w", err)
+	}
+	// boosts multiply the weight, so they would do nothing to a place that can't be rolled
+	weights := history.getWeights(filterNonDeletedPlaces(allPlaces), weightStrategy(history.Settings), now)
+	if weights[placeID] == 0 {
+		return fmt.Errorf("place can't be rolled now: %w", ErrNotAllowed)
+	}
+
 	boost := boosts.NewBoost(user.ID, roomID, placeID, now)
 	if err := r.boostsStore.Create(ctx, boost); err != nil {
 		return fmt.Errorf("failed to store boost: %w", err)
 	}
 
-	r.BoostCreated(&Boost{
-		Boost: boost,
-		User:  user,
-		Place: place,
-	})
+	return nil
+}
+
+// CreateVeto spends a point to exclude the place from the next roll, and from rerolls on the same day.
+func (r *Roller) CreateVeto(ctx context.Context, roomID rooms.ID, placeID places.ID, now time.Time) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	place, err := r.placesStore.Place(ctx, roomID, placeID)
+	if errors.Is(err, storage_places.ErrNotFound) {
+		return fmt.Errorf("place not found: %w", ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to get place: %w", err)
+	}
+
+	if place.IsDeleted {
+		return fmt.Errorf("place is deleted: %w", ErrNotFound)
+	}
+
+	history, err := r.history(ctx, roomID, now)
+	if err != nil {
+		return err
+	}
+
+	if err := history.CanVeto(user.ID, now); err != nil {
+		return fmt.Errorf("can't veto any more: %w", err)
+	}
+
+	veto := vetoes.NewVeto(user.ID, roomID, placeID, now)
+	if err := r.vetoesStore.Create(ctx, veto); err != nil {
+		return fmt.Errorf("failed to store veto: %w", err)
+	}
 
 	return nil
 }
@@ -368,23 +906,25 @@ func (r *Roller) CreateRoll(ctx context.Context, roomID rooms.ID, now time.Time)
 		return nil, ErrNoPlaces
 	}
 
-	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
+	history, err := r.history(ctx, roomID, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list rolls: %w", err)
+		return nil, err
 	}
 
-	allBoosts, err := r.boostsStore.Boosts(ctx, roomID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list boosts: %w", err)
-	}
-
-	history := buildHistory(allRolls, allBoosts, now)
 	if err := history.CanRoll(user.ID, now); err != nil {
 		return nil, fmt.Errorf("failed to validate rules: %w", err)
 	}
 
-	weights := history.getWeights(allPlaces, now)
-	randomIndex := weightedRandom(r.rand, weights)
+	weights := history.getWeights(allPlaces, weightStrategy(history.Settings), now)
+	if !hasPositiveWeight(weights) {
+		return nil, ErrNoPlaces
+	}
+	r.randGuard.Lock()
+	randomIndex, err := weightedRandom(r.rand, weights)
+	r.randGuard.Unlock()
+	if err != nil {
+		return nil, err
+	}
 	randomPlace := allPlaces[randomIndex]
 
 	roll := rolls.NewRoll(user.ID, roomID, randomPlace.ID, now)
@@ -398,25 +938,103 @@ func (r *Roller) CreateRoll(ctx context.Context, roomID rooms.ID, now time.Time)
 		Place: randomPlace,
 	}
 
-	r.RollCreated(rollView)
-
 	return rollView, nil
 }
 
+// Undo reverts the last roll or boost of the user in the room, if it was made within the undo window.
+func (r *Roller) Undo(ctx context.Context, roomID rooms.ID, now time.Time) error {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("expected to find who in the context")
+	}
+
+	allRolls, err := r.rollsStore.Rolls(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list rolls: %w", err)
+	}
+
+	allBoosts, err := r.boostsStore.Boosts(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list boosts: %w", err)
+	}
+
+	var lastRoll *rolls.Roll
+	for _, roll := range allRolls {
+		if roll.UserID != user.ID {
+			continue
+		}
+		if roll.PollID != "" {
+			// poll results are not undone
+			continue
+		}
+		if lastRoll == nil || roll.Time.After(lastRoll.Time) {
+			lastRoll = roll
+		}
+	}
+
+	var lastBoost *boosts.Boost
+	for _, boost := range allBoosts {
+		if boost.UserID != user.ID {
+			continue
+		}
+		if lastBoost == nil || boost.Time.After(lastBoost.Time) {
+			lastBoost = boost
+		}
+	}
+
+	if lastRoll == nil && lastBoost == nil {
+		return fmt.Errorf("nothing to undo: %w", ErrNotFound)
+	}
+
+	settings, err := r.roomSettings(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if lastBoost == nil || (lastRoll != nil && lastRoll.Time.After(lastBoost.Time)) {
+		if now.Sub(lastRoll.Time) > settings.UndoWindow() {
+			return fmt.Errorf("roll is too old to undo: %w", ErrNotAllowed)
+		}
+		if err := r.rollsStore.Revert(ctx, lastRoll, now); err != nil {
+			return fmt.Errorf("failed to revert roll: %w", err)
+		}
+		return nil
+	}
+
+	if now.Sub(lastBoost.Time) > settings.UndoWindow() {
+		return fmt.Errorf("boost is too old to undo: %w", ErrNotAllowed)
+	}
+	if err := r.boostsStore.Revert(ctx, lastBoost, now); err != nil {
+		return fmt.Errorf("failed to revert boost: %w", err)
+	}
+	return nil
+}
+
+func hasPositiveWeight(weights map[places.ID]float64) bool {
+	for _, weight := range weights {
+		if weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // weightedRandom returns a random index i from the slice of weights, proportional to the weights[i] value.
-func weightedRandom(rand *rand.Rand, weights map[places.ID]float64) places.ID {
+func weightedRandom(rand *rand.Rand, weights map[places.ID]float64) (places.ID, error) {
 	weightsSum := 0.0
 	for _, weight := range weights {
 		weightsSum += weight
 	}
+	if !isFinite(weightsSum) || weightsSum <= 0 {
+		return "", fmt.Errorf("invalid weights %v: %w", weights, ErrInvalid)
+	}
 
 	remainingDistance := rand.Float64() * weightsSum
 	for i, weight := range weights {
 		remainingDistance -= weight
 		if remainingDistance < 0 {
-			return i
+			return i, nil
 		}
 	}
-
-	panic("should never happen")
+	return "", fmt.Errorf("invalid weights %v: %w", weights, ErrInvalid)
 }
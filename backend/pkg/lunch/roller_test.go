@@ -5,12 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"lunch/pkg/lunch/channels"
 	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/places"
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/store"
 	"lunch/pkg/users"
@@ -34,6 +39,43 @@ func TestRoll_noPlaces(t *testing.T) {
 	assertNil(t, place)
 }
 
+func TestRoll_concurrentRooms(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	roomIDs := []rooms.ID{"first-room", "second-room", "third-room"}
+	for _, roomID := range roomIDs {
+		assertNoError(t, roller.CreatePlace(ctx, roomID, "place1"))
+		assertNoError(t, roller.CreatePlace(ctx, roomID, "place2"))
+	}
+
+	// rolls of different rooms share the roller, only ErrNoPoints is expected from rerolls
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, 10*len(roomIDs))
+	for _, roomID := range roomIDs {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(roomID rooms.ID) {
+				defer wg.Done()
+				if _, err := roller.CreateRoll(ctx, roomID, time.Now()); !errors.Is(err, ErrNoPoints) {
+					errs <- err
+				}
+			}(roomID)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assertNoError(t, err)
+	}
+}
+
 func TestRoll_reroll_then_boost(t *testing.T) {
 	t.Parallel()
 
@@ -163,6 +205,359 @@ func TestRoll_rerolls(t *testing.T) {
 	}
 }
 
+func TestListPlaces_roomsAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewCache(events.NewBoltStorage(bolt)), storage_users.NewBolt(bolt))
+
+	firstRoomID, secondRoomID := rooms.ID("first-room"), rooms.ID("second-room")
+	assertNoError(t, roller.CreatePlace(ctx, firstRoomID, "place1"))
+
+	firstRoomPlaces, err := roller.ListPlaces(ctx, firstRoomID, time.Now())
+	assertNoError(t, err)
+	assertEqual(t, 1, len(firstRoomPlaces))
+
+	_, err = roller.ListPlaces(ctx, secondRoomID, time.Now())
+	assertError(t, ErrNoPlaces, err)
+
+	assertNoError(t, roller.CreatePlace(ctx, secondRoomID, "place2"))
+
+	secondRoomPlaces, err := roller.ListPlaces(ctx, secondRoomID, time.Now())
+	assertNoError(t, err)
+	assertEqual(t, 1, len(secondRoomPlaces))
+	assertEqual(t, "place2", secondRoomPlaces[0].Name)
+}
+
+func TestCreatePlace_names(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	firstRoomID, secondRoomID := rooms.ID("first-room"), rooms.ID("second-room")
+	assertNoError(t, roller.CreatePlace(ctx, firstRoomID, " Pizza "))
+	assertError(t, ErrInvalid, roller.CreatePlace(ctx, firstRoomID, "  "))
+	assertError(t, ErrAlreadyExists, roller.CreatePlace(ctx, firstRoomID, "pizza"))
+	assertError(t, ErrInvalid, roller.CreatePlaceWithMetadata(ctx, firstRoomID, "Sushi", places.Metadata{PriceLevel: 5}))
+	assertNoError(t, roller.CreatePlaceWithMetadata(ctx, firstRoomID, "Sushi", places.Metadata{PriceLevel: 2, Tags: []string{"fish"}}))
+	assertNoError(t, roller.CreatePlace(ctx, secondRoomID, "PIZZA"))
+
+	pp, err := roller.ListPlaces(ctx, firstRoomID, time.Now())
+	assertNoError(t, err)
+	assertEqual(t, 2, len(pp))
+	byName := map[string]*Place{}
+	for _, place := range pp {
+		byName[place.Name] = place
+	}
+	assertEqual(t, true, byName["Pizza"] != nil)
+	assertEqual(t, 2, byName["Sushi"].Metadata.PriceLevel)
+
+	assertError(t, ErrAlreadyExists, roller.UpdatePlace(ctx, firstRoomID, byName["Sushi"].ID, "PIZZA", places.Metadata{}))
+	assertNoError(t, roller.UpdatePlace(ctx, firstRoomID, byName["Sushi"].ID, "SUSHI", places.Metadata{}))
+
+	assertNoError(t, roller.DeletePlace(ctx, firstRoomID, byName["Pizza"].ID))
+	assertNoError(t, roller.CreatePlace(ctx, firstRoomID, "pizza"))
+	assertError(t, ErrAlreadyExists, roller.RestorePlace(ctx, firstRoomID, byName["Pizza"].ID))
+}
+
+func TestDeletePlace_permissions(t *testing.T) {
+	t.Parallel()
+
+	owner, creator, stranger := testUser(), testUser(), testUser()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	usersStore := storage_users.NewBolt(bolt)
+	roller := New(events.NewBoltStorage(bolt), usersStore)
+	assertNoError(t, usersStore.Create(context.Background(), owner))
+	assertNoError(t, usersStore.Create(context.Background(), creator))
+
+	deleted := make(chan *Place, 1)
+	roller.OnPlaceDeleted(func(ctx context.Context, place *Place) error {
+		deleted <- place
+		return nil
+	})
+
+	assertNoError(t, roller.CreateRoom(testContext(owner), "room"))
+	ownerRooms, err := roller.ListRooms(testContext(owner))
+	assertNoError(t, err)
+	assertEqual(t, 1, len(ownerRooms))
+	roomID := ownerRooms[0].ID
+
+	assertNoError(t, roller.CreatePlace(testContext(creator), roomID, "place"))
+	places, err := roller.ListPlaces(testContext(creator), roomID, time.Now())
+	assertNoError(t, err)
+	placeID := places[0].ID
+
+	assertError(t, ErrNotAllowed, roller.DeletePlace(testContext(stranger), roomID, placeID))
+	assertNoError(t, roller.DeletePlace(testContext(owner), roomID, placeID))
+
+	// the place still belongs to its creator
+	place := <-deleted
+	assertEqual(t, creator.ID, place.User.ID)
+	assertEqual(t, owner.ID, place.Actor.ID)
+
+	_, err = roller.ListPlaces(testContext(creator), roomID, time.Now())
+	assertError(t, ErrNoPlaces, err)
+
+	assertError(t, ErrNotAllowed, roller.RestorePlace(testContext(stranger), roomID, placeID))
+	assertNoError(t, roller.RestorePlace(testContext(creator), roomID, placeID))
+
+	places, err = roller.ListPlaces(testContext(creator), roomID, time.Now())
+	assertNoError(t, err)
+	assertEqual(t, 1, len(places))
+}
+
+func TestBindChannel(t *testing.T) {
+	t.Parallel()
+
+	owner := testUser()
+	// binds happen one after another, the latest one wins
+	now := time.Now()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(testContext(owner), "room"))
+	rr, err := roller.ListRooms(testContext(owner))
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	now = now.Add(time.Second)
+	assertNoError(t, roller.BindChannel(testContext(owner), roomID, "channel", now))
+	now = now.Add(time.Second)
+	assertError(t, ErrNotAllowed, roller.BindChannel(testContext(testUser()), roomID, "channel", now))
+
+	// a teams channel with the same id can't take the slack one over
+	now = now.Add(time.Second)
+	assertError(t, ErrAlreadyExists, roller.BindTeamsChannel(testContext(owner), roomID, "channel", "sealed-url", now))
+	channel, err := roller.GetChannel(testContext(owner), "channel")
+	assertNoError(t, err)
+	assertEqual(t, channels.PlatformSlack, channel.Platform)
+
+	// members of other rooms can't move the channel to their room
+	other := testUser()
+	assertNoError(t, roller.CreateRoom(testContext(other), "other"))
+	rr, err = roller.ListRooms(testContext(other))
+	assertNoError(t, err)
+	otherRoomID := rr[0].ID
+	now = now.Add(time.Second)
+	assertError(t, ErrNotAllowed, roller.BindChannel(testContext(other), otherRoomID, "channel", now))
+
+	// unless they are members of the current room
+	assertNoError(t, roller.JoinRoom(testContext(other), roomID))
+	now = now.Add(time.Second)
+	assertNoError(t, roller.BindChannel(testContext(other), otherRoomID, "channel", now))
+	now = now.Add(time.Second)
+	assertError(t, ErrNotAllowed, roller.BindChannel(testContext(owner), roomID, "channel", now))
+
+	// or the ones who bound it
+	assertNoError(t, roller.LeaveRoom(testContext(other), otherRoomID))
+	now = now.Add(time.Second)
+	assertNoError(t, roller.BindChannel(testContext(other), roomID, "channel", now))
+	channel, err = roller.GetChannel(testContext(owner), "channel")
+	assertNoError(t, err)
+	assertEqual(t, roomID, channel.RoomID)
+}
+
+func TestRoll_roomStrategy(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+	oneDay := 24 * time.Hour
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	settings := rooms.DefaultSettings()
+	settings.Strategy = rooms.StrategyNoRepeat
+	assertError(t, ErrInvalid, roller.UpdateRoomSettings(ctx, roomID, settings))
+	settings.NoRepeatDays = 2
+	settings.Timezone = "Mars/Olympus_Mons"
+	assertError(t, ErrInvalid, roller.UpdateRoomSettings(ctx, roomID, settings))
+	settings.Timezone = "Europe/Stockholm"
+	settings.BoostMultiplier = math.NaN()
+	assertError(t, ErrInvalid, roller.UpdateRoomSettings(ctx, roomID, settings))
+	settings.BoostMultiplier = 2
+	settings.UndoMinutes = math.Inf(1)
+	assertError(t, ErrInvalid, roller.UpdateRoomSettings(ctx, roomID, settings))
+	settings.UndoMinutes = 5
+	assertNoError(t, roller.UpdateRoomSettings(ctx, roomID, settings))
+	assertError(t, ErrNotAllowed, roller.UpdateRoomSettings(testContext(testUser()), roomID, rooms.DefaultSettings()))
+
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	_, err = roller.CreateRoll(ctx, roomID, today)
+	assertNoError(t, err)
+
+	places, err := roller.ListPlaces(ctx, roomID, today.Add(oneDay))
+	assertNoError(t, err)
+	assertEqual(t, 0.0, places[0].Chance)
+	// a boost wouldn't change the chance
+	assertError(t, ErrNotAllowed, roller.CreateBoost(ctx, roomID, places[0].ID, today.Add(oneDay)))
+
+	_, err = roller.CreateRoll(ctx, roomID, today.Add(oneDay))
+	assertError(t, ErrNoPlaces, err)
+
+	_, err = roller.CreateRoll(ctx, roomID, today.Add(2*oneDay))
+	assertNoError(t, err)
+}
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+	nextMonday := time.Date(2021, time.September, 13, 0, 0, 0, 0, time.UTC)
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "UTC"
+	assertNoError(t, roller.UpdateRoomSettings(ctx, roomID, settings))
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	quota, err := roller.Quota(ctx, roomID, today)
+	assertNoError(t, err)
+	assertEqual(t, &Quota{RoomID: roomID, Points: 1, FreeRoll: true, ResetsAt: nextMonday}, quota)
+
+	_, err = roller.CreateRoll(ctx, roomID, today)
+	assertNoError(t, err)
+
+	quota, err = roller.Quota(ctx, roomID, today)
+	assertNoError(t, err)
+	assertEqual(t, &Quota{RoomID: roomID, Points: 1, FreeRoll: false, ResetsAt: nextMonday}, quota)
+
+	_, err = roller.CreateRoll(ctx, roomID, today.Add(time.Minute))
+	assertNoError(t, err)
+
+	quota, err = roller.Quota(ctx, roomID, today)
+	assertNoError(t, err)
+	assertEqual(t, &Quota{RoomID: roomID, Points: 0, FreeRoll: false, ResetsAt: nextMonday}, quota)
+
+	quota, err = roller.Quota(testContext(testUser()), roomID, today)
+	assertNoError(t, err)
+	assertEqual(t, &Quota{RoomID: roomID, Points: 1, FreeRoll: false, ResetsAt: nextMonday}, quota)
+}
+
+func TestUndo(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	assertError(t, ErrNotFound, roller.Undo(ctx, roomID, today))
+
+	_, err = roller.CreateRoll(ctx, roomID, today)
+	assertNoError(t, err)
+	_, err = roller.CreateRoll(ctx, roomID, today.Add(time.Minute))
+	assertNoError(t, err)
+
+	// only own actions can be undone
+	assertError(t, ErrNotFound, roller.Undo(testContext(testUser()), roomID, today.Add(time.Minute)))
+
+	// reroll is undone, and the point is refunded
+	assertNoError(t, roller.Undo(ctx, roomID, today.Add(2*time.Minute)))
+	quota, err := roller.Quota(ctx, roomID, today.Add(2*time.Minute))
+	assertNoError(t, err)
+	assertEqual(t, 1, quota.Points)
+	rolls, err := roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(rolls))
+
+	// first roll is too old to undo
+	assertError(t, ErrNotAllowed, roller.Undo(ctx, roomID, today.Add(3*time.Minute)))
+
+	places, err := roller.ListPlaces(ctx, roomID, today)
+	assertNoError(t, err)
+	assertNoError(t, roller.CreateBoost(ctx, roomID, places[0].ID, today.Add(10*time.Minute)))
+	assertNoError(t, roller.Undo(ctx, roomID, today.Add(11*time.Minute)))
+	quota, err = roller.Quota(ctx, roomID, today.Add(11*time.Minute))
+	assertNoError(t, err)
+	assertEqual(t, 1, quota.Points)
+	boosts, err := roller.ListBoosts(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 0, len(boosts))
+}
+
+func TestRoll_everythingVetoed(t *testing.T) {
+	t.Parallel()
+
+	today := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+
+	ctx := testContext(testUser())
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place1"))
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place2"))
+
+	places, err := roller.ListPlaces(ctx, roomID, today)
+	assertNoError(t, err)
+	vetoed, other := places[0].ID, places[1].ID
+
+	assertNoError(t, roller.CreateVeto(ctx, roomID, vetoed, today))
+	assertError(t, ErrNoPoints, roller.CreateVeto(ctx, roomID, other, today.Add(time.Second)))
+
+	places, err = roller.ListPlaces(ctx, roomID, today)
+	assertNoError(t, err)
+	for _, place := range places {
+		if place.ID == other {
+			assertEqual(t, 1.0, place.Chance)
+		} else {
+			assertEqual(t, 0.0, place.Chance)
+		}
+	}
+
+	assertNoError(t, roller.CreateVeto(testContext(testUser()), roomID, other, today.Add(time.Minute)))
+
+	_, err = roller.CreateRoll(ctx, roomID, today.Add(2*time.Minute))
+	assertError(t, ErrNoPlaces, err)
+}
+
 var userID *int64 = new(int64)
 
 func testUser() *users.User {
@@ -214,3 +609,59 @@ func assertEqual(t *testing.T, expected, got interface{}) {
 		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
 	}
 }
+
+func TestWeightedRandom(t *testing.T) {
+	t.Parallel()
+
+	random := rand.New(rand.NewSource(1))
+
+	id, err := weightedRandom(random, map[places.ID]float64{"a": 0, "b": 1})
+	assertNoError(t, err)
+	assertEqual(t, places.ID("b"), id)
+
+	_, err = weightedRandom(random, map[places.ID]float64{"a": math.NaN(), "b": 1})
+	assertError(t, ErrInvalid, err)
+	_, err = weightedRandom(random, map[places.ID]float64{"a": math.Inf(1)})
+	assertError(t, ErrInvalid, err)
+}
+
+func TestToday(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	user := testUser()
+	ctx := testContext(user)
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "Europe/Stockholm"
+	settings.Strategy = rooms.StrategyUniform
+	settings.Points = 5
+	assertNoError(t, roller.UpdateRoomSettings(ctx, roomID, settings))
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	// 23:00 on sunday in Stockholm
+	_, err = roller.CreateRoll(ctx, roomID, monday.Add(-15*time.Hour))
+	assertNoError(t, err)
+	// sunday in UTC, but monday in Stockholm
+	_, err = roller.CreateRoll(ctx, roomID, monday.Add(-13*time.Hour))
+	assertNoError(t, err)
+	_, err = roller.CreateRoll(ctx, roomID, monday)
+	assertNoError(t, err)
+
+	day, err := roller.Today(ctx, roomID, monday)
+	assertNoError(t, err)
+	assertEqual(t, "2021-09-06", day.Date)
+	assertEqual(t, 2, len(day.Rolls))
+	assertEqual(t, true, monday.Equal(day.Rolls[1].Time))
+}
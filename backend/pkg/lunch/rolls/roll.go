@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/polls"
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/users"
 )
@@ -13,6 +14,8 @@ type Roll struct {
 	PlaceID places.ID `dynamodbav:"place_id" json:"placeId"`
 	Time    time.Time `dynamodbav:"time,unixtime" json:"time"`
 	RoomID  rooms.ID  `json:"roomId"`
+	// PollID is set if the place was voted for instead of rolled.
+	PollID polls.ID `json:"pollId,omitempty"`
 }
 
 func NewRoll(userID users.ID, roomID rooms.ID, placeID places.ID, now time.Time) *Roll {
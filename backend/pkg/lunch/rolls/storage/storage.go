@@ -6,14 +6,27 @@ import (
 	"time"
 
 	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/polls"
 	"lunch/pkg/lunch/rolls"
 	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
 )
 
 var (
-	rollCreated events.Type = "rolls/created"
+	rollCreated  events.Type = "rolls/created"
+	rollReverted events.Type = "rolls/reverted"
 )
 
+// created is the payload of the created event, rolls of places voted for in a poll have one.
+type created struct {
+	PollID polls.ID `json:"pollId"`
+}
+
+// revert is the payload of the reverted event, it points to the roll by its time.
+type revert struct {
+	Time events.UnixNanoTime `json:"time"`
+}
+
 type Storage struct {
 	eventsStorage events.Storage
 }
@@ -25,27 +38,102 @@ func New(eventsStorage events.Storage) *Storage {
 }
 
 func (s *Storage) Create(ctx context.Context, roll *rolls.Roll) error {
-	return s.eventsStorage.Create(ctx, &events.Event{
+	event := &events.Event{
 		UserID:    roll.UserID,
 		PlaceID:   roll.PlaceID,
 		RoomID:    roll.RoomID,
 		Type:      rollCreated,
 		Timestamp: events.UnixNanoTime(roll.Time),
-	})
+	}
+	if roll.PollID != "" {
+		if err := event.MarshalPayload(&created{PollID: roll.PollID}); err != nil {
+			return err
+		}
+	}
+	return s.eventsStorage.Create(ctx, event)
+}
+
+// Revert marks the roll as reverted, so it's not returned anymore.
+func (s *Storage) Revert(ctx context.Context, roll *rolls.Roll, now time.Time) error {
+	event := &events.Event{
+		UserID:    roll.UserID,
+		PlaceID:   roll.PlaceID,
+		RoomID:    roll.RoomID,
+		Type:      rollReverted,
+		Timestamp: events.UnixNanoTime(now),
+	}
+	if err := event.MarshalPayload(&revert{Time: events.UnixNanoTime(roll.Time)}); err != nil {
+		return err
+	}
+	return s.eventsStorage.Create(ctx, event)
 }
 
 func (s *Storage) Rolls(ctx context.Context, roomID rooms.ID) ([]*rolls.Roll, error) {
-	events, err := s.eventsStorage.ByRoomID(ctx, roomID, rollCreated)
+	events, err := s.eventsStorage.ByRoomID(ctx, roomID, rollCreated, rollReverted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
+
+	type key struct {
+		UserID users.ID
+		Time   int64
+	}
+	reverted := map[key]bool{}
+	for _, event := range events {
+		roll, ok, err := Reverted(event)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			reverted[key{UserID: roll.UserID, Time: roll.Time.UnixNano()}] = true
+		}
+	}
+
 	result := make([]*rolls.Roll, 0, len(events))
 	for _, event := range events {
-		result = append(result, &rolls.Roll{
-			UserID:  event.UserID,
-			PlaceID: event.PlaceID,
-			Time:    time.Time(event.Timestamp),
-		})
+		roll, ok, err := Created(event)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || roll.PollID == "" && reverted[key{UserID: roll.UserID, Time: roll.Time.UnixNano()}] {
+			continue
+		}
+		result = append(result, roll)
 	}
 	return result, nil
 }
+
+// Created returns the roll recorded by the event.
+func Created(event *events.Event) (*rolls.Roll, bool, error) {
+	if event.Type != rollCreated {
+		return nil, false, nil
+	}
+	payload := &created{}
+	if err := event.UnmarshalPayload(payload); err != nil {
+		return nil, false, err
+	}
+	return &rolls.Roll{
+		UserID:  event.UserID,
+		PlaceID: event.PlaceID,
+		RoomID:  event.RoomID,
+		Time:    time.Time(event.Timestamp),
+		PollID:  payload.PollID,
+	}, true, nil
+}
+
+// Reverted returns the roll reverted by the event.
+func Reverted(event *events.Event) (*rolls.Roll, bool, error) {
+	if event.Type != rollReverted {
+		return nil, false, nil
+	}
+	payload := &revert{}
+	if err := event.UnmarshalPayload(payload); err != nil {
+		return nil, false, err
+	}
+	return &rolls.Roll{
+		UserID:  event.UserID,
+		PlaceID: event.PlaceID,
+		RoomID:  event.RoomID,
+		Time:    time.Time(payload.Time),
+	}, true, nil
+}
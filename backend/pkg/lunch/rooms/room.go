@@ -16,6 +16,7 @@ type Room struct {
 	UserID    users.ID          `json:"userId"`
 	Time      time.Time         `json:"time"`
 	MemberIDs map[users.ID]bool `json:"memberIds"`
+	Settings  Settings          `json:"settings"`
 }
 
 func New(userID users.ID, name string) *Room {
@@ -27,5 +28,6 @@ func New(userID users.ID, name string) *Room {
 		MemberIDs: map[users.ID]bool{
 			userID: true,
 		},
+		Settings: DefaultSettings(),
 	}
 }
@@ -0,0 +1,169 @@
+package rooms
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnknownSetting = fmt.Errorf("unknown setting")
+)
+
+type Strategy string
+
+const (
+	// StrategyLinear makes recently rolled places less likely, linearly recovering over a number of days
+	// equal to the number of places.
+	StrategyLinear Strategy = "linear"
+	// StrategyExponential makes recently rolled places less likely, recovering with a configurable half-life.
+	StrategyExponential Strategy = "exponential"
+	// StrategyUniform gives every place the same chance.
+	StrategyUniform Strategy = "uniform"
+	// StrategyNoRepeat excludes places rolled within the last configurable number of days.
+	StrategyNoRepeat Strategy = "no_repeat"
+)
+
+// Period is how often points are reset.
+type Period string
+
+const (
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+// Settings are room specific rules.
+type Settings struct {
+	Strategy Strategy `json:"strategy"`
+	// HalfLifeDays is used by the exponential strategy.
+	HalfLifeDays float64 `json:"halfLifeDays,omitempty"`
+	// NoRepeatDays is used by the no repeat strategy.
+	NoRepeatDays int `json:"noRepeatDays,omitempty"`
+
+	// Points is how many points every member has per period. Boosts and rerolls cost one point.
+	Points int `json:"points"`
+	// Period is how often points are reset.
+	Period Period `json:"period"`
+	// BoostMultiplier is how much every active boost multiplies the place weight.
+	BoostMultiplier float64 `json:"boostMultiplier"`
+	// FreeDailyRoll makes the first roll of the day free for anyone.
+	FreeDailyRoll bool `json:"freeDailyRoll"`
+	// UndoMinutes is for how long after a roll or a boost it can be undone.
+	UndoMinutes float64 `json:"undoMinutes"`
+	// Timezone is an IANA time zone name days and periods are counted in. Empty means the server time zone.
+	Timezone string `json:"timezone,omitempty"`
+	// ReminderMinutes is how many minutes before the scheduled roll members are reminded. Zero means no reminders.
+	ReminderMinutes int `json:"reminderMinutes,omitempty"`
+	// NotifyChannel posts notifications to channels bound to the room, instead of direct messages to members.
+	NotifyChannel bool `json:"notifyChannel,omitempty"`
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		Strategy:        StrategyLinear,
+		Points:          1,
+		Period:          PeriodWeek,
+		BoostMultiplier: 5,
+		FreeDailyRoll:   true,
+		UndoMinutes:     2,
+	}
+}
+
+// UndoWindow returns for how long after a roll or a boost it can be undone.
+func (s Settings) UndoWindow() time.Duration {
+	return time.Duration(s.UndoMinutes * float64(time.Minute))
+}
+
+// ReminderBefore returns how long before the scheduled roll members are reminded.
+func (s Settings) ReminderBefore() time.Duration {
+	return time.Duration(s.ReminderMinutes) * time.Minute
+}
+
+// Location returns the time zone of the room, falling back to the server time zone.
+func (s Settings) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Set parses the value and assigns it to the setting with the given json name.
+func (s *Settings) Set(key, value string) error {
+	switch key {
+	case "strategy":
+		s.Strategy = Strategy(value)
+	case "halfLifeDays":
+		halfLifeDays, err := parseFloat(key, value)
+		if err != nil {
+			return err
+		}
+		s.HalfLifeDays = halfLifeDays
+	case "noRepeatDays":
+		noRepeatDays, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a number", key)
+		}
+		s.NoRepeatDays = noRepeatDays
+	case "points":
+		points, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a number", key)
+		}
+		s.Points = points
+	case "period":
+		s.Period = Period(value)
+	case "boostMultiplier":
+		boostMultiplier, err := parseFloat(key, value)
+		if err != nil {
+			return err
+		}
+		s.BoostMultiplier = boostMultiplier
+	case "freeDailyRoll":
+		freeDailyRoll, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a boolean", key)
+		}
+		s.FreeDailyRoll = freeDailyRoll
+	case "undoMinutes":
+		undoMinutes, err := parseFloat(key, value)
+		if err != nil {
+			return err
+		}
+		s.UndoMinutes = undoMinutes
+	case "timezone":
+		if _, err := time.LoadLocation(value); err != nil {
+			return fmt.Errorf("'%s' must be an IANA time zone, like Europe/Stockholm", key)
+		}
+		s.Timezone = value
+	case "reminderMinutes":
+		reminderMinutes, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a number", key)
+		}
+		s.ReminderMinutes = reminderMinutes
+	case "notifyChannel":
+		notifyChannel, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a boolean", key)
+		}
+		s.NotifyChannel = notifyChannel
+	default:
+		return fmt.Errorf("'%s': %w", key, ErrUnknownSetting)
+	}
+	return nil
+}
+
+// parseFloat parses a finite number. NaN and infinities would pass any limit of a setting, as comparisons with
+// NaN are false.
+func parseFloat(key, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("'%s' must be a number", key)
+	}
+	return f, nil
+}
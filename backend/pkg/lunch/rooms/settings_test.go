@@ -0,0 +1,32 @@
+package rooms
+
+import "testing"
+
+func TestSettings_Set(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		key, value string
+		valid      bool
+	}{
+		{"halfLifeDays", "2.5", true},
+		{"halfLifeDays", "NaN", false},
+		{"boostMultiplier", "1.5", true},
+		{"boostMultiplier", "NaN", false},
+		{"boostMultiplier", "Inf", false},
+		{"undoMinutes", "5", true},
+		{"undoMinutes", "-Inf", false},
+		{"points", "three", false},
+	}
+
+	for _, testCase := range testCases {
+		settings := DefaultSettings()
+		err := settings.Set(testCase.key, testCase.value)
+		if testCase.valid && err != nil {
+			t.Errorf("%s=%s: unexpected error: %s", testCase.key, testCase.value, err)
+		}
+		if !testCase.valid && err == nil {
+			t.Errorf("%s=%s: expected an error", testCase.key, testCase.value)
+		}
+	}
+}
@@ -9,8 +9,6 @@ import (
 	"lunch/pkg/lunch/events"
 	"lunch/pkg/lunch/rooms"
 	"lunch/pkg/users"
-
-	"github.com/google/uuid"
 )
 
 var (
@@ -21,6 +19,18 @@ const (
 	roomCreated events.Type = "rooms/created"
 	roomJoined  events.Type = "rooms/joined"
 	roomLeft    events.Type = "rooms/left"
+
+	roomSettingsUpdated events.Type = "rooms/settings_updated"
+)
+
+// Change is what an event did to a room.
+type Change string
+
+const (
+	ChangeCreated         Change = "created"
+	ChangeJoined          Change = "joined"
+	ChangeLeft            Change = "left"
+	ChangeSettingsUpdated Change = "settings_updated"
 )
 
 type Storage struct {
@@ -38,7 +48,7 @@ func (s *Storage) Create(ctx context.Context, room *rooms.Room) error {
 		UserID:    room.UserID,
 		Timestamp: events.UnixNanoTime(room.Time),
 		Type:      roomCreated,
-		RoomID:    rooms.ID(uuid.NewString()),
+		RoomID:    room.ID,
 		Name:      room.Name,
 	})
 }
@@ -61,8 +71,21 @@ func (s *Storage) Leave(ctx context.Context, user *users.User, roomID rooms.ID)
 	})
 }
 
+func (s *Storage) UpdateSettings(ctx context.Context, user *users.User, roomID rooms.ID, settings rooms.Settings) error {
+	event := &events.Event{
+		UserID:    user.ID,
+		Timestamp: events.UnixNanoTime(time.Now()),
+		Type:      roomSettingsUpdated,
+		RoomID:    roomID,
+	}
+	if err := event.MarshalPayload(settings); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
 func (s *Storage) Room(ctx context.Context, roomID rooms.ID) (*rooms.Room, error) {
-	events, err := s.storage.ByRoomID(ctx, roomID, roomCreated, roomJoined, roomLeft)
+	events, err := s.storage.ByRoomID(ctx, roomID, roomCreated, roomJoined, roomLeft, roomSettingsUpdated)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
@@ -76,6 +99,7 @@ func (s *Storage) Room(ctx context.Context, roomID rooms.ID) (*rooms.Room, error
 	})
 
 	memberIDs := make(map[users.ID]bool)
+	settings := rooms.DefaultSettings()
 	for _, event := range events {
 		switch event.Type {
 		case roomCreated:
@@ -84,6 +108,11 @@ func (s *Storage) Room(ctx context.Context, roomID rooms.ID) (*rooms.Room, error
 			memberIDs[event.UserID] = true
 		case roomLeft:
 			delete(memberIDs, event.UserID)
+		case roomSettingsUpdated:
+			settings = rooms.DefaultSettings()
+			if err := event.UnmarshalPayload(&settings); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -93,6 +122,7 @@ func (s *Storage) Room(ctx context.Context, roomID rooms.ID) (*rooms.Room, error
 		UserID:    events[0].UserID,
 		Time:      time.Time(events[0].Timestamp),
 		MemberIDs: memberIDs,
+		Settings:  settings,
 	}, nil
 }
 
@@ -117,3 +147,19 @@ func (s *Storage) Rooms(ctx context.Context, userID users.ID) (map[rooms.ID]bool
 	}
 	return result, nil
 }
+
+// RoomChange returns what the event did to the room with the event room id.
+func RoomChange(event *events.Event) (Change, bool) {
+	switch event.Type {
+	case roomCreated:
+		return ChangeCreated, true
+	case roomJoined:
+		return ChangeJoined, true
+	case roomLeft:
+		return ChangeLeft, true
+	case roomSettingsUpdated:
+		return ChangeSettingsUpdated, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,83 @@
+package lunch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/schedules"
+	storage_schedules "lunch/pkg/lunch/schedules/storage"
+	"lunch/pkg/users"
+)
+
+// UpdateSchedule sets when the room is rolled automatically. Empty weekdays turn the schedule off.
+// Only the room owner can change the schedule.
+func (r *Roller) UpdateSchedule(ctx context.Context, roomID rooms.ID, hour, minute int, weekdays []time.Weekday, now time.Time) (*schedules.Schedule, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return nil, fmt.Errorf("%02d:%02d is not a valid time: %w", hour, minute, ErrInvalid)
+	}
+
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.UserID != user.ID {
+		return nil, fmt.Errorf("only room owner can change the schedule: %w", ErrNotAllowed)
+	}
+
+	schedule := schedules.New(roomID, user.ID, hour, minute, weekdays, now)
+	if err := r.schedulesStore.Update(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// GetSchedule returns when the room is rolled automatically.
+func (r *Roller) GetSchedule(ctx context.Context, roomID rooms.ID) (*schedules.Schedule, error) {
+	schedule, err := r.schedulesStore.Schedule(ctx, roomID)
+	if errors.Is(err, storage_schedules.ErrNotFound) {
+		return nil, fmt.Errorf("schedule not found: %w", ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DueRooms returns rooms that are scheduled to be rolled in (from, to], in their time zones.
+func (r *Roller) DueRooms(ctx context.Context, from, to time.Time) ([]rooms.ID, error) {
+	allSchedules, err := r.schedulesStore.Schedules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	result := []rooms.ID{}
+	for _, schedule := range allSchedules {
+		settings, err := r.roomSettings(ctx, schedule.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if schedule.Due(from, to, settings.Location()) {
+			result = append(result, schedule.RoomID)
+		}
+	}
+	return result, nil
+}
+
+// RolledToday returns true if anyone rolled in the room today, in the room time zone.
+func (r *Roller) RolledToday(ctx context.Context, roomID rooms.ID, now time.Time) (bool, error) {
+	history, err := r.history(ctx, roomID, now)
+	if err != nil {
+		return false, err
+	}
+	today := dateOf(now.In(history.Settings.Location()))
+	return len(history.ThisPeriodRollsBy[today]) > 0, nil
+}
@@ -0,0 +1,115 @@
+package schedules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+// Schedule is when a room is rolled automatically.
+type Schedule struct {
+	RoomID rooms.ID `json:"roomId"`
+	UserID users.ID `json:"userId"`
+	// Hour and Minute are the local time of the roll, in the room time zone.
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+	// Weekdays are days of the week to roll on. Empty means the schedule is off.
+	Weekdays []time.Weekday `json:"weekdays"`
+	Time     time.Time      `json:"time"`
+}
+
+func New(roomID rooms.ID, userID users.ID, hour, minute int, weekdays []time.Weekday, now time.Time) *Schedule {
+	return &Schedule{
+		RoomID:   roomID,
+		UserID:   userID,
+		Hour:     hour,
+		Minute:   minute,
+		Weekdays: weekdays,
+		Time:     now,
+	}
+}
+
+// Due returns true if the schedule has a roll in (from, to], with the local time taken in loc.
+func (s *Schedule) Due(from, to time.Time, loc *time.Location) bool {
+	next, ok := s.Next(from, loc)
+	return ok && !next.After(to)
+}
+
+// Next returns the first roll after now, with the local time taken in loc. It returns false if the schedule is off.
+func (s *Schedule) Next(now time.Time, loc *time.Location) (time.Time, bool) {
+	now = now.In(loc)
+	year, month, day := now.Date()
+	// a week and a day, in case today's roll has already happened
+	for i := 0; i <= 7; i++ {
+		d := time.Date(year, month, day+i, 0, 0, 0, 0, loc)
+		if !s.isOn(d.Weekday()) {
+			continue
+		}
+		at := time.Date(d.Year(), d.Month(), d.Day(), s.Hour, s.Minute, 0, 0, loc)
+		if at.After(now) {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *Schedule) isOn(weekday time.Weekday) bool {
+	for _, w := range s.Weekdays {
+		if w == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the schedule in the same format as ParseTime and ParseWeekdays accept.
+func (s *Schedule) String() string {
+	if len(s.Weekdays) == 0 {
+		return "off"
+	}
+	days := make([]string, 0, len(s.Weekdays))
+	for _, w := range s.Weekdays {
+		days = append(days, strings.ToLower(w.String()[:3]))
+	}
+	return fmt.Sprintf("%02d:%02d %s", s.Hour, s.Minute, strings.Join(days, ","))
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseTime parses local time of the day, like 11:30.
+func ParseTime(value string) (int, int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("'%s' must be a time like 11:30", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ParseWeekdays parses comma separated days of the week, like mon,tue,wed.
+func ParseWeekdays(value string) ([]time.Weekday, error) {
+	result := []time.Weekday{}
+	seen := map[time.Weekday]bool{}
+	for _, name := range strings.Split(value, ",") {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("'%s' must be a day of the week, like mon", name)
+		}
+		if seen[weekday] {
+			continue
+		}
+		seen[weekday] = true
+		result = append(result, weekday)
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package schedules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDue(t *testing.T) {
+	t.Parallel()
+
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	schedule := New("room", "user", 11, 30, weekdays, time.Time{})
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, stockholm)
+	saturday := time.Date(2021, time.September, 11, 0, 0, 0, 0, stockholm)
+
+	testCases := []struct {
+		name     string
+		from, to time.Time
+		loc      *time.Location
+		expected bool
+	}{
+		{
+			name:     "minute of the roll",
+			from:     monday.Add(11*time.Hour + 29*time.Minute),
+			to:       monday.Add(11*time.Hour + 30*time.Minute),
+			loc:      stockholm,
+			expected: true,
+		},
+		{
+			name:     "right after the roll",
+			from:     monday.Add(11*time.Hour + 30*time.Minute),
+			to:       monday.Add(11*time.Hour + 31*time.Minute),
+			loc:      stockholm,
+			expected: false,
+		},
+		{
+			name:     "day off",
+			from:     saturday.Add(11*time.Hour + 29*time.Minute),
+			to:       saturday.Add(11*time.Hour + 30*time.Minute),
+			loc:      stockholm,
+			expected: false,
+		},
+		{
+			name:     "time zone of the room",
+			from:     monday.Add(11*time.Hour + 29*time.Minute),
+			to:       monday.Add(11*time.Hour + 30*time.Minute),
+			loc:      time.UTC,
+			expected: false,
+		},
+		{
+			name:     "interval over midnight",
+			from:     monday.Add(-time.Hour),
+			to:       monday.Add(12 * time.Hour),
+			loc:      stockholm,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertEqual(t, tc.expected, schedule.Due(tc.from, tc.to, tc.loc))
+		})
+	}
+}
+
+func TestParseWeekdays(t *testing.T) {
+	t.Parallel()
+
+	weekdays, err := ParseWeekdays("mon,Tue, fri,mon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, []time.Weekday{time.Monday, time.Tuesday, time.Friday}, weekdays)
+
+	_, err = ParseWeekdays("monday")
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/schedules"
+)
+
+var (
+	ErrNotFound = fmt.Errorf("not found")
+)
+
+const (
+	scheduleUpdated events.Type = "schedules/updated"
+)
+
+type Storage struct {
+	storage events.Storage
+}
+
+func New(storage events.Storage) *Storage {
+	return &Storage{
+		storage: storage,
+	}
+}
+
+// Update replaces the schedule of the room.
+func (s *Storage) Update(ctx context.Context, schedule *schedules.Schedule) error {
+	event := &events.Event{
+		UserID:    schedule.UserID,
+		RoomID:    schedule.RoomID,
+		Timestamp: events.UnixNanoTime(schedule.Time),
+		Type:      scheduleUpdated,
+	}
+	if err := event.MarshalPayload(schedule); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Schedule returns the schedule of the room.
+func (s *Storage) Schedule(ctx context.Context, roomID rooms.ID) (*schedules.Schedule, error) {
+	events, err := s.storage.ByRoomID(ctx, roomID, scheduleUpdated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	replayed, err := replay(events)
+	if err != nil {
+		return nil, err
+	}
+	schedule, ok := replayed[roomID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return schedule, nil
+}
+
+// Schedules returns schedules of all rooms that are on.
+func (s *Storage) Schedules(ctx context.Context) ([]*schedules.Schedule, error) {
+	events, err := s.storage.ByType(ctx, scheduleUpdated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	replayed, err := replay(events)
+	if err != nil {
+		return nil, err
+	}
+	result := []*schedules.Schedule{}
+	for _, schedule := range replayed {
+		if len(schedule.Weekdays) == 0 {
+			continue
+		}
+		result = append(result, schedule)
+	}
+	return result, nil
+}
+
+func replay(ee []*events.Event) (map[rooms.ID]*schedules.Schedule, error) {
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+	result := map[rooms.ID]*schedules.Schedule{}
+	for _, event := range ee {
+		schedule := &schedules.Schedule{}
+		if err := event.UnmarshalPayload(schedule); err != nil {
+			return nil, err
+		}
+		schedule.RoomID = event.RoomID
+		schedule.UserID = event.UserID
+		schedule.Time = time.Time(event.Timestamp)
+		result[event.RoomID] = schedule
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/vetoes"
+)
+
+var (
+	vetoCreated events.Type = "vetoes/created"
+)
+
+type Storage struct {
+	eventsStorage events.Storage
+}
+
+func New(eventsStorage events.Storage) *Storage {
+	return &Storage{
+		eventsStorage: eventsStorage,
+	}
+}
+
+func (s *Storage) Create(ctx context.Context, veto *vetoes.Veto) error {
+	return s.eventsStorage.Create(ctx, &events.Event{
+		UserID:    veto.UserID,
+		PlaceID:   veto.PlaceID,
+		RoomID:    veto.RoomID,
+		Type:      vetoCreated,
+		Timestamp: events.UnixNanoTime(veto.Time),
+	})
+}
+
+func (s *Storage) Vetoes(ctx context.Context, roomID rooms.ID) ([]*vetoes.Veto, error) {
+	events, err := s.eventsStorage.ByRoomID(ctx, roomID, vetoCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	result := make([]*vetoes.Veto, 0, len(events))
+	for _, event := range events {
+		if veto, ok := Created(event); ok {
+			result = append(result, veto)
+		}
+	}
+	return result, nil
+}
+
+// Created returns the veto recorded by the event.
+func Created(event *events.Event) (*vetoes.Veto, bool) {
+	if event.Type != vetoCreated {
+		return nil, false
+	}
+	return &vetoes.Veto{
+		UserID:  event.UserID,
+		PlaceID: event.PlaceID,
+		RoomID:  event.RoomID,
+		Time:    time.Time(event.Timestamp),
+	}, true
+}
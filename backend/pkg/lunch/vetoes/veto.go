@@ -0,0 +1,25 @@
+package vetoes
+
+import (
+	"time"
+
+	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+type Veto struct {
+	UserID  users.ID  `json:"userId"`
+	PlaceID places.ID `json:"placeId"`
+	Time    time.Time `json:"time"`
+	RoomID  rooms.ID  `json:"roomId"`
+}
+
+func NewVeto(userID users.ID, roomID rooms.ID, placeID places.ID, now time.Time) *Veto {
+	return &Veto{
+		UserID:  userID,
+		PlaceID: placeID,
+		RoomID:  roomID,
+		Time:    now,
+	}
+}
@@ -1,10 +1,16 @@
 package lunch
 
 import (
+	"time"
+
 	"lunch/pkg/lunch/boosts"
 	"lunch/pkg/lunch/places"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/reminders"
 	"lunch/pkg/lunch/rolls"
 	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/vetoes"
+	"lunch/pkg/lunch/webhooks"
 	"lunch/pkg/users"
 )
 
@@ -12,6 +18,8 @@ type Place struct {
 	*places.Place
 	User   *users.User `json:"user"`
 	Chance float64     `json:"chance"`
+	// Actor is who created, changed, deleted or restored the place, it's set in place events only.
+	Actor *users.User `json:"actor,omitempty"`
 }
 
 type Boost struct {
@@ -20,14 +28,65 @@ type Boost struct {
 	Place *places.Place `json:"place"`
 }
 
+type Veto struct {
+	*vetoes.Veto
+	User  *users.User   `json:"user"`
+	Place *places.Place `json:"place"`
+}
+
 type Roll struct {
 	*rolls.Roll
 	User  *users.User   `json:"user"`
 	Place *places.Place `json:"place"`
 }
 
+type Poll struct {
+	*polls.Poll
+	User *users.User `json:"user"`
+	// Places are places that can be voted for.
+	Places []*places.Place `json:"places"`
+	// Votes is the current tally of the poll.
+	Votes map[places.ID]int `json:"votes"`
+}
+
+type Reminder struct {
+	*reminders.Reminder
+	Room *rooms.Room `json:"room"`
+	// Users are room members who didn't turn reminders off.
+	Users []*users.User `json:"users"`
+}
+
 type Room struct {
 	*rooms.Room
 	User    *users.User   `json:"user"`
 	Members []*users.User `json:"members"`
 }
+
+// Delivery is a webhook delivery with the webhook it's sent to.
+type Delivery struct {
+	*webhooks.Delivery
+	Webhook *webhooks.Webhook `json:"webhook"`
+}
+
+// Quota is how many points a user has left in a room.
+type Quota struct {
+	RoomID rooms.ID `json:"roomId"`
+	// Points is how many points are left this period.
+	Points int `json:"points"`
+	// FreeRoll is true if nobody rolled today, and the first roll of the day is free.
+	FreeRoll bool `json:"freeRoll"`
+	// ResetsAt is when points are reset.
+	ResetsAt time.Time `json:"resetsAt"`
+}
+
+const dayLayout = "2006-01-02"
+
+// Day is what happened in a room on a day, in the room time zone.
+type Day struct {
+	RoomID rooms.ID `json:"roomId"`
+	// Date is the local date, like 2021-09-06.
+	Date   string   `json:"date"`
+	Rolls  []*Roll  `json:"rolls"`
+	Boosts []*Boost `json:"boosts"`
+	Vetoes []*Veto  `json:"vetoes"`
+}
@@ -0,0 +1,240 @@
+package lunch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/webhooks"
+	storage_webhooks "lunch/pkg/lunch/webhooks/storage"
+	"lunch/pkg/users"
+)
+
+// webhookTypes are types of events that can be sent to webhooks.
+var webhookTypes = []Type{
+	TypeRollCreated,
+	TypeBoostCreated,
+	TypePlaceCreated,
+	TypeRoomUpdated,
+}
+
+// CreateWebhook registers the url to be notified about events of the given types in the room.
+// Only the room owner can manage webhooks.
+func (r *Roller) CreateWebhook(ctx context.Context, roomID rooms.ID, url string, eventTypes []string, now time.Time) (*webhooks.Webhook, error) {
+	user, err := r.webhooksOwner(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range eventTypes {
+		if !validWebhookType(eventType) {
+			return nil, fmt.Errorf("unknown event type '%s': %w", eventType, ErrInvalid)
+		}
+	}
+
+	webhook, err := webhooks.New(roomID, user.ID, url, eventTypes, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err, ErrInvalid)
+	}
+
+	err = r.webhooksStore.Create(ctx, webhook)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage_webhooks.ErrNoKey):
+		return nil, fmt.Errorf("webhooks are disabled, secrets can't be encrypted: %w", ErrInvalid)
+	default:
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns webhooks registered in the room, oldest first.
+func (r *Roller) ListWebhooks(ctx context.Context, roomID rooms.ID) ([]*webhooks.Webhook, error) {
+	if _, err := r.webhooksOwner(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	all, err := r.webhooksStore.Webhooks(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	result := []*webhooks.Webhook{}
+	for _, webhook := range all {
+		if !webhook.IsDeleted {
+			result = append(result, webhook)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result, nil
+}
+
+// DeleteWebhook stops notifying the webhook. Its pending deliveries are not attempted anymore.
+func (r *Roller) DeleteWebhook(ctx context.Context, roomID rooms.ID, webhookID webhooks.ID, now time.Time) error {
+	user, err := r.webhooksOwner(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	all, err := r.webhooksStore.Webhooks(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	webhook, ok := all[webhookID]
+	if !ok || webhook.IsDeleted {
+		return fmt.Errorf("webhook not found: %w", ErrNotFound)
+	}
+
+	if err := r.webhooksStore.Delete(ctx, user.ID, webhook, now); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log of the room, newest first. If webhookID is set, only deliveries
+// to that webhook are returned.
+func (r *Roller) ListDeliveries(ctx context.Context, roomID rooms.ID, webhookID webhooks.ID) ([]*webhooks.Delivery, error) {
+	if _, err := r.webhooksOwner(ctx, roomID); err != nil {
+		return nil, err
+	}
+
+	dd, err := r.webhooksStore.Deliveries(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+
+	result := []*webhooks.Delivery{}
+	for i := len(dd) - 1; i >= 0; i-- {
+		if webhookID != "" && dd[i].WebhookID != webhookID {
+			continue
+		}
+		result = append(result, dd[i])
+	}
+	return result, nil
+}
+
+// DueDeliveries returns pending deliveries that should be attempted now, with webhooks they are sent to.
+func (r *Roller) DueDeliveries(ctx context.Context, now time.Time) ([]*Delivery, error) {
+	pending, err := r.webhooksStore.Pending(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending deliveries: %w", err)
+	}
+
+	byRoom := map[rooms.ID]map[webhooks.ID]*webhooks.Webhook{}
+	result := []*Delivery{}
+	for _, delivery := range pending {
+		if !delivery.Due(now) {
+			continue
+		}
+		roomWebhooks, ok := byRoom[delivery.RoomID]
+		if !ok {
+			roomWebhooks, err = r.webhooksStore.Webhooks(ctx, delivery.RoomID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list webhooks: %w", err)
+			}
+			byRoom[delivery.RoomID] = roomWebhooks
+		}
+		webhook, ok := roomWebhooks[delivery.WebhookID]
+		if !ok || webhook.IsDeleted {
+			continue
+		}
+		result = append(result, &Delivery{
+			Delivery: delivery,
+			Webhook:  webhook,
+		})
+	}
+	return result, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt, and schedules the next one if it failed.
+func (r *Roller) RecordAttempt(ctx context.Context, delivery *webhooks.Delivery, attempt *webhooks.Attempt) error {
+	attempt.DeliveryID = delivery.ID
+	if err := r.webhooksStore.RecordAttempt(ctx, delivery.RoomID, attempt); err != nil {
+		return fmt.Errorf("failed to record attempt: %w", err)
+	}
+	delivery.Apply(attempt)
+	return nil
+}
+
+// enqueueDeliveries stores a delivery of the event for every webhook in the room that wants it.
+func (r *Roller) enqueueDeliveries(ctx context.Context, e *event) error {
+	var (
+		roomID  rooms.ID
+		payload interface{}
+	)
+	switch e.Type {
+	case TypeRollCreated:
+		roomID, payload = e.Roll.RoomID, e.Roll
+	case TypeBoostCreated:
+		roomID, payload = e.Boost.RoomID, e.Boost
+	case TypePlaceCreated:
+		roomID, payload = e.Place.RoomID, e.Place
+	case TypeRoomUpdated:
+		roomID, payload = e.Room.ID, e.Room
+	default:
+		return fmt.Errorf("unexpected event type %s", e.Type.String())
+	}
+
+	all, err := r.webhooksStore.Webhooks(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	var body json.RawMessage
+	for _, webhook := range all {
+		if webhook.IsDeleted || !webhook.Subscribed(e.Type.String()) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(payload); err != nil {
+				return fmt.Errorf("failed to marshal payload: %w", err)
+			}
+		}
+		webhook := webhook
+		// a retry must not deliver the event twice to webhooks it was already queued for
+		if err := Once(ctx, fmt.Sprintf("delivery/%s", webhook.ID), func() error {
+			delivery := webhooks.NewDelivery(webhook, e.Type.String(), body, time.Now())
+			if err := r.webhooksStore.CreateDelivery(ctx, delivery); err != nil {
+				return fmt.Errorf("failed to create delivery: %w", err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// webhooksOwner returns the user from the context if they own the room.
+func (r *Roller) webhooksOwner(ctx context.Context, roomID rooms.ID) (*users.User, error) {
+	user, ok := users.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("expected to find who in the context")
+	}
+
+	room, err := r.GetRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.UserID != user.ID {
+		return nil, fmt.Errorf("only room owner can manage webhooks: %w", ErrNotAllowed)
+	}
+	return user, nil
+}
+
+func validWebhookType(eventType string) bool {
+	for _, t := range webhookTypes {
+		if t.String() == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/lunch/webhooks"
+	"lunch/pkg/seal"
+	"lunch/pkg/users"
+)
+
+var (
+	ErrNotFound = fmt.Errorf("not found")
+	// ErrNoKey is returned when a webhook is created without a key to encrypt its secret with.
+	ErrNoKey = fmt.Errorf("no key to encrypt secrets with")
+)
+
+// secretPurpose separates keys of sealed secrets from other values sealed with the same key.
+const secretPurpose = "lunch/webhooks/secret"
+
+const (
+	webhookCreated    events.Type = "webhooks/created"
+	webhookDeleted    events.Type = "webhooks/deleted"
+	deliveryCreated   events.Type = "webhooks/delivery_created"
+	deliveryAttempted events.Type = "webhooks/delivery_attempted"
+)
+
+// webhook is the payload of the webhook created event.
+type webhook struct {
+	URL string `json:"url"`
+	// Secret is set by webhooks created before secrets were encrypted.
+	Secret       string   `json:"secret,omitempty"`
+	SealedSecret string   `json:"sealedSecret,omitempty"`
+	EventTypes   []string `json:"eventTypes"`
+}
+
+// delivery is the payload of the delivery created event.
+type delivery struct {
+	WebhookID webhooks.ID     `json:"webhookId"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type Storage struct {
+	storage events.Storage
+	// key encrypts webhook secrets, anyone who knows a secret can sign deliveries of the webhook.
+	key []byte
+}
+
+func New(storage events.Storage, key []byte) *Storage {
+	return &Storage{
+		storage: storage,
+		key:     key,
+	}
+}
+
+func (s *Storage) Create(ctx context.Context, hook *webhooks.Webhook) error {
+	if len(s.key) == 0 {
+		return ErrNoKey
+	}
+	sealedSecret, err := seal.Seal(s.key, secretPurpose, hook.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to seal secret: %w", err)
+	}
+
+	event := &events.Event{
+		UserID:    hook.UserID,
+		RoomID:    hook.RoomID,
+		Timestamp: events.UnixNanoTime(hook.Time),
+		Type:      webhookCreated,
+		Name:      string(hook.ID),
+	}
+	if err := event.MarshalPayload(&webhook{
+		URL:          hook.URL,
+		SealedSecret: sealedSecret,
+		EventTypes:   hook.EventTypes,
+	}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+func (s *Storage) Delete(ctx context.Context, userID users.ID, hook *webhooks.Webhook, now time.Time) error {
+	return s.storage.Create(ctx, &events.Event{
+		UserID:    userID,
+		RoomID:    hook.RoomID,
+		Timestamp: events.UnixNanoTime(now),
+		Type:      webhookDeleted,
+		Name:      string(hook.ID),
+	})
+}
+
+// Webhooks returns all webhooks of the room, including deleted ones.
+func (s *Storage) Webhooks(ctx context.Context, roomID rooms.ID) (map[webhooks.ID]*webhooks.Webhook, error) {
+	ee, err := s.storage.ByRoomID(ctx, roomID, webhookCreated, webhookDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	sortByTime(ee)
+
+	result := map[webhooks.ID]*webhooks.Webhook{}
+	for _, event := range ee {
+		id := webhooks.ID(event.Name)
+		switch event.Type {
+		case webhookCreated:
+			payload := &webhook{}
+			if err := event.UnmarshalPayload(payload); err != nil {
+				return nil, err
+			}
+			secret, err := s.openSecret(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open secret of webhook %s: %w", id, err)
+			}
+			result[id] = &webhooks.Webhook{
+				ID:         id,
+				RoomID:     event.RoomID,
+				UserID:     event.UserID,
+				URL:        payload.URL,
+				Secret:     secret,
+				EventTypes: payload.EventTypes,
+				Time:       time.Time(event.Timestamp),
+			}
+		case webhookDeleted:
+			if hook, ok := result[id]; ok {
+				hook.IsDeleted = true
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *Storage) openSecret(payload *webhook) (string, error) {
+	if payload.SealedSecret == "" {
+		return payload.Secret, nil
+	}
+	return seal.Open(s.key, secretPurpose, payload.SealedSecret)
+}
+
+// CreateDelivery queues the delivery, it's pending until the first attempt is recorded.
+func (s *Storage) CreateDelivery(ctx context.Context, d *webhooks.Delivery) error {
+	event := &events.Event{
+		UserID:    users.System.ID,
+		RoomID:    d.RoomID,
+		Timestamp: events.UnixNanoTime(d.Time),
+		Type:      deliveryCreated,
+		Name:      string(d.ID),
+	}
+	if err := event.MarshalPayload(&delivery{
+		WebhookID: d.WebhookID,
+		EventType: d.EventType,
+		Payload:   d.Payload,
+	}); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// RecordAttempt stores the outcome of a delivery attempt.
+func (s *Storage) RecordAttempt(ctx context.Context, roomID rooms.ID, attempt *webhooks.Attempt) error {
+	event := &events.Event{
+		UserID:    users.System.ID,
+		RoomID:    roomID,
+		Timestamp: events.UnixNanoTime(attempt.Time),
+		Type:      deliveryAttempted,
+		Name:      string(attempt.DeliveryID),
+	}
+	if err := event.MarshalPayload(attempt); err != nil {
+		return err
+	}
+	return s.storage.Create(ctx, event)
+}
+
+// Deliveries returns all deliveries in the room, oldest first.
+func (s *Storage) Deliveries(ctx context.Context, roomID rooms.ID) ([]*webhooks.Delivery, error) {
+	ee, err := s.storage.ByRoomID(ctx, roomID, deliveryCreated, deliveryAttempted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return replayDeliveries(ee)
+}
+
+// Pending returns deliveries in all rooms that are not delivered yet, and didn't fail for good. Deliveries
+// created more than webhooks.MaxAge before now are not returned.
+func (s *Storage) Pending(ctx context.Context, now time.Time) ([]*webhooks.Delivery, error) {
+	ee, err := s.storage.ByTypeSince(ctx, now.Add(-webhooks.MaxAge), deliveryCreated, deliveryAttempted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	dd, err := replayDeliveries(ee)
+	if err != nil {
+		return nil, err
+	}
+	result := []*webhooks.Delivery{}
+	for _, d := range dd {
+		if d.Status == webhooks.StatusPending {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func replayDeliveries(ee []*events.Event) ([]*webhooks.Delivery, error) {
+	sortByTime(ee)
+
+	result := []*webhooks.Delivery{}
+	byID := map[webhooks.DeliveryID]*webhooks.Delivery{}
+	for _, event := range ee {
+		id := webhooks.DeliveryID(event.Name)
+		switch event.Type {
+		case deliveryCreated:
+			payload := &delivery{}
+			if err := event.UnmarshalPayload(payload); err != nil {
+				return nil, err
+			}
+			d := &webhooks.Delivery{
+				ID:            id,
+				WebhookID:     payload.WebhookID,
+				RoomID:        event.RoomID,
+				EventType:     payload.EventType,
+				Payload:       payload.Payload,
+				Status:        webhooks.StatusPending,
+				NextAttemptAt: time.Time(event.Timestamp),
+				Time:          time.Time(event.Timestamp),
+				UpdatedAt:     time.Time(event.Timestamp),
+			}
+			byID[id] = d
+			result = append(result, d)
+		case deliveryAttempted:
+			d, ok := byID[id]
+			if !ok {
+				continue
+			}
+			attempt := &webhooks.Attempt{}
+			if err := event.UnmarshalPayload(attempt); err != nil {
+				return nil, err
+			}
+			attempt.Time = time.Time(event.Timestamp)
+			d.Apply(attempt)
+		}
+	}
+	return result, nil
+}
+
+func sortByTime(ee []*events.Event) {
+	sort.Slice(ee, func(i, j int) bool {
+		return time.Time(ee[i].Timestamp).Before(time.Time(ee[j].Timestamp))
+	})
+}
@@ -0,0 +1,226 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// HeaderSignature is the hex encoded HMAC-SHA256 of the timestamp and the body, joined with a dot,
+	// prefixed with "sha256=". The key is the webhook secret.
+	HeaderSignature = "X-Lunch-Signature"
+	// HeaderTimestamp is when the delivery was attempted, in unix seconds.
+	HeaderTimestamp = "X-Lunch-Timestamp"
+	// HeaderEvent is the type of the event, for example roll_created.
+	HeaderEvent = "X-Lunch-Event"
+	// HeaderDelivery is the id of the delivery, it stays the same between attempts.
+	HeaderDelivery = "X-Lunch-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is attempted before it's marked as failed.
+	MaxAttempts = 8
+	// MaxAge is how long after it was created a delivery can be attempted. It's longer than all backoffs
+	// together, so that pending deliveries can be found by looking at recent ones only.
+	MaxAge = 24 * time.Hour
+
+	minBackoff = 30 * time.Second
+	maxBackoff = 2 * time.Hour
+)
+
+type ID string
+
+// Webhook is a URL that is notified about events in a room.
+type Webhook struct {
+	ID     ID       `json:"id"`
+	RoomID rooms.ID `json:"roomId"`
+	UserID users.ID `json:"userId"`
+	URL    string   `json:"url"`
+	// Secret is the key deliveries are signed with.
+	Secret string `json:"secret"`
+	// EventTypes are types of events the webhook is notified about, for example roll_created.
+	EventTypes []string  `json:"eventTypes"`
+	Time       time.Time `json:"time"`
+	IsDeleted  bool      `json:"-"`
+}
+
+func New(roomID rooms.ID, userID users.ID, webhookURL string, eventTypes []string, now time.Time) (*Webhook, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http or https url")
+	}
+	if err := validateHost(u.Hostname()); err != nil {
+		return nil, err
+	}
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("at least one event type must be set")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return &Webhook{
+		ID:         ID(uuid.NewString()),
+		RoomID:     roomID,
+		UserID:     userID,
+		URL:        webhookURL,
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		Time:       now,
+	}, nil
+}
+
+// validateHost returns an error if the host is localhost or an internal address, so that owners can't make
+// the server post to internal services. Names are checked again when they are resolved, with AllowedIP.
+func validateHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("host %s is not allowed", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !AllowedIP(ip) {
+		return fmt.Errorf("address %s is not allowed", host)
+	}
+	return nil
+}
+
+// AllowedIP returns false for loopback, private, link-local and other addresses that are not public.
+func AllowedIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Subscribed returns true if the webhook is notified about the event type.
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryID string
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+)
+
+// Delivery is an event sent, or about to be sent, to a webhook.
+type Delivery struct {
+	ID        DeliveryID      `json:"id"`
+	WebhookID ID              `json:"webhookId"`
+	RoomID    rooms.ID        `json:"roomId"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    Status          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// StatusCode is the http status code of the last attempt, 0 if there was no response.
+	StatusCode int    `json:"statusCode,omitempty"`
+	LastError  string `json:"lastError,omitempty"`
+	// NextAttemptAt is when the delivery is attempted again, if it's pending.
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	Time          time.Time `json:"time"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func NewDelivery(webhook *Webhook, eventType string, payload json.RawMessage, now time.Time) *Delivery {
+	return &Delivery{
+		ID:            DeliveryID(uuid.NewString()),
+		WebhookID:     webhook.ID,
+		RoomID:        webhook.RoomID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		Time:          now,
+		UpdatedAt:     now,
+	}
+}
+
+// Attempt is the outcome of a single delivery attempt.
+type Attempt struct {
+	DeliveryID DeliveryID `json:"deliveryId"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// Succeeded returns true if the webhook responded with a 2xx status code.
+func (a *Attempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Apply updates the delivery with the attempt outcome. Failed deliveries are retried with exponential backoff,
+// until MaxAttempts is reached.
+func (d *Delivery) Apply(attempt *Attempt) {
+	d.Attempts++
+	d.StatusCode = attempt.StatusCode
+	d.LastError = attempt.Error
+	d.UpdatedAt = attempt.Time
+
+	switch {
+	case attempt.Succeeded():
+		d.Status = StatusDelivered
+		d.NextAttemptAt = time.Time{}
+	case d.Attempts >= MaxAttempts:
+		d.Status = StatusFailed
+		d.NextAttemptAt = time.Time{}
+	default:
+		d.Status = StatusPending
+		d.NextAttemptAt = attempt.Time.Add(Backoff(d.Attempts))
+	}
+}
+
+// Due returns true if the delivery should be attempted now.
+func (d *Delivery) Due(now time.Time) bool {
+	return d.Status == StatusPending && !d.NextAttemptAt.After(now)
+}
+
+// Backoff returns how long to wait before the next attempt, after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	backoff := minBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// Sign returns the signature of the body sent at the timestamp, as sent in HeaderSignature.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	hash.Write([]byte("."))
+	hash.Write(body)
+	return "sha256=" + hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{9, 2 * time.Hour},
+		{20, 2 * time.Hour},
+	}
+
+	for _, testCase := range testCases {
+		if got := Backoff(testCase.attempts); got != testCase.expected {
+			t.Errorf("%d attempts: expected %s, got %s", testCase.attempts, testCase.expected, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+	webhook, err := New("room", "user", "https://example.com/hook", []string{"roll_created"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := NewDelivery(webhook, "roll_created", []byte(`{}`), now)
+	if !delivery.Due(now) {
+		t.Fatal("expected a new delivery to be due")
+	}
+
+	delivery.Apply(&Attempt{StatusCode: 500, Time: now})
+	if delivery.Status != StatusPending || !delivery.NextAttemptAt.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("unexpected delivery after a failed attempt: %+v", delivery)
+	}
+	if delivery.Due(now.Add(29 * time.Second)) {
+		t.Error("expected the delivery to wait for backoff")
+	}
+
+	delivery.Apply(&Attempt{StatusCode: 204, Time: now.Add(30 * time.Second)})
+	if delivery.Status != StatusDelivered || delivery.Attempts != 2 || delivery.Due(now.Add(time.Hour)) {
+		t.Errorf("unexpected delivery after a successful attempt: %+v", delivery)
+	}
+
+	failing := NewDelivery(webhook, "roll_created", []byte(`{}`), now)
+	for i := 0; i < MaxAttempts; i++ {
+		failing.Apply(&Attempt{Error: "connection refused", Time: now})
+	}
+	if failing.Status != StatusFailed || failing.LastError != "connection refused" {
+		t.Errorf("expected the delivery to fail after %d attempts: %+v", MaxAttempts, failing)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		url        string
+		eventTypes []string
+		expectErr  bool
+	}{
+		{"https://example.com/hook", []string{"roll_created"}, false},
+		{"http://hooks.example.com:8080", []string{"roll_created"}, false},
+		{"http://localhost:8080", []string{"roll_created"}, true},
+		{"http://api.localhost./hook", []string{"roll_created"}, true},
+		{"http://192.168.1.1/hook", []string{"roll_created"}, true},
+		{"http://[fe80::1]/hook", []string{"roll_created"}, true},
+		{"http://0.0.0.0/hook", []string{"roll_created"}, true},
+		{"https://93.184.216.34/hook", []string{"roll_created"}, false},
+		{"ftp://example.com", []string{"roll_created"}, true},
+		{"/hook", []string{"roll_created"}, true},
+		{"https://example.com/hook", nil, true},
+	}
+
+	for _, testCase := range testCases {
+		webhook, err := New("room", "user", testCase.url, testCase.eventTypes, time.Now())
+		if testCase.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", testCase.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", testCase.url, err)
+			continue
+		}
+		if len(webhook.Secret) != 64 {
+			t.Errorf("%s: unexpected secret '%s'", testCase.url, webhook.Secret)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package lunch
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/webhooks"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+var testWebhooksKey = []byte("test-webhooks-key")
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+
+	owner := testUser()
+	member := testUser()
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	usersStore := storage_users.NewBolt(bolt)
+	for _, u := range []*users.User{owner, member} {
+		assertNoError(t, usersStore.Create(testContext(u), u))
+	}
+	eventsStorage := events.NewBoltStorage(bolt)
+	roller := New(eventsStorage, usersStore, WithWebhooksKey(testWebhooksKey))
+
+	assertNoError(t, roller.CreateRoom(testContext(owner), "room"))
+	rr, err := roller.ListRooms(testContext(owner))
+	assertNoError(t, err)
+	roomID := rr[0].ID
+	assertNoError(t, roller.JoinRoom(testContext(member), roomID))
+
+	// secrets can't be stored without a key to encrypt them with
+	_, err = New(eventsStorage, usersStore).CreateWebhook(testContext(owner), roomID, "https://example.com/hook", []string{"roll_created"}, time.Now())
+	assertError(t, ErrInvalid, err)
+
+	_, err = roller.CreateWebhook(testContext(member), roomID, "https://example.com/hook", []string{"roll_created"}, time.Now())
+	assertError(t, ErrNotAllowed, err)
+
+	_, err = roller.CreateWebhook(testContext(owner), roomID, "https://example.com/hook", []string{"veto_created"}, time.Now())
+	assertError(t, ErrInvalid, err)
+
+	_, err = roller.CreateWebhook(testContext(owner), roomID, "example.com", []string{"roll_created"}, time.Now())
+	assertError(t, ErrInvalid, err)
+
+	for _, internal := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://10.0.0.1/hook", "http://169.254.169.254/latest/meta-data"} {
+		_, err = roller.CreateWebhook(testContext(owner), roomID, internal, []string{"roll_created"}, time.Now())
+		assertError(t, ErrInvalid, err)
+	}
+
+	rolls, err := roller.CreateWebhook(testContext(owner), roomID, "https://example.com/rolls", []string{"roll_created"}, time.Now())
+	assertNoError(t, err)
+	places, err := roller.CreateWebhook(testContext(owner), roomID, "https://example.com/places", []string{"place_created", "boost_created"}, time.Now())
+	assertNoError(t, err)
+
+	ww, err := roller.ListWebhooks(testContext(owner), roomID)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(ww))
+	assertEqual(t, rolls.ID, ww[0].ID)
+	assertEqual(t, rolls.Secret, ww[0].Secret)
+
+	stored, err := eventsStorage.ByRoomID(context.Background(), roomID)
+	assertNoError(t, err)
+	for _, event := range stored {
+		assertEqual(t, false, strings.Contains(string(event.Payload), rolls.Secret))
+	}
+
+	_, err = roller.ListWebhooks(testContext(member), roomID)
+	assertError(t, ErrNotAllowed, err)
+
+	assertNoError(t, roller.CreatePlace(testContext(member), roomID, "place"))
+	due := waitDeliveries(t, roller, 1)
+	assertEqual(t, places.ID, due[0].Webhook.ID)
+	assertEqual(t, "place_created", due[0].EventType)
+
+	place := &Place{}
+	assertNoError(t, json.Unmarshal(due[0].Payload, place))
+	assertEqual(t, "place", place.Name)
+	assertEqual(t, member.ID, place.User.ID)
+
+	assertNoError(t, roller.RecordAttempt(testContext(users.System), due[0].Delivery, &webhooks.Attempt{StatusCode: 200, Time: time.Now()}))
+
+	_, err = roller.CreateRoll(testContext(member), roomID, time.Now())
+	assertNoError(t, err)
+	due = waitDeliveries(t, roller, 1)
+	assertEqual(t, rolls.ID, due[0].Webhook.ID)
+	assertEqual(t, "roll_created", due[0].EventType)
+
+	assertNoError(t, roller.RecordAttempt(testContext(users.System), due[0].Delivery, &webhooks.Attempt{StatusCode: 503, Time: time.Now()}))
+
+	// retried after backoff
+	due, err = roller.DueDeliveries(testContext(users.System), time.Now())
+	assertNoError(t, err)
+	assertEqual(t, 0, len(due))
+	due, err = roller.DueDeliveries(testContext(users.System), time.Now().Add(webhooks.Backoff(1)))
+	assertNoError(t, err)
+	assertEqual(t, 1, len(due))
+
+	// too old to be attempted
+	due, err = roller.DueDeliveries(testContext(users.System), time.Now().Add(webhooks.MaxAge+time.Minute))
+	assertNoError(t, err)
+	assertEqual(t, 0, len(due))
+
+	log, err := roller.ListDeliveries(testContext(owner), roomID, "")
+	assertNoError(t, err)
+	assertEqual(t, 2, len(log))
+	assertEqual(t, webhooks.StatusPending, log[0].Status)
+	assertEqual(t, 503, log[0].StatusCode)
+	assertEqual(t, webhooks.StatusDelivered, log[1].Status)
+
+	log, err = roller.ListDeliveries(testContext(owner), roomID, places.ID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(log))
+
+	// pending deliveries of deleted webhooks are dropped
+	assertError(t, ErrNotAllowed, roller.DeleteWebhook(testContext(member), roomID, rolls.ID, time.Now()))
+	assertNoError(t, roller.DeleteWebhook(testContext(owner), roomID, rolls.ID, time.Now()))
+	assertError(t, ErrNotFound, roller.DeleteWebhook(testContext(owner), roomID, rolls.ID, time.Now()))
+	due, err = roller.DueDeliveries(testContext(users.System), time.Now().Add(time.Hour))
+	assertNoError(t, err)
+	assertEqual(t, 0, len(due))
+}
+
+func TestWebhooks_twoInstances(t *testing.T) {
+	t.Parallel()
+
+	owner := testUser()
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	usersStore := storage_users.NewBolt(bolt)
+	assertNoError(t, usersStore.Create(testContext(owner), owner))
+
+	// two server instances sharing the database
+	roller := New(events.NewBoltStorage(bolt), usersStore, WithWebhooksKey(testWebhooksKey))
+	other := New(events.NewBoltStorage(bolt), usersStore, WithWebhooksKey(testWebhooksKey))
+
+	assertNoError(t, roller.CreateRoom(testContext(owner), "room"))
+	rr, err := roller.ListRooms(testContext(owner))
+	assertNoError(t, err)
+	roomID := rr[0].ID
+	_, err = roller.CreateWebhook(testContext(owner), roomID, "https://example.com/places", []string{"place_created"}, time.Now())
+	assertNoError(t, err)
+
+	assertNoError(t, other.CreatePlace(testContext(owner), roomID, "place"))
+	assertNoError(t, roller.Wait(context.Background()))
+	waitDeliveries(t, other, 1)
+}
+
+// waitDeliveries waits for deliveries that are created in the background.
+func waitDeliveries(t *testing.T, roller *Roller, n int) []*Delivery {
+	t.Helper()
+
+	assertNoError(t, roller.Wait(context.Background()))
+	due, err := roller.DueDeliveries(testContext(users.System), time.Now())
+	assertNoError(t, err)
+	if len(due) != n {
+		t.Fatalf("expected %d deliveries, got %d", n, len(due))
+	}
+	return due
+}
@@ -0,0 +1,105 @@
+package lunch
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+)
+
+// WeightStrategy defines how likely a place is to be rolled, based on when it was rolled last time.
+// Boosts are applied on top of the weight returned by a strategy.
+type WeightStrategy interface {
+	// Weight returns a non-negative weight of a place, higher weights mean higher chance to be rolled.
+	// lastRolledAt is zero if the place was never rolled.
+	Weight(lastRolledAt time.Time, placesTotal int, now time.Time) float64
+}
+
+// LinearWeights gives the lowest weight to the most recently rolled place, and increases it by one for every day
+// since the roll, up to the number of places.
+type LinearWeights struct{}
+
+func (LinearWeights) Weight(lastRolledAt time.Time, placesTotal int, now time.Time) float64 {
+	if lastRolledAt.IsZero() {
+		return float64(placesTotal)
+	}
+	rolledAgo := now.Sub(lastRolledAt)
+	rolledDaysAgo := int(math.Floor(rolledAgo.Hours() / hoursInADay))
+	if rolledDaysAgo >= placesTotal {
+		return float64(placesTotal)
+	}
+	return float64(rolledDaysAgo) + 1
+}
+
+// ExponentialWeights gives zero weight to a place right after it was rolled, and recovers half of the
+// remaining weight every HalfLife.
+type ExponentialWeights struct {
+	HalfLife time.Duration
+}
+
+func (e ExponentialWeights) Weight(lastRolledAt time.Time, placesTotal int, now time.Time) float64 {
+	if lastRolledAt.IsZero() {
+		return 1
+	}
+	rolledAgo := now.Sub(lastRolledAt)
+	if rolledAgo <= 0 {
+		return 0
+	}
+	return 1 - math.Pow(2, -float64(rolledAgo)/float64(e.HalfLife))
+}
+
+// UniformWeights gives every place the same weight.
+type UniformWeights struct{}
+
+func (UniformWeights) Weight(lastRolledAt time.Time, placesTotal int, now time.Time) float64 {
+	return 1
+}
+
+// NoRepeatWeights excludes places that were rolled within the last Days days.
+type NoRepeatWeights struct {
+	Days int
+}
+
+func (n NoRepeatWeights) Weight(lastRolledAt time.Time, placesTotal int, now time.Time) float64 {
+	if lastRolledAt.IsZero() {
+		return 1
+	}
+	if now.Sub(lastRolledAt) < time.Duration(n.Days)*hoursInADay*time.Hour {
+		return 0
+	}
+	return 1
+}
+
+// weightStrategy returns a strategy configured by the room settings.
+func weightStrategy(settings rooms.Settings) WeightStrategy {
+	switch settings.Strategy {
+	case rooms.StrategyExponential:
+		return ExponentialWeights{HalfLife: time.Duration(settings.HalfLifeDays * hoursInADay * float64(time.Hour))}
+	case rooms.StrategyUniform:
+		return UniformWeights{}
+	case rooms.StrategyNoRepeat:
+		return NoRepeatWeights{Days: settings.NoRepeatDays}
+	default:
+		return LinearWeights{}
+	}
+}
+
+func validateStrategy(settings rooms.Settings) error {
+	switch settings.Strategy {
+	case rooms.StrategyLinear, rooms.StrategyUniform:
+		return nil
+	case rooms.StrategyExponential:
+		if settings.HalfLifeDays <= 0 || !isFinite(settings.HalfLifeDays) {
+			return fmt.Errorf("half-life must be positive: %w", ErrInvalid)
+		}
+		return nil
+	case rooms.StrategyNoRepeat:
+		if settings.NoRepeatDays <= 0 {
+			return fmt.Errorf("number of days must be positive: %w", ErrInvalid)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown strategy '%s': %w", settings.Strategy, ErrInvalid)
+	}
+}
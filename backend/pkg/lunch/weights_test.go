@@ -0,0 +1,62 @@
+package lunch
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"lunch/pkg/lunch/rooms"
+)
+
+func TestWeightStrategies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, time.September, 6, 9, 0, 0, 0, time.UTC) // Monday
+	oneDay := 24 * time.Hour
+
+	testCases := []struct {
+		name         string
+		strategy     WeightStrategy
+		lastRolledAt time.Time
+		placesTotal  int
+		expected     float64
+	}{
+		{"linear, never rolled", LinearWeights{}, time.Time{}, 5, 5},
+		{"linear, rolled today", LinearWeights{}, now.Add(-time.Hour), 5, 1},
+		{"linear, rolled two days ago", LinearWeights{}, now.Add(-2 * oneDay), 5, 3},
+		{"linear, rolled long ago", LinearWeights{}, now.Add(-10 * oneDay), 5, 5},
+		{"exponential, never rolled", ExponentialWeights{HalfLife: oneDay}, time.Time{}, 5, 1},
+		{"exponential, rolled just now", ExponentialWeights{HalfLife: oneDay}, now, 5, 0},
+		{"exponential, rolled one half-life ago", ExponentialWeights{HalfLife: oneDay}, now.Add(-oneDay), 5, 0.5},
+		{"exponential, rolled two half-lifes ago", ExponentialWeights{HalfLife: oneDay}, now.Add(-2 * oneDay), 5, 0.75},
+		{"uniform, never rolled", UniformWeights{}, time.Time{}, 5, 1},
+		{"uniform, rolled just now", UniformWeights{}, now, 5, 1},
+		{"no repeat, never rolled", NoRepeatWeights{Days: 3}, time.Time{}, 5, 1},
+		{"no repeat, rolled within the window", NoRepeatWeights{Days: 3}, now.Add(-2 * oneDay), 5, 0},
+		{"no repeat, rolled before the window", NoRepeatWeights{Days: 3}, now.Add(-3 * oneDay), 5, 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assertEqual(t, tc.expected, tc.strategy.Weight(tc.lastRolledAt, tc.placesTotal, now))
+		})
+	}
+}
+
+func TestValidateStrategy_halfLife(t *testing.T) {
+	t.Parallel()
+
+	settings := rooms.DefaultSettings()
+	settings.Strategy = rooms.StrategyExponential
+
+	for _, halfLifeDays := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		settings.HalfLifeDays = halfLifeDays
+		assertError(t, ErrInvalid, validateStrategy(settings))
+	}
+
+	settings.HalfLifeDays = 2
+	assertNoError(t, validateStrategy(settings))
+}
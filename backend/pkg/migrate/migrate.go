@@ -2,6 +2,7 @@ package migrate
 
 import (
 	"context"
+	"fmt"
 
 	storage_boosts "lunch/pkg/lunch/boosts/storage"
 	"lunch/pkg/lunch/events"
@@ -26,14 +27,19 @@ var (
 	cfg           = mustLoadConfig()
 	dynamodbStore = store.NewDynamoDB(cfg)
 
-	eventsStore = events.NewDynamoDBStore(dynamodbStore, "lunch-production-webapp-events")
-	places      = storage_places.New(eventsStore)
-	boosts      = storage_boosts.New(eventsStore)
-	rolls       = storage_rolls.New(eventsStore)
+	eventsStore = events.NewDynamoDBStore(dynamodbStore, "lunch-production-webapp-events-by-id")
+	// legacyEventsStore is keyed by user id and time, so events of the same user at the same time collide.
+	legacyEventsStore = events.NewDynamoDBStore(dynamodbStore, "lunch-production-webapp-events")
+	places            = storage_places.New(eventsStore)
+	boosts            = storage_boosts.New(eventsStore)
+	rolls             = storage_rolls.New(eventsStore)
 
 	sturdyRoomID = rooms.ID("69c83096-995a-48ce-b843-80a926b0a9ec")
 )
 
 func Run(ctx context.Context) error {
+	if err := legacyEventsStore.CopyTo(ctx, eventsStore); err != nil {
+		return fmt.Errorf("failed to copy events: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSub delivers messages published to a channel to subscribers of the channel.
+type PubSub interface {
+	// Publish sends the message to all subscribers of the channel.
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe calls fn with every message published to the channel, one by one, until ctx is done.
+	// It returns once the subscription is active.
+	Subscribe(ctx context.Context, channel string, fn func([]byte)) error
+	// Distributed returns true if messages are delivered to subscribers in other processes too.
+	Distributed() bool
+}
+
+type subscriber struct {
+	fn func([]byte)
+}
+
+// Memory delivers messages to subscribers in this process only.
+type Memory struct {
+	guard       *sync.RWMutex
+	subscribers map[string][]*subscriber
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		guard:       &sync.RWMutex{},
+		subscribers: map[string][]*subscriber{},
+	}
+}
+
+// Publish calls subscribers of the channel before it returns.
+func (m *Memory) Publish(ctx context.Context, channel string, message []byte) error {
+	m.guard.RLock()
+	subscribers := m.subscribers[channel]
+	m.guard.RUnlock()
+
+	for _, s := range subscribers {
+		s.fn(message)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, channel string, fn func([]byte)) error {
+	s := &subscriber{fn: fn}
+
+	m.guard.Lock()
+	m.subscribers[channel] = append(m.subscribers[channel], s)
+	m.guard.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.guard.Lock()
+		defer m.guard.Unlock()
+
+		active := []*subscriber{}
+		for _, other := range m.subscribers[channel] {
+			if other != s {
+				active = append(active, other)
+			}
+		}
+		m.subscribers[channel] = active
+	}()
+	return nil
+}
+
+func (m *Memory) Distributed() bool {
+	return false
+}
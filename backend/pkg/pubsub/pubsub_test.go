@@ -0,0 +1,205 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestMemory(t *testing.T) {
+	ps := NewMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := [][]byte{}
+	if err := ps.Subscribe(ctx, "channel", func(message []byte) {
+		received = append(received, message)
+	}); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+
+	if err := ps.Publish(context.Background(), "channel", []byte("hello")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	if err := ps.Publish(context.Background(), "other", []byte("ignored")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	if len(received) != 1 || string(received[0]) != "hello" {
+		t.Errorf("expected [hello], got %q", received)
+	}
+
+	cancel()
+	waitFor(t, func() bool {
+		ps.guard.RLock()
+		defer ps.guard.RUnlock()
+		return len(ps.subscribers["channel"]) == 0
+	})
+}
+
+func TestRedis_fanOut(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	// two instances connected to the same server
+	first, err := NewRedis(fmt.Sprintf("redis://:secret@%s", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer first.Close()
+	second, err := NewRedis(fmt.Sprintf("redis://:secret@%s", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer second.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	firstReceived := newMessages()
+	if err := first.Subscribe(ctx, "channel", firstReceived.add); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	secondReceived := newMessages()
+	if err := second.Subscribe(ctx, "channel", secondReceived.add); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+
+	if err := first.Publish(ctx, "channel", []byte("one")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	if err := second.Publish(ctx, "channel", []byte("two")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	firstReceived.waitFor(t, "one", "two")
+	secondReceived.waitFor(t, "one", "two")
+}
+
+func TestRedis_reconnects(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	ps, err := NewRedis(fmt.Sprintf("redis://%s", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer ps.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := newMessages()
+	if err := ps.Subscribe(ctx, "channel", received.add); err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	if err := ps.Publish(ctx, "channel", []byte("before")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	received.waitFor(t, "before")
+
+	// the server restarts at the same address
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatalf("failed to restart: %s", err)
+	}
+	waitFor(t, func() bool {
+		return server.PubSubNumSub("channel")["channel"] == 1
+	})
+
+	if err := ps.Publish(ctx, "channel", []byte("after")); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+	received.waitFor(t, "before", "after")
+}
+
+func TestRedis_wrongPassword(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	ps, err := NewRedis(fmt.Sprintf("redis://:wrong@%s", server.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	if err := ps.Subscribe(context.Background(), "channel", func([]byte) {}); err == nil {
+		t.Errorf("expected subscribe to fail")
+	}
+}
+
+func TestRedis_unresponsive(t *testing.T) {
+	// a server that accepts connections, but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(io.Discard, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	ps, err := NewRedis(fmt.Sprintf("redis://%s", listener.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	defer ps.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	if err := ps.Publish(ctx, "channel", []byte("lost")); err == nil {
+		t.Errorf("expected publish to fail")
+	}
+	if elapsed := time.Since(started); elapsed > readTimeout {
+		t.Errorf("expected publish to give up with the context, took %s", elapsed)
+	}
+}
+
+type messages struct {
+	guard    *sync.Mutex
+	received []string
+}
+
+func newMessages() *messages {
+	return &messages{guard: &sync.Mutex{}}
+}
+
+func (m *messages) add(message []byte) {
+	m.guard.Lock()
+	m.received = append(m.received, string(message))
+	m.guard.Unlock()
+}
+
+func (m *messages) waitFor(t *testing.T, expected ...string) {
+	t.Helper()
+
+	waitFor(t, func() bool {
+		m.guard.Lock()
+		defer m.guard.Unlock()
+		return fmt.Sprint(m.received) == fmt.Sprint(expected)
+	})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,95 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	// readTimeout and writeTimeout bound every command, so a server that stopped answering doesn't block publishers.
+	readTimeout  = 3 * time.Second
+	writeTimeout = 3 * time.Second
+	// healthCheckInterval is how often subscriptions ping the server when no messages come, so that broken
+	// connections are noticed and reconnected.
+	healthCheckInterval = 30 * time.Second
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// Redis delivers messages with PUBLISH and SUBSCRIBE commands of a Redis server, so that subscribers in every
+// process connected to the same server get them.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis returns a client of the server at the url, like redis://:password@localhost:6379.
+func NewRedis(redisURL string) (*Redis, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	options.DialTimeout = dialTimeout
+	options.ReadTimeout = readTimeout
+	options.WriteTimeout = writeTimeout
+	// the connection could have been closed by the server since it was last used, so commands are retried
+	// once with a fresh one
+	options.MaxRetries = 1
+	options.MinRetryBackoff = minReconnectBackoff
+	options.MaxRetryBackoff = maxReconnectBackoff
+	return &Redis{
+		client: redis.NewClient(options),
+	}, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, channel string, message []byte) error {
+	if err := r.client.Publish(ctx, channel, message).Err(); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe reconnects and subscribes again if the connection breaks. Messages published while it's
+// disconnected are lost.
+func (r *Redis) Subscribe(ctx context.Context, channel string, fn func([]byte)) error {
+	subscription := r.client.Subscribe(ctx, channel)
+	// the confirmation is the first message of the subscription
+	if _, err := subscription.Receive(ctx); err != nil {
+		subscription.Close()
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	messages := subscription.Channel(redis.WithChannelHealthCheckInterval(healthCheckInterval))
+	go func() {
+		defer func() {
+			if err := subscription.Close(); err != nil {
+				log.Printf("[WARN] pubsub: failed to close subscription to %s: %s", channel, err)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				fn([]byte(message.Payload))
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *Redis) Distributed() bool {
+	return true
+}
+
+// Close closes connections used for publishing. Subscriptions are closed when their context is done.
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
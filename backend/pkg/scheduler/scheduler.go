@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/users"
+)
+
+const (
+	defaultInterval = time.Minute
+	// claimFor is how long a claim on a room for a day is kept, long enough for the day to be over in every time zone.
+	claimFor = 48 * time.Hour
+)
+
+// Scheduler rolls rooms automatically according to their schedules, reminds members before the roll, and closes
+// polls that stopped accepting votes.
+type Scheduler struct {
+	roller   *lunch.Roller
+	claims   claims.Storage
+	interval time.Duration
+	now      func() time.Time
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// New returns a scheduler. Claims make sure that only one of the server instances reminds or rolls a room.
+func New(roller *lunch.Roller, claimsStore claims.Storage) *Scheduler {
+	return &Scheduler{
+		roller:   roller,
+		claims:   claimsStore,
+		interval: defaultInterval,
+		now:      time.Now,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start checks schedules every interval in the background, until Stop is called. Rolls that were due while
+// the scheduler wasn't running are skipped.
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(s.interval)
+	go func() {
+		defer close(s.stopped)
+		defer ticker.Stop()
+
+		from := s.now()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				to := s.now()
+				if err := s.tick(from, to); err != nil {
+					log.Printf("[ERROR] scheduler: %s", err)
+				}
+				from = to
+			}
+		}
+	}()
+}
+
+// Stop waits for the current check to finish, and stops the scheduler.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.done)
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tick reminds and rolls all rooms that are due in (from, to], and closes polls that are due at to.
+func (s *Scheduler) tick(from, to time.Time) error {
+	ctx := users.NewContext(context.Background(), users.System)
+
+	pp, err := s.roller.DuePolls(ctx, to)
+	if err != nil {
+		return fmt.Errorf("failed to list due polls: %w", err)
+	}
+
+	for _, poll := range pp {
+		if err := s.closePoll(ctx, poll, to); err != nil {
+			log.Printf("[ERROR] scheduler: poll %s: %s", poll.ID, err)
+		}
+	}
+
+	reminderRoomIDs, err := s.roller.DueReminders(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to list due reminders: %w", err)
+	}
+
+	for _, roomID := range reminderRoomIDs {
+		if err := s.remind(ctx, roomID, to); err != nil {
+			log.Printf("[ERROR] scheduler: room %s: %s", roomID, err)
+		}
+	}
+
+	roomIDs, err := s.roller.DueRooms(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to list due rooms: %w", err)
+	}
+
+	for _, roomID := range roomIDs {
+		if err := s.roll(ctx, roomID, to); err != nil {
+			log.Printf("[ERROR] scheduler: room %s: %s", roomID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) remind(ctx context.Context, roomID rooms.ID, now time.Time) error {
+	rolled, err := s.roller.RolledToday(ctx, roomID, now)
+	if err != nil {
+		return err
+	}
+	if rolled {
+		// nothing to remind about
+		return nil
+	}
+
+	if err := s.claim(ctx, "reminder", roomID, now); errors.Is(err, claims.ErrClaimed) {
+		// another instance reminds
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := s.roller.Remind(ctx, roomID, now); err != nil {
+		return fmt.Errorf("failed to remind: %w", err)
+	}
+	return nil
+}
+
+func (s *Scheduler) roll(ctx context.Context, roomID rooms.ID, now time.Time) error {
+	rolled, err := s.roller.RolledToday(ctx, roomID, now)
+	if err != nil {
+		return err
+	}
+	if rolled {
+		// someone was faster
+		return nil
+	}
+
+	if err := s.claim(ctx, "roll", roomID, now); errors.Is(err, claims.ErrClaimed) {
+		// another instance rolls
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	roll, err := s.roller.CreateRoll(ctx, roomID, now)
+	switch {
+	case err == nil:
+		log.Printf("[INFO] scheduler: rolled %s in room %s", roll.Place.Name, roomID)
+		return nil
+	case errors.Is(err, lunch.ErrNoPlaces):
+		return nil
+	default:
+		return fmt.Errorf("failed to roll: %w", err)
+	}
+}
+
+func (s *Scheduler) closePoll(ctx context.Context, poll *polls.Poll, now time.Time) error {
+	key := fmt.Sprintf("scheduler/poll/%s", poll.ID)
+	if err := s.claims.Claim(ctx, key, now, now.Add(claimFor)); errors.Is(err, claims.ErrClaimed) {
+		// another instance closes it
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+
+	roll, err := s.roller.ClosePoll(ctx, poll.RoomID, now)
+	switch {
+	case err == nil:
+		log.Printf("[INFO] scheduler: %s won the poll in room %s", roll.Place.Name, poll.RoomID)
+		return nil
+	case errors.Is(err, lunch.ErrNoPlaces), errors.Is(err, lunch.ErrNotFound):
+		// nobody voted, or someone closed it
+		return nil
+	default:
+		return fmt.Errorf("failed to close poll: %w", err)
+	}
+}
+
+// claim claims the action in the room for the day, in the room time zone. A room is scheduled once a day, so
+// instances that check it at slightly different times claim the same key.
+func (s *Scheduler) claim(ctx context.Context, action string, roomID rooms.ID, now time.Time) error {
+	room, err := s.roller.GetRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	day := now.In(room.Settings.Location()).Format("2006-01-02")
+	key := fmt.Sprintf("scheduler/%s/%s/%s", action, roomID, day)
+	if err := s.claims.Claim(ctx, key, now, now.Add(claimFor)); err != nil {
+		return fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+	return nil
+}
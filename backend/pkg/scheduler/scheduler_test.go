@@ -0,0 +1,336 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"lunch/pkg/claims"
+	"lunch/pkg/lunch"
+	"lunch/pkg/lunch/events"
+	"lunch/pkg/lunch/polls"
+	"lunch/pkg/lunch/rooms"
+	"lunch/pkg/store"
+	"lunch/pkg/users"
+	storage_users "lunch/pkg/users/storage"
+)
+
+func TestTick(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ctx := users.NewContext(context.Background(), owner)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "UTC"
+	assertNoError(t, roller.UpdateRoomSettings(ctx, roomID, settings))
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+
+	_, err = roller.UpdateSchedule(ctx, roomID, 11, 30, []time.Weekday{time.Monday, time.Tuesday}, monday)
+	assertNoError(t, err)
+
+	claimsStore := claims.NewBolt(bolt)
+	s := New(roller, claimsStore)
+
+	// not due yet
+	assertNoError(t, s.tick(monday.Add(11*time.Hour), monday.Add(11*time.Hour+29*time.Minute)))
+	rolls, err := roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 0, len(rolls))
+
+	assertNoError(t, s.tick(monday.Add(11*time.Hour+29*time.Minute), monday.Add(11*time.Hour+30*time.Minute)))
+	rolls, err = roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(rolls))
+	assertEqual(t, users.System.ID, rolls[0].UserID)
+	assertEqual(t, users.System.Name, rolls[0].User.Name)
+
+	// another instance checks the schedule a little later
+	other := New(lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt)), claims.NewBolt(bolt))
+	assertNoError(t, other.tick(monday.Add(11*time.Hour+29*time.Minute+10*time.Second), monday.Add(11*time.Hour+30*time.Minute+10*time.Second)))
+	rolls, err = roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(rolls))
+
+	// someone rolled on tuesday before the schedule
+	tuesday := monday.AddDate(0, 0, 1)
+	_, err = roller.CreateRoll(ctx, roomID, tuesday.Add(11*time.Hour))
+	assertNoError(t, err)
+	assertNoError(t, s.tick(tuesday.Add(11*time.Hour+29*time.Minute), tuesday.Add(11*time.Hour+30*time.Minute)))
+	rolls, err = roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(rolls))
+
+	// another instance claimed the roll, and hasn't stored it yet
+	nextMonday := monday.AddDate(0, 0, 7)
+	due := nextMonday.Add(11*time.Hour + 30*time.Minute)
+	assertNoError(t, claimsStore.Claim(ctx, fmt.Sprintf("scheduler/roll/%s/%s", roomID, due.Format("2006-01-02")), due, due.Add(claimFor)))
+	assertNoError(t, s.tick(due.Add(-time.Minute), due))
+	rolls, err = roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(rolls))
+}
+
+func TestTick_rooms(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ctx := users.NewContext(context.Background(), owner)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	// rooms due at the same time are rolled by the system user at the same time
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "UTC"
+	for _, name := range []string{"first", "second"} {
+		assertNoError(t, roller.CreateRoom(ctx, name))
+	}
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(rr))
+	for _, room := range rr {
+		assertNoError(t, roller.UpdateRoomSettings(ctx, room.ID, settings))
+		assertNoError(t, roller.CreatePlace(ctx, room.ID, "place"))
+		_, err = roller.UpdateSchedule(ctx, room.ID, 11, 30, []time.Weekday{time.Monday}, monday)
+		assertNoError(t, err)
+	}
+
+	s := New(roller, claims.NewBolt(bolt))
+	assertNoError(t, s.tick(monday.Add(11*time.Hour+29*time.Minute), monday.Add(11*time.Hour+30*time.Minute)))
+
+	for _, room := range rr {
+		rolls, err := roller.ListRolls(ctx, room.ID)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(rolls))
+	}
+}
+
+func TestReminders(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	owner := &users.User{ID: "owner", Name: "owner"}
+	member := &users.User{ID: "member", Name: "member"}
+	ownerCtx := users.NewContext(context.Background(), owner)
+	memberCtx := users.NewContext(context.Background(), member)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	usersStore := storage_users.NewBolt(bolt)
+	assertNoError(t, usersStore.Create(ownerCtx, owner))
+	assertNoError(t, usersStore.Create(memberCtx, member))
+	roller := lunch.New(events.NewBoltStorage(bolt), usersStore)
+
+	reminded := make(chan *lunch.Reminder, 1)
+	roller.OnReminderDue(func(ctx context.Context, reminder *lunch.Reminder) error {
+		reminded <- reminder
+		return nil
+	})
+
+	assertNoError(t, roller.CreateRoom(ownerCtx, "room"))
+	rr, err := roller.ListRooms(ownerCtx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+	assertNoError(t, roller.JoinRoom(memberCtx, roomID))
+
+	settings := rooms.DefaultSettings()
+	settings.Timezone = "UTC"
+	settings.ReminderMinutes = 15
+	assertNoError(t, roller.UpdateRoomSettings(ownerCtx, roomID, settings))
+	assertNoError(t, roller.CreatePlace(ownerCtx, roomID, "place"))
+
+	_, err = roller.UpdateSchedule(ownerCtx, roomID, 11, 30, []time.Weekday{time.Monday}, monday)
+	assertNoError(t, err)
+	assertNoError(t, roller.MuteReminders(memberCtx, roomID, true, monday.Add(time.Minute)))
+
+	claimsStore := claims.NewBolt(bolt)
+	s := New(roller, claimsStore)
+
+	// roll is due, reminder is not
+	assertNoError(t, s.tick(monday.Add(11*time.Hour+29*time.Minute), monday.Add(11*time.Hour+30*time.Minute)))
+	assertNoError(t, roller.Wait(ownerCtx))
+	select {
+	case <-reminded:
+		t.Fatal("unexpected reminder")
+	default:
+	}
+
+	nextMonday := monday.AddDate(0, 0, 7)
+	assertNoError(t, s.tick(nextMonday.Add(11*time.Hour+14*time.Minute), nextMonday.Add(11*time.Hour+15*time.Minute)))
+	assertNoError(t, roller.Wait(ownerCtx))
+	select {
+	case reminder := <-reminded:
+		assertEqual(t, roomID, reminder.RoomID)
+		assertEqual(t, nextMonday.Add(11*time.Hour+30*time.Minute), reminder.RollsAt)
+		assertEqual(t, 1, len(reminder.Users))
+		assertEqual(t, owner.ID, reminder.Users[0].ID)
+	default:
+		t.Fatal("expected a reminder")
+	}
+
+	// another instance claimed the reminder
+	mondayAfter := nextMonday.AddDate(0, 0, 7)
+	due := mondayAfter.Add(11*time.Hour + 15*time.Minute)
+	assertNoError(t, claimsStore.Claim(ownerCtx, fmt.Sprintf("scheduler/reminder/%s/%s", roomID, due.Format("2006-01-02")), due, due.Add(claimFor)))
+	assertNoError(t, s.tick(due.Add(-time.Minute), due))
+	assertNoError(t, roller.Wait(ownerCtx))
+	select {
+	case <-reminded:
+		t.Fatal("unexpected reminder")
+	default:
+	}
+}
+
+func TestPolls(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ctx := users.NewContext(context.Background(), owner)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	assertNoError(t, roller.CreateRoom(ctx, "room"))
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	roomID := rr[0].ID
+	assertNoError(t, roller.CreatePlace(ctx, roomID, "place"))
+	places, err := roller.ListPlaces(ctx, roomID, monday)
+	assertNoError(t, err)
+
+	noon := monday.Add(12 * time.Hour)
+	_, err = roller.OpenPoll(ctx, roomID, polls.MethodApproval, noon, monday.Add(11*time.Hour))
+	assertNoError(t, err)
+	_, err = roller.Vote(ctx, roomID, places[0].ID, monday.Add(11*time.Hour+time.Minute))
+	assertNoError(t, err)
+
+	claimsStore := claims.NewBolt(bolt)
+	s := New(roller, claimsStore)
+
+	// still accepting votes
+	assertNoError(t, s.tick(noon.Add(-2*time.Minute), noon.Add(-time.Minute)))
+	poll, err := roller.GetPoll(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, false, poll.IsClosed)
+
+	assertNoError(t, s.tick(noon.Add(-time.Minute), noon))
+	poll, err = roller.GetPoll(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, true, poll.IsClosed)
+	rolls, err := roller.ListRolls(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(rolls))
+	assertEqual(t, poll.ID, rolls[0].PollID)
+
+	// another instance claimed the next poll, and hasn't closed it yet
+	closesAt := noon.Add(time.Hour)
+	poll, err = roller.OpenPoll(ctx, roomID, polls.MethodApproval, closesAt, noon)
+	assertNoError(t, err)
+	assertNoError(t, claimsStore.Claim(ctx, fmt.Sprintf("scheduler/poll/%s", poll.ID), closesAt, closesAt.Add(claimFor)))
+	assertNoError(t, s.tick(closesAt.Add(-time.Minute), closesAt))
+	poll, err = roller.GetPoll(ctx, roomID)
+	assertNoError(t, err)
+	assertEqual(t, false, poll.IsClosed)
+}
+
+func TestPolls_rooms(t *testing.T) {
+	t.Parallel()
+
+	monday := time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	owner := &users.User{ID: "owner", Name: "owner"}
+	ctx := users.NewContext(context.Background(), owner)
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+	roller := lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt))
+
+	// polls of both rooms are closed by the system user at the same time
+	noon := monday.Add(12 * time.Hour)
+	for _, name := range []string{"first", "second"} {
+		assertNoError(t, roller.CreateRoom(ctx, name))
+	}
+	rr, err := roller.ListRooms(ctx)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(rr))
+	for _, room := range rr {
+		assertNoError(t, roller.CreatePlace(ctx, room.ID, "place"))
+		places, err := roller.ListPlaces(ctx, room.ID, monday)
+		assertNoError(t, err)
+		_, err = roller.OpenPoll(ctx, room.ID, polls.MethodApproval, noon, monday.Add(11*time.Hour))
+		assertNoError(t, err)
+		_, err = roller.Vote(ctx, room.ID, places[0].ID, monday.Add(11*time.Hour+time.Minute))
+		assertNoError(t, err)
+	}
+
+	s := New(roller, claims.NewBolt(bolt))
+	assertNoError(t, s.tick(noon.Add(-time.Minute), noon))
+
+	for _, room := range rr {
+		poll, err := roller.GetPoll(ctx, room.ID)
+		assertNoError(t, err)
+		assertEqual(t, true, poll.IsClosed)
+		rolls, err := roller.ListRolls(ctx, room.ID)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(rolls))
+		assertEqual(t, poll.ID, rolls[0].PollID)
+	}
+}
+
+func TestStop(t *testing.T) {
+	t.Parallel()
+
+	file, err := ioutil.TempFile("", "test-bolt-*")
+	assertNoError(t, err)
+	bolt, err := store.NewBolt(file.Name())
+	assertNoError(t, err)
+
+	s := New(lunch.New(events.NewBoltStorage(bolt), storage_users.NewBolt(bolt)), claims.NewBolt(bolt))
+	s.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assertNoError(t, s.Stop(ctx))
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("\nexpected: %+v\ngot: %+v", nil, err)
+	}
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if expected != got {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -0,0 +1,56 @@
+// Package seal encrypts secrets before they are stored, with keys derived from server configuration.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// Seal encrypts the plaintext with a key derived from the secret and the purpose, so that the same secret can
+// seal different kinds of values. The result is base64 encoded.
+func Seal(secret []byte, purpose, plaintext string) (string, error) {
+	gcm, err := newGCM(secret, purpose)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed with the same secret and purpose.
+func Open(secret []byte, purpose, sealed string) (string, error) {
+	gcm, err := newGCM(secret, purpose)
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode: %w", err)
+	}
+	if len(b) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed value is too short")
+	}
+	plaintext, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(secret []byte, purpose string) (cipher.AEAD, error) {
+	key := sha256.Sum256(append([]byte(purpose+":"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
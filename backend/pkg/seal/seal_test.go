@@ -0,0 +1,31 @@
+package seal
+
+import "testing"
+
+func TestSeal(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("secret")
+	sealed, err := Seal(secret, "purpose", "plaintext")
+	if err != nil {
+		t.Fatalf("failed to seal: %s", err)
+	}
+	if sealed == "plaintext" {
+		t.Fatalf("expected the value to be encrypted")
+	}
+
+	opened, err := Open(secret, "purpose", sealed)
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	if opened != "plaintext" {
+		t.Errorf("expected 'plaintext', got '%s'", opened)
+	}
+
+	if _, err := Open(secret, "other", sealed); err == nil {
+		t.Error("expected an error when opening with another purpose")
+	}
+	if _, err := Open([]byte("other"), "purpose", sealed); err == nil {
+		t.Error("expected an error when opening with another secret")
+	}
+}
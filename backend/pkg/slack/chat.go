@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"context"
+)
+
+// https://api.slack.com/methods/chat.postMessage
+type PostMessageRequest struct {
+	Channel string `json:"channel"`
+	// ThreadTS makes the message a reply in the thread of the message with this timestamp.
+	ThreadTS string      `json:"thread_ts,omitempty"`
+	Text     string      `json:"text"`
+	Blocks   interface{} `json:"blocks,omitempty"`
+}
+
+// PostMessage posts a message, and returns its timestamp.
+func (c *Client) PostMessage(ctx context.Context, token string, req *PostMessageRequest) (string, error) {
+	var resp struct {
+		Response
+		TS string `json:"ts"`
+	}
+	if err := c.callJSON(ctx, "chat.postMessage", req.Channel, token, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+// https://api.slack.com/methods/chat.update
+type UpdateMessageRequest struct {
+	Channel string      `json:"channel"`
+	TS      string      `json:"ts"`
+	Text    string      `json:"text"`
+	Blocks  interface{} `json:"blocks,omitempty"`
+}
+
+// UpdateMessage replaces a message.
+func (c *Client) UpdateMessage(ctx context.Context, token string, req *UpdateMessageRequest) error {
+	var resp Response
+	return c.callJSON(ctx, "chat.update", req.Channel, token, req, &resp)
+}
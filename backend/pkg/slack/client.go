@@ -0,0 +1,225 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRetries        = 3
+	defaultRetryAfter = time.Second
+)
+
+// Error is returned when Slack responds with ok: false.
+type Error struct {
+	Method string
+	Code   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Method, e.Code)
+}
+
+// Response is the part of every Web API response that tells if the call succeeded.
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+func (r *Response) response() *Response {
+	return r
+}
+
+type response interface {
+	response() *Response
+}
+
+// Client calls the Slack Web API. It waits for per-method rate limits, and retries calls that were rate limited
+// by Slack.
+type Client struct {
+	baseURL  string
+	client   *http.Client
+	limiters *limiters
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+func New(cfg *Configuration) *Client {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   &http.Client{Timeout: timeout},
+		limiters: &limiters{},
+		now:      time.Now,
+		sleep:    sleep,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// callJSON calls a method with a json body. key is what the method is rate limited by in addition to the method,
+// for example a channel.
+func (c *Client) callJSON(ctx context.Context, method, key, token string, in interface{}, out response) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.call(ctx, method, key, out, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodURL(method), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		return req, nil
+	})
+}
+
+// callForm calls a method with a form body, used by methods that don't accept json.
+func (c *Client) callForm(ctx context.Context, method string, form url.Values, out response) error {
+	body := form.Encode()
+	return c.call(ctx, method, "", out, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodURL(method), strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+}
+
+// callGet calls a method with arguments in the query, if any.
+func (c *Client) callGet(ctx context.Context, method, token string, query url.Values, out response) error {
+	methodURL := c.methodURL(method)
+	if len(query) > 0 {
+		methodURL = fmt.Sprintf("%s?%s", methodURL, query.Encode())
+	}
+	return c.call(ctx, method, "", out, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, methodURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return req, nil
+	})
+}
+
+func (c *Client) methodURL(method string) string {
+	return fmt.Sprintf("%s/%s", c.baseURL, method)
+}
+
+func (c *Client) call(ctx context.Context, method, key string, out response, newRequest func() (*http.Request, error)) error {
+	if err := c.sleep(ctx, c.limiters.reserve(method, key, c.now())); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	resp, err := c.do(ctx, newRequest)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", method, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: failed to decode response: %w", method, err)
+	}
+
+	if r := out.response(); !r.OK {
+		return &Error{Method: method, Code: r.Error}
+	}
+	return nil
+}
+
+// do sends the request, and retries it after the time Slack asks to wait if it was rate limited.
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxRetries {
+			return resp, nil
+		}
+
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		log.Printf("[WARN] slack: rate limited, retrying in %s", retryAfter)
+		if err := c.sleep(ctx, retryAfter); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return defaultRetryAfter
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Respond posts a message to the response_url of a command or an interaction.
+func (c *Client) Respond(ctx context.Context, responseURL string, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	resp, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to post response: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostMessage(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "/chat.postMessage", r.URL.Path)
+		assertEqual(t, "Bearer token", r.Header.Get("Authorization"))
+
+		req := &PostMessageRequest{}
+		assertNoError(t, json.NewDecoder(r.Body).Decode(req))
+		assertEqual(t, "C1", req.Channel)
+		assertEqual(t, "1.1", req.ThreadTS)
+
+		_, _ = w.Write([]byte(`{"ok":true,"ts":"1.2"}`))
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL)
+	ts, err := client.PostMessage(context.Background(), "token", &PostMessageRequest{Channel: "C1", ThreadTS: "1.1", Text: "hi"})
+	assertNoError(t, err)
+	assertEqual(t, "1.2", ts)
+}
+
+func TestUserInfo(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "/users.info", r.URL.Path)
+		assertEqual(t, "U1", r.URL.Query().Get("user"))
+		assertEqual(t, "Bearer token", r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte(`{"ok":true,"user":{"id":"U1","name":"spengler"}}`))
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL)
+	user, err := client.UserInfo(context.Background(), "token", "U1")
+	assertNoError(t, err)
+	assertEqual(t, "spengler", user.Name)
+}
+
+func TestCall_error(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL)
+	err := client.UpdateMessage(context.Background(), "token", &UpdateMessageRequest{Channel: "C1", TS: "1.1"})
+
+	slackErr := &Error{}
+	if !errors.As(err, &slackErr) {
+		t.Fatalf("expected slack error, got %v", err)
+	}
+	assertEqual(t, "channel_not_found", slackErr.Code)
+}
+
+func TestCall_retryAfter(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"user":{"id":"U1","name":"user"}}`))
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL)
+	slept := []time.Duration{}
+	client.sleep = func(_ context.Context, d time.Duration) error {
+		if d > 0 {
+			slept = append(slept, d)
+		}
+		return nil
+	}
+
+	identity, err := client.UserIdentity(context.Background(), "token")
+	assertNoError(t, err)
+	assertEqual(t, "U1", identity.ID)
+	assertEqual(t, 3, calls)
+	assertEqual(t, 2, len(slept))
+	assertEqual(t, 7*time.Second, slept[0])
+}
+
+func TestCall_retriesExhausted(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := testClient(server.URL)
+	client.sleep = func(context.Context, time.Duration) error { return nil }
+
+	_, err := client.PostMessage(context.Background(), "token", &PostMessageRequest{Channel: "C1"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCall_rateLimit(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2021, time.September, 6, 12, 0, 0, 0, time.UTC)
+	client := testClient(server.URL)
+	client.now = func() time.Time { return now }
+
+	guard := sync.Mutex{}
+	waits := map[string][]time.Duration{}
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		guard.Lock()
+		defer guard.Unlock()
+		channel := ctx.Value(channelKey{}).(string)
+		waits[channel] = append(waits[channel], d)
+		return nil
+	}
+
+	for _, channel := range []string{"C1", "C1", "C1", "C2"} {
+		ctx := context.WithValue(context.Background(), channelKey{}, channel)
+		_, err := client.PostMessage(ctx, "token", &PostMessageRequest{Channel: channel})
+		assertNoError(t, err)
+	}
+
+	// one message per second per channel
+	assertEqual(t, 3, len(waits["C1"]))
+	assertEqual(t, time.Duration(0), waits["C1"][0])
+	assertEqual(t, time.Second, waits["C1"][1])
+	assertEqual(t, 2*time.Second, waits["C1"][2])
+	assertEqual(t, time.Duration(0), waits["C2"][0])
+}
+
+type channelKey struct{}
+
+func TestRespond(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "/response", r.URL.Path)
+		_, _ = w.Write([]byte(`ok`))
+	}))
+	defer server.Close()
+
+	client := testClient("http://unused")
+	assertNoError(t, client.Respond(context.Background(), server.URL+"/response", map[string]string{"text": "hi"}))
+}
+
+func testClient(baseURL string) *Client {
+	return New(&Configuration{BaseURL: baseURL, Timeout: time.Second})
+}
+
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("\nexpected: %+v\ngot: %+v", nil, err)
+	}
+}
+
+func assertEqual(t *testing.T, expected, got interface{}) {
+	t.Helper()
+
+	if expected != got {
+		t.Errorf("\nexpected: %+v\ngot: %+v", expected, got)
+	}
+}
@@ -0,0 +1,37 @@
+package slack
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://slack.com/api"
+	defaultTimeout = 10 * time.Second
+)
+
+type Configuration struct {
+	// BaseURL is the root of the Web API, methods are called as BaseURL/method.
+	BaseURL string
+	// Timeout is for a single request, waiting for rate limits and retries is not included.
+	Timeout time.Duration
+}
+
+func (c *Configuration) Parse() error {
+	c.BaseURL = defaultBaseURL
+	if baseURL := os.Getenv("SLACK_API_URL"); baseURL != "" {
+		c.BaseURL = baseURL
+	}
+
+	c.Timeout = defaultTimeout
+	if timeout := os.Getenv("SLACK_API_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("SLACK_API_TIMEOUT must be a duration, like 10s: %w", err)
+		}
+		c.Timeout = d
+	}
+
+	return nil
+}
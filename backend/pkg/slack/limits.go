@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// limit is how many calls can be made per period.
+type limit struct {
+	Calls  int
+	Period time.Duration
+}
+
+// https://api.slack.com/docs/rate-limits
+var (
+	tier1 = limit{Calls: 1, Period: time.Minute}
+	tier2 = limit{Calls: 20, Period: time.Minute}
+	tier3 = limit{Calls: 50, Period: time.Minute}
+	tier4 = limit{Calls: 100, Period: time.Minute}
+
+	// chat.postMessage is limited to about one message per second per channel.
+	postMessageLimit = limit{Calls: 1, Period: time.Second}
+)
+
+var methodLimits = map[string]limit{
+	"chat.postMessage": postMessageLimit,
+	"chat.update":      tier3,
+	"oauth.v2.access":  tier4,
+	"users.identity":   tier4,
+	"users.info":       tier4,
+	"views.open":       tier4,
+	"views.publish":    tier4,
+}
+
+func methodLimit(method string) limit {
+	if l, ok := methodLimits[method]; ok {
+		return l
+	}
+	return tier3
+}
+
+// limiter is a token bucket that spreads calls evenly over the period, allowing small bursts.
+type limiter struct {
+	guard    sync.Mutex
+	interval time.Duration
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+func newLimiter(l limit, now time.Time) *limiter {
+	burst := float64(l.Calls / 10)
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		interval: l.Period / time.Duration(l.Calls),
+		burst:    burst,
+		tokens:   burst,
+		last:     now,
+	}
+}
+
+// reserve takes a token, and returns how long to wait before the call can be made.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.guard.Lock()
+	defer l.guard.Unlock()
+
+	if now.After(l.last) {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// limiters keeps a limiter per method, or per method and channel.
+type limiters struct {
+	guard    sync.Mutex
+	limiters map[string]*limiter
+}
+
+func (ll *limiters) reserve(method, key string, now time.Time) time.Duration {
+	ll.guard.Lock()
+	if ll.limiters == nil {
+		ll.limiters = map[string]*limiter{}
+	}
+	id := method + "/" + key
+	l, ok := ll.limiters[id]
+	if !ok {
+		l = newLimiter(methodLimit(method), now)
+		ll.limiters[id] = l
+	}
+	ll.guard.Unlock()
+
+	return l.reserve(now)
+}
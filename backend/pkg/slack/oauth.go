@@ -0,0 +1,74 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+)
+
+// https://api.slack.com/methods/oauth.v2.access
+type OAuthAccessRequest struct {
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+}
+
+type AuthedUser struct {
+	ID          string `json:"id"`
+	AccessToken string `json:"access_token"`
+}
+
+type OAuthAccessResponse struct {
+	Response
+	AuthedUser *AuthedUser `json:"authed_user"`
+}
+
+// OAuthAccess exchanges a code for access tokens.
+func (c *Client) OAuthAccess(ctx context.Context, req *OAuthAccessRequest) (*OAuthAccessResponse, error) {
+	form := url.Values{}
+	form.Set("client_id", req.ClientID)
+	form.Set("client_secret", req.ClientSecret)
+	form.Set("code", req.Code)
+	form.Set("redirect_uri", req.RedirectURI)
+	form.Set("grant_type", "authorization_code")
+
+	resp := &OAuthAccessResponse{}
+	if err := c.callForm(ctx, "oauth.v2.access", form, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type Identity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserIdentity returns the user the token belongs to.
+// https://api.slack.com/methods/users.identity
+func (c *Client) UserIdentity(ctx context.Context, token string) (*Identity, error) {
+	var resp struct {
+		Response
+		User *Identity `json:"user"`
+	}
+	if err := c.callGet(ctx, "users.identity", token, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.User, nil
+}
+
+// UserInfo returns the profile of any user of the workspace.
+// https://api.slack.com/methods/users.info
+func (c *Client) UserInfo(ctx context.Context, token, userID string) (*Identity, error) {
+	query := url.Values{}
+	query.Set("user", userID)
+
+	var resp struct {
+		Response
+		User *Identity `json:"user"`
+	}
+	if err := c.callGet(ctx, "users.info", token, query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.User, nil
+}
@@ -0,0 +1,29 @@
+package slack
+
+import (
+	"context"
+)
+
+// https://api.slack.com/methods/views.publish
+type PublishViewRequest struct {
+	UserID string      `json:"user_id"`
+	View   interface{} `json:"view"`
+}
+
+// PublishView replaces the App Home of the user.
+func (c *Client) PublishView(ctx context.Context, token string, req *PublishViewRequest) error {
+	var resp Response
+	return c.callJSON(ctx, "views.publish", req.UserID, token, req, &resp)
+}
+
+// https://api.slack.com/methods/views.open
+type OpenViewRequest struct {
+	TriggerID string      `json:"trigger_id"`
+	View      interface{} `json:"view"`
+}
+
+// OpenView opens a modal. Trigger ids expire in 3 seconds, so the view must be opened right away.
+func (c *Client) OpenView(ctx context.Context, token string, req *OpenViewRequest) error {
+	var resp Response
+	return c.callJSON(ctx, "views.open", "", token, req, &resp)
+}
@@ -55,6 +55,42 @@ func (b *Bolt) Put(ctx context.Context, bucket, key string, value interface{}) e
 	})
 }
 
+// Update calls fn with the current value of the key, or nil if there is none, and puts the value fn returns.
+// Both happen in one transaction, so fn can check the current value before it's replaced.
+func (b *Bolt) Update(ctx context.Context, bucket, key string, fn func(current []byte) (interface{}, error)) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %v", err)
+		}
+		value, err := fn(b.Get([]byte(key)))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value: %v", err)
+		}
+		if err := b.Put([]byte(key), data); err != nil {
+			return fmt.Errorf("failed to put value: %v", err)
+		}
+		return nil
+	})
+}
+
+func (b *Bolt) Delete(ctx context.Context, bucket, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		if err := b.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("failed to delete value: %v", err)
+		}
+		return nil
+	})
+}
+
 func (b *Bolt) Get(ctx context.Context, bucket, key string, dest interface{}) error {
 	return b.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
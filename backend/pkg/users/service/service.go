@@ -25,11 +25,15 @@ func (s *Service) List(ctx context.Context) (map[users.ID]*users.User, error) {
 	return s.store.List(ctx)
 }
 
+// Create creates the user, or updates their name if it has changed since.
 func (s *Service) Create(ctx context.Context, user *users.User) error {
-	if _, err := s.store.Get(ctx, user.ID); errors.Is(err, storage.ErrNotFound) {
+	if existing, err := s.store.Get(ctx, user.ID); errors.Is(err, storage.ErrNotFound) {
 		return s.store.Create(ctx, user)
 	} else if err == nil {
-		return nil
+		if existing.Name == user.Name {
+			return nil
+		}
+		return s.store.Update(ctx, user)
 	} else {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
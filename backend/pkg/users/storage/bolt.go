@@ -27,6 +27,10 @@ func (s *bolt) Create(ctx context.Context, user *users.User) error {
 	return s.db.Put(ctx, s.bucketName, string(user.ID), user)
 }
 
+func (s *bolt) Update(ctx context.Context, user *users.User) error {
+	return s.db.Put(ctx, s.bucketName, string(user.ID), user)
+}
+
 func (s *bolt) Get(ctx context.Context, id users.ID) (*users.User, error) {
 	var user *users.User
 	if err := s.db.Get(ctx, s.bucketName, string(id), &user); errors.Is(err, store.ErrNotFound) {
@@ -43,7 +43,19 @@ func (c *cache) Create(ctx context.Context, user *users.User) error {
 	if err := c.storage.Create(ctx, user); err != nil {
 		return err
 	}
+	c.put(user)
+	return nil
+}
 
+func (c *cache) Update(ctx context.Context, user *users.User) error {
+	if err := c.storage.Update(ctx, user); err != nil {
+		return err
+	}
+	c.put(user)
+	return nil
+}
+
+func (c *cache) put(user *users.User) {
 	c.byIDGuard.Lock()
 	c.byID[user.ID] = &cached{
 		user: user,
@@ -53,8 +65,6 @@ func (c *cache) Create(ctx context.Context, user *users.User) error {
 	c.listGuard.Lock()
 	c.list[user.ID] = user
 	c.listGuard.Unlock()
-
-	return nil
 }
 
 func (c *cache) Get(ctx context.Context, id users.ID) (*users.User, error) {
@@ -31,6 +31,15 @@ func (d *dynamoDB) Create(ctx context.Context, user *users.User) error {
 	return nil
 }
 
+func (d *dynamoDB) Update(ctx context.Context, user *users.User) error {
+	if err := d.storage.Execute(ctx, fmt.Sprintf(`
+		UPDATE "%s" SET "name" = ? WHERE id = ?
+	`, d.tableName), user.Name, user.ID); err != nil {
+		return fmt.Errorf("failed to update: %w", err)
+	}
+	return nil
+}
+
 func (d *dynamoDB) Get(ctx context.Context, id users.ID) (*users.User, error) {
 	users := []*users.User{}
 	if err := d.storage.Query(ctx, &users, fmt.Sprintf(`SELECT * FROM "%s" WHERE id = ?`, d.tableName), id); err != nil {
@@ -11,6 +11,7 @@ var ErrNotFound = fmt.Errorf("not found")
 
 type Storage interface {
 	Create(context.Context, *users.User) error
+	Update(context.Context, *users.User) error
 	Get(context.Context, users.ID) (*users.User, error)
 	List(context.Context) (map[users.ID]*users.User, error)
 }
@@ -1,8 +1,32 @@
 package users
 
+import "strings"
+
 type ID string
 
+// TeamsID returns the id of a Microsoft Teams user. Ids of users from chats other than Slack are prefixed with
+// the chat name, so they never clash with Slack ids.
+func TeamsID(id string) ID {
+	return ID("teams:" + id)
+}
+
+// DiscordID returns the id of a Discord user.
+func DiscordID(id string) ID {
+	return ID("discord:" + id)
+}
+
+// IsSlack returns true if the user comes from Slack, or signed in with Slack.
+func (id ID) IsSlack() bool {
+	return !strings.Contains(string(id), ":")
+}
+
 type User struct {
 	ID   ID     `dynamodbav:"id" json:"id"`
 	Name string `dynamodbav:"name" json:"name"`
 }
+
+// System is the user automatic actions are made by.
+var System = &User{
+	ID:   "system",
+	Name: "Lunch bot",
+}